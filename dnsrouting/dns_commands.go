@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/pronabpal/iptp/core"
+)
+
+// DNSStats is the JSON shape of 'dns stats --json'
+type DNSStats struct {
+	Running       bool   `json:"running"`
+	TotalQueries  int    `json:"total_queries"`
+	UniqueDomains int    `json:"unique_domains"`
+	ListenAddress string `json:"listen_address"`
+	UpstreamDNS   string `json:"upstream_dns"`
+	LogFile       string `json:"log_file"`
+}
+
+// dnsRouter is the shell's DNS router (default config, not started)
+var dnsRouter = NewDNSRouter("0.0.0.0:53", "8.8.8.8:53")
+
+func init() {
+	// Let iptpd start, stop and report on the router
+	core.DaemonDNS = func() core.DNSController { return dnsRouter }
+
+	core.RegisterCommand(&core.Command{
+		Name:  "dns",
+		Usage: "dns SUBCOMMAND",
+		Help:  "Manage the DNS router",
+		Group: "DNS Router",
+		Subcommands: []core.Subcommand{
+			{Usage: "dns start", Help: "Start DNS router service"},
+			{Usage: "dns stop", Help: "Stop DNS router service"},
+			{Usage: "dns status", Help: "Show DNS router status"},
+			{Usage: "dns logs [N] [--json]", Help: "Show last N DNS queries (default 10)"},
+			{Usage: "dns stats [--json]", Help: "Show DNS statistics"},
+			{Usage: "dns install", Help: "Show service installation instructions"},
+		},
+		Run: cmdDNS,
+		Exec: func(state *core.State, process string, args []string) int {
+			if len(args) == 0 {
+				fmt.Println("Usage: iptp dns [status|logs|stats]")
+				return 1
+			}
+			switch args[0] {
+			case "status":
+				return dnsStatusCommand()
+			case "logs":
+				return dnsLogsCommand(args[1:])
+			case "stats":
+				return dnsStatsCommand(args[1:])
+			default:
+				fmt.Printf("'dns %s' is only available inside the iptp shell (or via 'iptp daemon')\n", args[0])
+				return 1
+			}
+		},
+	})
+}
+
+// cmdDNS handles DNS router management commands
+func cmdDNS(sh *core.Shell, args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: dns [start|stop|status|logs|stats|install]")
+		return
+	}
+
+	subCmd := args[0]
+	subArgs := args[1:]
+
+	switch subCmd {
+	case "start":
+		dnsStart(subArgs)
+	case "stop":
+		dnsStop()
+	case "status":
+		dnsStatusCommand()
+	case "logs":
+		dnsLogsCommand(subArgs)
+	case "stats":
+		dnsStatsCommand(subArgs)
+	case "install":
+		dnsInstall()
+	default:
+		fmt.Printf("Unknown dns command: %s\n", subCmd)
+		fmt.Println("Available: start, stop, status, logs, stats, install")
+	}
+}
+
+// dnsStart starts the DNS router
+func dnsStart(args []string) {
+	if dnsRouter.IsRunning() {
+		fmt.Println("✗ DNS router is already running")
+		fmt.Println("  Use 'dns stop' first if you want to restart")
+		return
+	}
+
+	// Parse optional arguments for custom config
+	listenAddr := "0.0.0.0:53"
+	upstreamDNS := "8.8.8.8:53"
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--listen", "-l":
+			if i+1 < len(args) {
+				listenAddr = args[i+1]
+				i++
+			}
+		case "--upstream", "-u":
+			if i+1 < len(args) {
+				upstreamDNS = args[i+1]
+				i++
+			}
+		}
+	}
+
+	// Update router config
+	dnsRouter = NewDNSRouter(listenAddr, upstreamDNS)
+
+	if err := dnsRouter.Start(); err != nil {
+		fmt.Printf("✗ Failed to start DNS router: %v\n", err)
+		fmt.Println("\nNote: DNS runs on port 53, which requires root/admin privileges")
+		fmt.Println("Try: sudo iptp")
+		return
+	}
+
+	fmt.Println("✓ DNS router is now running")
+	fmt.Println("\nTo use this DNS router:")
+	fmt.Println("  1. Go to WiFi/Network settings")
+	fmt.Println("  2. Set DNS server to your machine's IP")
+	fmt.Println("  3. Devices using your hotspot will route through this DNS")
+}
+
+// dnsStop stops the DNS router
+func dnsStop() {
+	if !dnsRouter.IsRunning() {
+		fmt.Println("DNS router is not running")
+		return
+	}
+
+	if err := dnsRouter.Stop(); err != nil {
+		fmt.Printf("✗ Failed to stop DNS router: %v\n", err)
+		return
+	}
+
+	fmt.Println("✓ DNS router stopped")
+}
+
+// dnsStatusCommand shows DNS router status
+func dnsStatusCommand() int {
+	stats := currentDNSStats()
+	if stats.Running {
+		fmt.Println("Status: ✓ RUNNING")
+		fmt.Printf("  Listen: %s\n", stats.ListenAddress)
+		fmt.Printf("  Upstream: %s\n", stats.UpstreamDNS)
+		fmt.Printf("  Queries: %d\n", stats.TotalQueries)
+		fmt.Printf("  Unique domains: %d\n", stats.UniqueDomains)
+	} else {
+		fmt.Println("Status: ✗ STOPPED")
+	}
+	return 0
+}
+
+// dnsLogsCommand shows recent DNS query logs
+func dnsLogsCommand(args []string) int {
+	format, args, err := core.ParseOutputFlags(args)
+	if err != nil {
+		return core.OutputUsageError(err)
+	}
+
+	count := 10
+
+	// Parse count argument
+	if len(args) > 0 {
+		fmt.Sscanf(args[0], "%d", &count)
+	}
+
+	queries := recentDNSQueries(count)
+	if !format.Text() {
+		return core.WriteOutput(format, queries)
+	}
+
+	if len(queries) == 0 {
+		fmt.Println("No queries logged yet")
+		return 0
+	}
+
+	fmt.Printf("=== Last %d DNS Queries ===\n", len(queries))
+	for _, q := range queries {
+		fmt.Printf("[%s] %s -> %s (%s) = %s\n",
+			q.Timestamp[11:19], // Just show time HH:MM:SS
+			q.ClientIP,
+			q.Domain,
+			q.QueryType,
+			q.Response)
+	}
+
+	fmt.Printf("\nLog file: %s\n", dnsRouter.logFile)
+	return 0
+}
+
+// dnsStatsCommand shows DNS statistics
+func dnsStatsCommand(args []string) int {
+	format, _, err := core.ParseOutputFlags(args)
+	if err != nil {
+		return core.OutputUsageError(err)
+	}
+
+	stats := currentDNSStats()
+	if !format.Text() {
+		return core.WriteOutput(format, stats)
+	}
+
+	fmt.Println("=== DNS Router Statistics ===")
+	fmt.Printf("Running: %v\n", stats.Running)
+	fmt.Printf("Total queries: %d\n", stats.TotalQueries)
+	fmt.Printf("Unique domains: %d\n", stats.UniqueDomains)
+	fmt.Printf("Listen address: %s\n", stats.ListenAddress)
+	fmt.Printf("Upstream DNS: %s\n", stats.UpstreamDNS)
+	fmt.Printf("Log file: %s\n", stats.LogFile)
+	return 0
+}
+
+// currentDNSStats reports on the router in this process or, when it isn't
+// running here, on the one iptpd runs
+func currentDNSStats() DNSStats {
+	raw := dnsRouter.GetStats()
+	if !dnsRouter.IsRunning() {
+		if client, err := core.DialDaemon(); err == nil {
+			var remote map[string]interface{}
+			if client.Call("dns.status", nil, &remote) == nil {
+				raw = remote
+			}
+			client.Close()
+		}
+	}
+
+	stats := DNSStats{}
+	stats.Running, _ = raw["running"].(bool)
+	stats.TotalQueries = toInt(raw["total_queries"])
+	stats.UniqueDomains = toInt(raw["unique_domains"])
+	stats.ListenAddress, _ = raw["listen_address"].(string)
+	stats.UpstreamDNS, _ = raw["upstream_dns"].(string)
+	stats.LogFile, _ = raw["log_file"].(string)
+	return stats
+}
+
+// toInt reads a count that may have come back from JSON as a float64
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// recentDNSQueries returns the last count queries, from memory when the
+// router runs in this process and from the query log otherwise
+func recentDNSQueries(count int) []DNSQuery {
+	if dnsRouter.IsRunning() {
+		return dnsRouter.GetRecentQueries(count)
+	}
+
+	queries := []DNSQuery{}
+	f, err := os.Open(dnsRouter.logFile)
+	if err != nil {
+		return queries
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var q DNSQuery
+		if json.Unmarshal(scanner.Bytes(), &q) == nil {
+			queries = append(queries, q)
+		}
+	}
+	if count >= 0 && len(queries) > count {
+		queries = queries[len(queries)-count:]
+	}
+	return queries
+}
+
+// dnsInstall shows service installation instructions
+func dnsInstall() {
+	fmt.Println("=== Install DNS Router as System Service ===")
+	fmt.Println()
+
+	if err := dnsRouter.InstallService(); err != nil {
+		fmt.Printf("✗ Error: %v\n", err)
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/miekg/dns"
+
+	"github.com/pronabpal/iptp/core"
 )
 
 // DNSQuery represents a logged DNS query
@@ -179,12 +181,17 @@ func (dr *DNSRouter) Start() error {
 			dr.statusMutex.Lock()
 			dr.running = false
 			dr.statusMutex.Unlock()
+			dr.writeStatusFile(false)
 		}
 	}()
 
 	// Give it a moment to start
 	time.Sleep(100 * time.Millisecond)
 
+	if dr.IsRunning() {
+		dr.writeStatusFile(true)
+	}
+
 	return nil
 }
 
@@ -200,12 +207,33 @@ func (dr *DNSRouter) Stop() error {
 	if dr.server != nil {
 		err := dr.server.Shutdown()
 		dr.running = false
+		dr.writeStatusFile(false)
 		return err
 	}
 
 	return nil
 }
 
+// writeStatusFile publishes whether the router is running so other iptp
+// shells can show it cheaply without talking to the router
+func (dr *DNSRouter) writeStatusFile(running bool) {
+	status := map[string]interface{}{
+		"running":        running,
+		"pid":            os.Getpid(),
+		"listen_address": dr.listenAddr,
+		"upstream_dns":   dr.upstreamDNS,
+		"timestamp":      time.Now().Format(time.RFC3339),
+	}
+
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(core.DNSStatusFilePath(), data, 0644); err != nil {
+		log.Printf("Failed to write DNS status file: %v", err)
+	}
+}
+
 // IsRunning checks if the DNS router is running
 func (dr *DNSRouter) IsRunning() bool {
 	dr.statusMutex.RLock()
@@ -4,7 +4,10 @@ go 1.23.0
 
 toolchain go1.23.2
 
-require github.com/miekg/dns v1.1.68
+require (
+	github.com/miekg/dns v1.1.68
+	github.com/pronabpal/iptp v0.0.0
+)
 
 require (
 	golang.org/x/mod v0.24.0 // indirect
@@ -13,3 +16,6 @@ require (
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/tools v0.33.0 // indirect
 )
+
+// The shell core is shared with ../iptp-go
+replace github.com/pronabpal/iptp => ../iptp-go
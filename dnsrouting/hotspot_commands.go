@@ -0,0 +1,374 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pronabpal/iptp/core"
+)
+
+// HotspotStatus is the JSON shape of 'hotspot status --json'
+type HotspotStatus struct {
+	Enabled    bool   `json:"enabled"`
+	IPAddress  string `json:"ip_address"` // "" when disabled or unknown
+	DNSRunning bool   `json:"dns_running"`
+	DNSQueries int    `json:"dns_queries"`
+	Error      string `json:"error,omitempty"`
+}
+
+// hotspotManager controls the WiFi hotspot for the shell
+var hotspotManager = NewHotspotManager()
+
+func init() {
+	core.RegisterCommand(&core.Command{
+		Name:  "hotspot",
+		Usage: "hotspot SUBCOMMAND",
+		Help:  "Manage the WiFi hotspot",
+		Group: "WiFi Hotspot",
+		Subcommands: []core.Subcommand{
+			{Usage: "hotspot auto", Help: "Quick setup: hotspot + DNS monitoring"},
+			{Usage: "hotspot enable", Help: "Enable WiFi hotspot (with options)"},
+			{Usage: "hotspot disable", Help: "Disable WiFi hotspot"},
+			{Usage: "hotspot status [--json]", Help: "Show hotspot status"},
+			{Usage: "hotspot test", Help: "Test WiFi detection (diagnostics)"},
+		},
+		Run: cmdHotspot,
+		Exec: func(state *core.State, process string, args []string) int {
+			if len(args) == 0 || args[0] != "status" {
+				fmt.Println("Only 'hotspot status' works outside the iptp shell")
+				return 1
+			}
+			return hotspotStatusCommand(args[1:])
+		},
+	})
+}
+
+// cmdHotspot handles WiFi hotspot management commands
+func cmdHotspot(sh *core.Shell, args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: hotspot [enable|disable|status|auto]")
+		return
+	}
+
+	subCmd := args[0]
+
+	switch subCmd {
+	case "enable":
+		hotspotEnable(sh, args[1:])
+	case "disable":
+		hotspotDisable()
+	case "status":
+		hotspotStatusCommand(args[1:])
+	case "auto":
+		hotspotAuto(args[1:])
+	case "test":
+		hotspotTest()
+	default:
+		fmt.Printf("Unknown hotspot command: %s\n", subCmd)
+		fmt.Println("Available: enable, disable, status, auto, test")
+	}
+}
+
+// hotspotEnable enables WiFi hotspot
+func hotspotEnable(sh *core.Shell, args []string) {
+	// Default SSID and password
+	ssid := "IPTP-Hotspot"
+	password := "iptp123456"
+
+	// Parse optional arguments
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--ssid", "-s":
+			if i+1 < len(args) {
+				ssid = args[i+1]
+				i++
+			}
+		case "--password", "-p":
+			if i+1 < len(args) {
+				password = args[i+1]
+				i++
+			}
+		}
+	}
+
+	fmt.Println("🔌 Checking WiFi connection status...")
+
+	// Check if already connected to WiFi
+	connected, err := hotspotManager.IsConnectedToWiFi()
+	if err != nil {
+		fmt.Printf("✗ Error checking WiFi status: %v\n", err)
+		return
+	}
+
+	if connected {
+		fmt.Println("⚠️  You are currently connected to a WiFi network")
+		fmt.Println("   Enabling hotspot may disconnect you from the network")
+		fmt.Print("\nContinue anyway? (y/N): ")
+
+		response, _ := sh.Input().ReadString('\n')
+		response = strings.ToLower(strings.TrimSpace(response))
+
+		if response != "y" && response != "yes" {
+			fmt.Println("Cancelled")
+			return
+		}
+	}
+
+	fmt.Println("\n📱 Enabling WiFi hotspot...")
+
+	if err := hotspotManager.EnableHotspot(ssid, password); err != nil {
+		fmt.Printf("✗ Failed to enable hotspot: %v\n", err)
+		return
+	}
+
+	// Try to get the IP address
+	ip, err := hotspotManager.GetIPAddress()
+	if err == nil {
+		fmt.Printf("\n✓ Hotspot is ready!\n")
+		fmt.Printf("  Your IP: %s\n", ip)
+		fmt.Println("\nTo use the DNS router with this hotspot:")
+		fmt.Printf("  1. Devices connect to: %s\n", ssid)
+		fmt.Printf("  2. They will use DNS: %s\n", ip)
+		fmt.Println("  3. Start DNS router: dns start")
+		fmt.Println("  4. Monitor queries: dns logs")
+	}
+}
+
+// hotspotDisable disables WiFi hotspot
+func hotspotDisable() {
+	fmt.Println("📱 Disabling WiFi hotspot...")
+
+	if err := hotspotManager.DisableHotspot(); err != nil {
+		fmt.Printf("✗ Failed to disable hotspot: %v\n", err)
+		return
+	}
+
+	fmt.Println("✓ Hotspot disabled")
+}
+
+// hotspotStatusCommand shows hotspot status
+func hotspotStatusCommand(args []string) int {
+	format, _, err := core.ParseOutputFlags(args)
+	if err != nil {
+		return core.OutputUsageError(err)
+	}
+
+	status := currentHotspotStatus()
+	if !format.Text() {
+		return core.WriteOutput(format, status)
+	}
+
+	if status.Error != "" {
+		fmt.Printf("✗ Error checking hotspot status: %s\n", status.Error)
+		return 1
+	}
+
+	if status.Enabled {
+		fmt.Println("Status: ✓ ENABLED")
+
+		if status.IPAddress != "" {
+			fmt.Printf("  IP Address: %s\n", status.IPAddress)
+			fmt.Println("\nDevices should use this IP as their DNS server")
+
+			// Check if DNS router is running
+			if status.DNSRunning {
+				fmt.Println("  DNS Router: ✓ RUNNING")
+				fmt.Printf("  DNS Queries: %d\n", status.DNSQueries)
+			} else {
+				fmt.Println("  DNS Router: ✗ NOT RUNNING")
+				fmt.Println("\nTip: Start DNS router with 'dns start'")
+			}
+		}
+	} else {
+		fmt.Println("Status: ✗ DISABLED")
+		fmt.Println("\nTo enable: hotspot enable")
+	}
+	return 0
+}
+
+// currentHotspotStatus gathers hotspot and DNS router state
+func currentHotspotStatus() HotspotStatus {
+	var status HotspotStatus
+	enabled, err := hotspotManager.GetHotspotStatus()
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	status.Enabled = enabled
+
+	if enabled {
+		if ip, err := hotspotManager.GetIPAddress(); err == nil {
+			status.IPAddress = ip
+		}
+	}
+
+	dns := currentDNSStats()
+	status.DNSRunning = dns.Running
+	status.DNSQueries = dns.TotalQueries
+	return status
+}
+
+// hotspotAuto automatically enables hotspot and DNS for monitoring
+func hotspotAuto(args []string) {
+	// Default SSID and password
+	ssid := "IPTP-Hotspot"
+	password := "iptp123456"
+
+	// Parse optional arguments
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--ssid", "-s":
+			if i+1 < len(args) {
+				ssid = args[i+1]
+				i++
+			}
+		case "--password", "-p":
+			if i+1 < len(args) {
+				password = args[i+1]
+				i++
+			}
+		}
+	}
+
+	fmt.Println("🔌 Checking WiFi connection status...")
+
+	connected, err := hotspotManager.IsConnectedToWiFi()
+	if err != nil {
+		fmt.Printf("⚠️  Could not check WiFi status: %v\n", err)
+		fmt.Println("Continuing anyway...")
+		fmt.Println()
+	} else if connected {
+		fmt.Println("ℹ️  Currently connected to WiFi")
+		fmt.Println("   Creating hotspot will share this connection")
+		fmt.Println()
+	} else {
+		fmt.Println("ℹ️  Not currently on WiFi")
+		fmt.Println("   Creating standalone hotspot")
+		fmt.Println()
+	}
+
+	fmt.Println("📱 Setting up hotspot + DNS monitoring...")
+
+	if err := hotspotManager.EnableHotspot(ssid, password); err != nil {
+		fmt.Printf("✗ Failed to enable hotspot: %v\n", err)
+		return
+	}
+
+	// Try to get the IP address
+	ip, err := hotspotManager.GetIPAddress()
+	if err == nil {
+		fmt.Printf("\n✓ Hotspot enabled!\n")
+		fmt.Printf("  SSID: %s\n", ssid)
+		fmt.Printf("  Password: %s\n", password)
+		fmt.Printf("  Your IP: %s\n", ip)
+
+		// Auto-start DNS router too
+		fmt.Println("\n🌐 Starting DNS router...")
+		if err := dnsRouter.Start(); err != nil {
+			fmt.Printf("⚠️  DNS router failed: %v\n", err)
+			fmt.Println("   (DNS may already be running)")
+		} else {
+			fmt.Printf("✓ DNS router started on %s\n", ip)
+			fmt.Println("\n✨ Your network monitoring is ready!")
+			fmt.Println("   Devices can now connect and their DNS queries will be logged")
+		}
+	}
+}
+
+// hotspotTest tests WiFi detection and shows debug info
+func hotspotTest() {
+	fmt.Println("=== WiFi Detection Test ===")
+	fmt.Println()
+
+	// Test WiFi connection
+	fmt.Println("🔍 Testing WiFi connection detection...")
+	connected, err := hotspotManager.IsConnectedToWiFi()
+
+	if err != nil {
+		fmt.Printf("✗ Error: %v\n", err)
+	} else if connected {
+		fmt.Println("✓ WiFi is CONNECTED")
+	} else {
+		fmt.Println("✗ WiFi is NOT connected")
+	}
+
+	fmt.Println("\n🔍 Detailed diagnostics:")
+	fmt.Println("\n--- Method 1: networksetup ---")
+
+	// Check each common interface
+	interfaces := []string{"en0", "en1", "en2"}
+
+	for _, iface := range interfaces {
+		cmd := exec.Command("networksetup", "-getairportnetwork", iface)
+		output, err := cmd.Output()
+
+		if err != nil {
+			fmt.Printf("  %s: Error - %v\n", iface, err)
+		} else {
+			outputStr := strings.TrimSpace(string(output))
+			if len(outputStr) == 0 {
+				fmt.Printf("  %s: No output\n", iface)
+			} else {
+				fmt.Printf("  %s: %s\n", iface, outputStr)
+			}
+		}
+	}
+
+	fmt.Println("\n--- Method 2: airport command ---")
+	cmd := exec.Command("/System/Library/PrivateFrameworks/Apple80211.framework/Versions/Current/Resources/airport", "-I")
+	output, err := cmd.Output()
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+	} else {
+		outputLines := strings.Split(string(output), "\n")
+		for _, line := range outputLines {
+			line = strings.TrimSpace(line)
+			if strings.Contains(line, "SSID") ||
+				strings.Contains(line, "state") ||
+				strings.Contains(line, "BSSID") {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+	}
+
+	fmt.Println("\n--- Method 3: ifconfig status ---")
+	cmd = exec.Command("ifconfig")
+	output, err = cmd.Output()
+	if err == nil {
+		outputStr := string(output)
+		for _, iface := range interfaces {
+			ifaceIdx := strings.Index(outputStr, iface+":")
+			if ifaceIdx == -1 {
+				continue
+			}
+
+			// Get section for this interface
+			endIdx := ifaceIdx + 500
+			if endIdx > len(outputStr) {
+				endIdx = len(outputStr)
+			}
+			section := outputStr[ifaceIdx:endIdx]
+
+			// Look for status line
+			lines := strings.Split(section, "\n")
+			fmt.Printf("  %s:\n", iface)
+			for _, line := range lines {
+				if strings.Contains(line, "status:") ||
+					strings.Contains(line, "inet ") {
+					fmt.Printf("    %s\n", strings.TrimSpace(line))
+				}
+			}
+		}
+	}
+
+	fmt.Println("\n--- All network hardware ports ---")
+	cmd = exec.Command("networksetup", "-listallhardwareports")
+	output, err = cmd.Output()
+	if err == nil {
+		fmt.Println(string(output))
+	}
+
+	fmt.Println("\n=== Test Complete ===")
+	fmt.Println("\nIf WiFi detection is incorrect, please share this output!")
+}
@@ -0,0 +1,351 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// bookmarkPrefix marks a bookmark reference in goto: goto @NAME[/sub/dir]
+const bookmarkPrefix = "@"
+
+// Bookmark is a named, tagged directory
+// Bookmarks are stored beside the processes, not inside them, so several
+// processes can refer to the same bookmark and they can be exported
+type Bookmark struct {
+	Dir     string   `json:"dir"`
+	Tags    []string `json:"tags"`
+	Created string   `json:"created"`
+}
+
+// BookmarkInfo is the JSON shape of a bookmark in 'marks --json' and exports
+type BookmarkInfo struct {
+	Name      string   `json:"name"`
+	Dir       string   `json:"dir"`
+	Tags      []string `json:"tags"`
+	Created   string   `json:"created"`
+	Processes []string `json:"processes"` // processes that reference it
+}
+
+// SetBookmark creates or replaces a bookmark and links it to a process
+// Returns true if a bookmark with that name already existed
+func (s *State) SetBookmark(name string, mark Bookmark, processName string) bool {
+	if s.Bookmarks == nil {
+		s.Bookmarks = make(map[string]Bookmark)
+	}
+	_, existed := s.Bookmarks[name]
+	s.Bookmarks[name] = mark
+
+	if process, ok := s.Processes[processName]; ok && !containsString(process.Bookmarks, name) {
+		process.Bookmarks = append(process.Bookmarks, name)
+		s.Processes[processName] = process
+	}
+	return existed
+}
+
+// RemoveBookmark deletes a bookmark and every process reference to it
+func (s *State) RemoveBookmark(name string) bool {
+	if _, ok := s.Bookmarks[name]; !ok {
+		return false
+	}
+	delete(s.Bookmarks, name)
+
+	for processName, process := range s.Processes {
+		if !containsString(process.Bookmarks, name) {
+			continue
+		}
+		kept := make([]string, 0, len(process.Bookmarks))
+		for _, b := range process.Bookmarks {
+			if b != name {
+				kept = append(kept, b)
+			}
+		}
+		process.Bookmarks = kept
+		s.Processes[processName] = process
+	}
+	return true
+}
+
+// ResolveBookmark expands @NAME or @NAME/sub/dir to a directory
+func (s *State) ResolveBookmark(ref string) (string, error) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(ref, bookmarkPrefix), "/")
+	mark, ok := s.Bookmarks[name]
+	if !ok {
+		return "", fmt.Errorf("No bookmark named %s (see 'marks')", name)
+	}
+	if rest == "" {
+		return mark.Dir, nil
+	}
+	return filepath.Join(mark.Dir, rest), nil
+}
+
+// validBookmarkName reports whether name can be used in @NAME/sub/dir
+func validBookmarkName(name string) bool {
+	return name != "" && !strings.ContainsRune(name, '/') && strings.IndexFunc(name, unicode.IsSpace) < 0
+}
+
+// hasAllTags reports whether every tag in want is among tags
+func hasAllTags(tags, want []string) bool {
+	for _, tag := range want {
+		if !containsString(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// bookmarkInfos returns bookmarks carrying every tag in tags (all if none),
+// sorted by name
+func bookmarkInfos(state *State, tags []string) []BookmarkInfo {
+	names := make([]string, 0, len(state.Bookmarks))
+	for name := range state.Bookmarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := []BookmarkInfo{}
+	for _, name := range names {
+		mark := state.Bookmarks[name]
+		if !hasAllTags(mark.Tags, tags) {
+			continue
+		}
+		info := BookmarkInfo{Name: name, Dir: mark.Dir, Tags: mark.Tags, Created: mark.Created, Processes: []string{}}
+		if info.Tags == nil {
+			info.Tags = []string{}
+		}
+		for processName, process := range state.Processes {
+			if containsString(process.Bookmarks, name) {
+				info.Processes = append(info.Processes, processName)
+			}
+		}
+		sort.Strings(info.Processes)
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// parseTags collects --tag values; each may be comma-separated
+func parseTags(args []string) (tags, rest []string) {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--tag" || args[i] == "-t":
+			if i+1 < len(args) {
+				tags = appendTags(tags, args[i+1])
+				i++
+			}
+		case strings.HasPrefix(args[i], "--tag="):
+			tags = appendTags(tags, strings.TrimPrefix(args[i], "--tag="))
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return tags, rest
+}
+
+// appendTags adds comma-separated tags, skipping blanks and duplicates
+func appendTags(tags []string, value string) []string {
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !containsString(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// cmdMarkNonInteractive bookmarks the current directory
+// mark NAME [--tag t]...
+func cmdMarkNonInteractive(state *State, process string, args []string) int {
+	tags, rest := parseTags(args)
+	if len(rest) != 1 {
+		fmt.Println("Usage: mark NAME [--tag TAG]...")
+		return 1
+	}
+	name := strings.TrimPrefix(rest[0], bookmarkPrefix)
+	if !validBookmarkName(name) {
+		fmt.Printf("✗ Invalid bookmark name: %s\n", rest[0])
+		return 1
+	}
+
+	dir, _ := os.Getwd()
+	if _, ok := state.GetProcess(process); !ok {
+		state.SetProcess(process, "Working in "+process, dir)
+	}
+	existed := state.SetBookmark(name, Bookmark{
+		Dir:     dir,
+		Tags:    tags,
+		Created: time.Now().Format(time.RFC3339),
+	}, process)
+	state.Save()
+
+	verb := "Marked"
+	if existed {
+		verb = "Updated"
+	}
+	fmt.Printf("✓ %s @%s: %s\n", verb, name, dir)
+	if len(tags) > 0 {
+		fmt.Printf("  Tags: %s\n", strings.Join(tags, ", "))
+	}
+	return 0
+}
+
+// cmdUnmarkNonInteractive removes bookmarks
+func cmdUnmarkNonInteractive(state *State, process string, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: unmark NAME...")
+		return 1
+	}
+
+	status := 0
+	for _, arg := range args {
+		name := strings.TrimPrefix(arg, bookmarkPrefix)
+		if state.RemoveBookmark(name) {
+			fmt.Printf("✓ Removed @%s\n", name)
+		} else {
+			fmt.Printf("✗ No bookmark named %s\n", name)
+			status = 1
+		}
+	}
+	state.Save()
+	return status
+}
+
+// cmdMarksNonInteractive lists, exports or imports bookmarks
+// marks [--tag t]... [--json], marks export [FILE] [--tag t]..., marks import FILE
+// Several tags narrow the list to bookmarks that have all of them
+func cmdMarksNonInteractive(state *State, process string, args []string) int {
+	format, args, err := ParseOutputFlags(args)
+	if err != nil {
+		return OutputUsageError(err)
+	}
+	tags, rest := parseTags(args)
+
+	if len(rest) > 0 {
+		switch rest[0] {
+		case "export":
+			return exportBookmarks(state, tags, rest[1:])
+		case "import":
+			return importBookmarks(state, rest[1:])
+		default:
+			return OutputError(format, 1, "Usage: marks [--tag TAG]... [--json] | marks export [FILE] | marks import FILE")
+		}
+	}
+
+	infos := bookmarkInfos(state, tags)
+	if !format.Text() {
+		return WriteOutput(format, infos)
+	}
+
+	if len(infos) == 0 {
+		fmt.Println("No bookmarks (add one with 'mark NAME')")
+		return 0
+	}
+
+	title := "Bookmarks"
+	if len(tags) > 0 {
+		title += " tagged " + strings.Join(tags, ", ")
+	}
+	fmt.Printf("=== %s ===\n", title)
+	for _, info := range infos {
+		line := fmt.Sprintf("  @%-16s %s", info.Name, info.Dir)
+		if len(info.Tags) > 0 {
+			line += "  [" + strings.Join(info.Tags, ", ") + "]"
+		}
+		if len(info.Processes) > 0 {
+			line += "  ← " + strings.Join(info.Processes, ", ")
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+// exportBookmarks writes bookmarks as a JSON array to FILE or stdout
+func exportBookmarks(state *State, tags []string, args []string) int {
+	infos := bookmarkInfos(state, tags)
+	data, err := json.MarshalIndent(infos, "", "  ")
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	data = append(data, '\n')
+
+	if len(args) == 0 || args[0] == "-" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(args[0], data, 0644); err != nil {
+		fmt.Printf("✗ Cannot write %s: %v\n", args[0], err)
+		return 1
+	}
+	fmt.Printf("✓ Exported %d bookmark(s) to %s\n", len(infos), args[0])
+	return 0
+}
+
+// importBookmarks merges bookmarks from an export
+// Existing bookmarks with the same name are replaced; process links are not
+// imported, since they refer to the exporter's processes
+func importBookmarks(state *State, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: marks import FILE")
+		return 1
+	}
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		fmt.Printf("✗ Cannot read %s: %v\n", args[0], err)
+		return 1
+	}
+
+	var infos []BookmarkInfo
+	if err := json.Unmarshal(data, &infos); err != nil {
+		fmt.Printf("✗ Not a bookmark export: %v\n", err)
+		return 1
+	}
+
+	imported, skipped := 0, 0
+	for _, info := range infos {
+		if info.Dir == "" || !validBookmarkName(info.Name) {
+			skipped++
+			continue
+		}
+		created := info.Created
+		if created == "" {
+			created = time.Now().Format(time.RFC3339)
+		}
+		state.SetBookmark(info.Name, Bookmark{Dir: info.Dir, Tags: info.Tags, Created: created}, "")
+		imported++
+	}
+	state.Save()
+
+	fmt.Printf("✓ Imported %d bookmark(s)\n", imported)
+	if skipped > 0 {
+		fmt.Printf("✗ Skipped %d with an invalid name or no directory\n", skipped)
+		return 1
+	}
+	return 0
+}
+
+// completeBookmarks completes @NAME references
+func completeBookmarks(state *State, partial string) []string {
+	prefix := strings.TrimPrefix(partial, bookmarkPrefix)
+	var matches []string
+	for name := range state.Bookmarks {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, bookmarkPrefix+name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,314 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ExecuteCommand executes iptp in command mode (non-interactive)
+// e.g., iptp goto /path
+func ExecuteCommand(state *State, args []string) int {
+	if len(args) == 0 {
+		return 0
+	}
+
+	cmd := args[0]
+	cmdArgs := args[1:]
+
+	// Get current process name (from environment or default)
+	currentProcess := os.Getenv("iptp_PROCESS")
+	if currentProcess == "" {
+		currentProcess = fmt.Sprintf("shell_%d", os.Getpid())
+	}
+
+	if command, ok := LookupCommand(cmd); ok {
+		if command.Exec == nil {
+			fmt.Printf("'%s' is only available inside the iptp shell\n", cmd)
+			fmt.Println("Run 'iptp' to start it")
+			return 1
+		}
+		return command.Exec(state, currentProcess, cmdArgs)
+	}
+
+	if path, ok := FindPlugin(cmd); ok {
+		return RunPlugin(state, currentProcess, path, cmdArgs)
+	}
+
+	fmt.Printf("Unknown command: %s\n", cmd)
+	fmt.Println("Run 'iptp help' for usage")
+	return 1
+}
+
+func cmdGotoNonInteractive(state *State, process string, args []string) int {
+	interactive, forget, args := parseGotoFlags(args)
+	if forget {
+		return forgetDirectories(state, args)
+	}
+	if len(args) == 0 && !interactive {
+		fmt.Println("Usage: iptp goto PATH | - | TERM... | -i TERM... | --forget [PATH...]")
+		return 1
+	}
+	if len(args) == 1 && args[0] == "-" && !interactive {
+		return cmdPreviousDir(state, process, parentMover(state, process))
+	}
+
+	path, err := resolveGotoTarget(state, args, interactive, bufio.NewReader(os.Stdin))
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	oldDir, _ := os.Getwd()
+
+	// Resolve relative paths; the calling shell is moved by the init wrapper
+	if err := os.Chdir(path); err != nil {
+		fmt.Printf("✗ Cannot change directory: %v\n", err)
+		return 1
+	}
+
+	newDir, _ := os.Getwd()
+	fmt.Printf("✓ Changed to: %s\n", newDir)
+	moveParent(state, process, newDir, oldDir)
+	offerProjectNameNonInteractive(state, process, newDir)
+	return 0
+}
+
+func cmdNameNonInteractive(state *State, process string, args []string) int {
+	explain, args := parseExplainFlag(args)
+	here, args := parseHereFlag(args)
+	if len(args) == 0 {
+		if proc, ok := state.GetProcess(process); ok {
+			fmt.Printf("Current process: %s\n", process)
+			fmt.Printf("Intention: %s\n", proc.Intention)
+		} else {
+			fmt.Printf("Current process: %s\n", process)
+		}
+		return 0
+	}
+
+	intention := joinArgs(args)
+	_, match := ParseIntent(intention, time.Now())
+	processName := match.Name
+
+	// The name may belong to another process
+	config, _ := LoadConfig(getConfigFilePath())
+	switch checkNameCollision(bufio.NewReader(os.Stdin), config, state, process, processName, intention, isTerminal(os.Stdin)) {
+	case collisionAttach:
+		return attachNonInteractive(state, processName)
+	case collisionSuffix:
+		processName = suffixedName(state, processName)
+	case collisionCancel:
+		fmt.Println("✗ Cancelled")
+		return 1
+	}
+
+	currentDir, _ := os.Getwd()
+	state.SetProcess(processName, intention, currentDir)
+	state.Save()
+
+	// The calling shell now belongs to the named process
+	parentAction("process", processName)
+
+	fmt.Printf("✓ Shell named: %s\n", processName)
+	fmt.Printf("  Intention: %s\n", intention)
+	if explain {
+		printIntentionMatch(match)
+		if proc, ok := state.GetProcess(processName); ok {
+			printIntent(proc.Intent)
+		}
+	}
+
+	proc, _ := state.GetProcess(processName)
+	if here {
+		state.Learn(proc.Intent, currentDir)
+		state.Save()
+	} else {
+		resolveIntentNonInteractive(state, processName, proc.Intent)
+	}
+	return 0
+}
+
+func cmdSaveNonInteractive(state *State, process string) int {
+	currentDir, _ := os.Getwd()
+	state.UpdateDirectory(process, currentDir, "")
+	state.Save()
+
+	fmt.Printf("✓ Saved state for: %s @ %s\n", process, currentDir)
+	return 0
+}
+
+func cmdListNonInteractive(state *State, args []string) int {
+	format, args, err := ParseOutputFlags(args)
+	if err != nil {
+		return OutputUsageError(err)
+	}
+	archived, all, args := parseArchivedFlags(args)
+	filter, rest, err := parseIntentFilter(args)
+	if err != nil {
+		return OutputUsageError(err)
+	}
+	if len(rest) > 0 {
+		return OutputUsageError(fmt.Errorf("unknown argument %q (see 'iptp help')", rest[0]))
+	}
+	if !format.Text() {
+		var infos []ProcessInfo
+		for _, info := range filterProcessInfos(sortedProcessInfos(state), filter) {
+			if all || archived == (info.Archived != "") {
+				infos = append(infos, info)
+			}
+		}
+		if infos == nil {
+			infos = []ProcessInfo{}
+		}
+		repos := processGitStatuses(state)
+		for i := range infos {
+			if st, ok := repos[infos[i].CurrentDir]; ok {
+				infos[i].Git = &st
+			}
+		}
+		return WriteOutput(format, infos)
+	}
+
+	processes := state.ListProcesses()
+	if len(processes) == 0 {
+		fmt.Println("No saved processes")
+		return 0
+	}
+
+	live := liveProcesses()
+	repos := processGitStatuses(state)
+	now := time.Now()
+	fmt.Println("=== Available Processes ===")
+	for _, name := range processes {
+		if proc, ok := state.GetProcess(name); ok && filter.Match(proc.Intent, now) && listArchived(proc, archived, all) {
+			fmt.Printf("  → %s: %s (PID: %d)%s%s%s%s\n", name, proc.CurrentDir, proc.PID, intentMarker(proc.Intent), gitMarker(repos, proc.CurrentDir), liveMarker(live, name, proc), archivedMarker(proc))
+		}
+	}
+	if !archived && !all {
+		printArchivedHint(state)
+	}
+	return 0
+}
+
+func cmdJumpNonInteractive(state *State, process string, args []string) int {
+	format, args, err := ParseOutputFlags(args)
+	if err != nil {
+		return OutputUsageError(err)
+	}
+	if len(args) == 0 {
+		return OutputError(format, 1, "Usage: iptp jump PROCESS")
+	}
+
+	targetProcess := args[0]
+	proc, ok := state.GetProcess(targetProcess)
+	if !ok {
+		return OutputError(format, 1, fmt.Sprintf("✗ Process '%s' not found", targetProcess))
+	}
+
+	if !format.Text() {
+		return WriteOutput(format, newProcessInfo(targetProcess, proc, liveProcesses()))
+	}
+	if proc.Archived != "" {
+		fmt.Println(archivedError(targetProcess))
+		return 1
+	}
+
+	fmt.Printf("Process: %s\n", targetProcess)
+	fmt.Printf("Directory: %s\n", proc.CurrentDir)
+	fmt.Printf("Intention: %s\n", proc.Intention)
+
+	// Through the init wrapper, the calling shell becomes the target
+	// process and moves there, as jump does inside the iptp shell
+	if hasParentShell() {
+		parentAction("process", targetProcess)
+		oldDir, _ := os.Getwd()
+		moveParent(state, targetProcess, proc.CurrentDir, oldDir)
+	}
+
+	return 0
+}
+
+func cmdStateNonInteractive(state *State, process string, args []string) int {
+	format, _, err := ParseOutputFlags(args)
+	if err != nil {
+		return OutputUsageError(err)
+	}
+
+	proc, ok := state.GetProcess(process)
+	if !ok {
+		return OutputError(format, 1, "No state for current process")
+	}
+
+	currentDir, _ := os.Getwd()
+
+	if !format.Text() {
+		info := StateInfo{
+			ProcessInfo: newProcessInfo(process, proc, liveProcesses()),
+			ShellDir:    currentDir,
+			ShellPID:    os.Getpid(),
+			Recordings:  proc.Recordings,
+		}
+		if info.Recordings == nil {
+			info.Recordings = []Recording{}
+		}
+		return WriteOutput(format, info)
+	}
+
+	fmt.Println("=== Current Process State (IPTP Format) ===")
+	fmt.Printf("Process: %s\n", process)
+	fmt.Printf("Directory: %s\n", currentDir)
+	fmt.Printf("PID: %d\n", os.Getpid())
+	fmt.Println()
+	fmt.Println("=== Intentions ===")
+	fmt.Printf("  \"%s\"\n", proc.Intention)
+	printIntent(proc.Intent)
+	fmt.Println()
+	fmt.Println("=== Pulses (Trivalent) ===")
+	for _, pulse := range proc.Pulses {
+		data, _ := json.Marshal(pulse)
+		fmt.Printf("  %s\n", data)
+	}
+	printSession(proc)
+	printRecordings(proc)
+
+	return 0
+}
+
+func cmdHelpNonInteractive() {
+	fmt.Println("iptp - IPTP Shell Process Manager")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  iptp              - Start interactive shell")
+	fmt.Println("  iptp COMMAND      - Execute single command")
+	fmt.Println()
+	printCommandHelp(func(cmd *Command) bool { return cmd.Exec != nil })
+	fmt.Println("Plugins:")
+	fmt.Println("  iptp NAME [ARGS]    - Run iptp-NAME from PATH (see 'iptp plugins')")
+	fmt.Println()
+	fmt.Println("Interactive Mode:")
+	fmt.Println("  Run 'iptp' with no arguments to enter interactive shell")
+	fmt.Println("  Inside iptp shell, all commands work plus:")
+	fmt.Println("    - getmethere: Interactive directory finder")
+	fmt.Println("    - back: Navigate back in history")
+	fmt.Println("    - Execute any script or command")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  iptp                                 # Start shell")
+	fmt.Println("  iptp goto /var/www                   # Change directory")
+	fmt.Println("  iptp name \"working on authentication\"  # Name process")
+	fmt.Println("  iptp list                            # List processes")
+	fmt.Println()
+}
+
+func joinArgs(args []string) string {
+	result := ""
+	for i, arg := range args {
+		if i > 0 {
+			result += " "
+		}
+		result += arg
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultPrompt reproduces the original hardcoded prompt
+const DefaultPrompt = "[{name}] {dir}$ "
+
+// Config holds user settings read from the rc file
+type Config struct {
+	Prompt string
+	values map[string]string
+}
+
+// NewConfig creates a config with default settings
+func NewConfig() *Config {
+	return &Config{
+		Prompt: DefaultPrompt,
+		values: make(map[string]string),
+	}
+}
+
+// LoadConfig loads settings from an rc file
+// Lines look like: key = value   (values may be double-quoted)
+// Blank lines and lines starting with # are ignored
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	config := NewConfig()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		// Quoted values keep surrounding spaces and allow escapes like \n
+		if strings.HasPrefix(value, "\"") {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+
+		config.values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if prompt, ok := config.values["prompt"]; ok {
+		config.Prompt = prompt
+	}
+
+	return config, nil
+}
+
+// Get returns a raw setting from the rc file
+func (c *Config) Get(key string) (string, bool) {
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// getConfigFilePath returns the path to the rc file (~/.iptprc)
+// IPTPRC overrides the location
+func getConfigFilePath() string {
+	if path := os.Getenv("IPTPRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".iptprc"
+	}
+	return filepath.Join(home, ".iptprc")
+}
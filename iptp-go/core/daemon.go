@@ -0,0 +1,877 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// daemonDialTimeout bounds how long clients wait to find a running daemon
+const daemonDialTimeout = 200 * time.Millisecond
+
+// daemonCallTimeout bounds a single request/response round trip
+const daemonCallTimeout = 2 * time.Second
+
+// DaemonRequest is one line sent to iptpd
+type DaemonRequest struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// DaemonResponse is the reply to a request with the same ID
+type DaemonResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// DaemonEvent is pushed to connections that called "subscribe"
+type DaemonEvent struct {
+	Event     string      `json:"event"` // process.updated, process.deleted, pulse.set, bookmarks.updated, dns.started, dns.stopped
+	Name      string      `json:"name,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// DNSController is the part of the DNS router the daemon can drive
+// The dnsrouting build sets DaemonDNS; plain iptp has no DNS router
+type DNSController interface {
+	Start() error
+	Stop() error
+	IsRunning() bool
+	GetStats() map[string]interface{}
+}
+
+// DaemonDNS returns the DNS router to control, or nil if not built in
+var DaemonDNS func() DNSController
+
+// getDaemonSocketPath returns the Unix socket iptpd listens on, inside the
+// private runtime directory; IPTPD_SOCKET overrides the location
+func getDaemonSocketPath() (string, error) {
+	if path := os.Getenv("IPTPD_SOCKET"); path != "" {
+		return path, nil
+	}
+	dir, err := getRuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "iptpd.sock"), nil
+}
+
+// ===== Server =====
+
+// Daemon owns the state and serves it over a Unix socket
+type Daemon struct {
+	state       *State
+	mu          sync.Mutex
+	listener    net.Listener
+	subscribers map[chan DaemonEvent]bool
+	subMu       sync.Mutex
+	started     time.Time
+	index       *DirIndex
+}
+
+// RunDaemon serves the state file until stopped (this is what iptpd runs)
+func RunDaemon(stateFile string) error {
+	state, err := LoadState(stateFile)
+	if err != nil {
+		state = NewState(stateFile)
+	}
+
+	socketPath, err := getDaemonSocketPath()
+	if err != nil {
+		return err
+	}
+	if client, err := DialDaemon(); err == nil {
+		client.Close()
+		return fmt.Errorf("iptpd is already running on %s", socketPath)
+	}
+	os.Remove(socketPath) // stale socket from a crashed daemon
+
+	listener, err := listenUnixPrivate(socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	d := &Daemon{
+		state:       state,
+		listener:    listener,
+		subscribers: make(map[chan DaemonEvent]bool),
+		started:     time.Now(),
+		index:       LoadDirIndex(),
+	}
+
+	fmt.Printf("✓ iptpd listening on %s\n", socketPath)
+	fmt.Printf("  State file: %s\n", stateFile)
+
+	stopIndexer := make(chan struct{})
+	defer close(stopIndexer)
+	go d.index.runIndexer(stopIndexer)
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			// Closed by the shutdown method
+			return nil
+		}
+		go d.serve(conn)
+	}
+}
+
+// serve handles one client connection
+func (d *Daemon) serve(conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		var req DaemonRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			encoder.Encode(DaemonResponse{Error: "bad request: " + err.Error()})
+			continue
+		}
+
+		if req.Method == "subscribe" {
+			encoder.Encode(DaemonResponse{ID: req.ID, Result: json.RawMessage(`true`)})
+			d.stream(conn, encoder)
+			return
+		}
+
+		result, err := d.handle(req)
+		resp := DaemonResponse{ID: req.ID}
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Result, _ = json.Marshal(result)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return
+		}
+
+		if req.Method == "shutdown" {
+			d.listener.Close()
+			return
+		}
+	}
+}
+
+// handle dispatches a request to the matching method
+func (d *Daemon) handle(req DaemonRequest) (interface{}, error) {
+	var params struct {
+		Name        string                `json:"name"`
+		Process     json.RawMessage       `json:"process"`
+		Base        json.RawMessage       `json:"base"`
+		Pulse       *Pulse                `json:"pulse"`
+		Bookmarks   map[string]Bookmark   `json:"bookmarks"`
+		Resolutions map[string]Resolution `json:"resolutions"`
+		Removed     []string              `json:"removed"`
+		Visits      []string              `json:"visits"`
+		Forget      []string              `json:"forget"`
+		Roots       []string              `json:"roots"`
+	}
+	if len(req.Params) > 0 {
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, fmt.Errorf("bad params: %v", err)
+		}
+	}
+
+	switch req.Method {
+	case "ping", "shutdown":
+		return d.status(), nil
+
+	case "list":
+		// Marshal under the lock; processes share slices with the state
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		data, err := json.Marshal(d.state.Processes)
+		return json.RawMessage(data), err
+
+	case "get":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		proc, ok := d.state.GetProcess(params.Name)
+		if !ok {
+			return nil, fmt.Errorf("process '%s' not found", params.Name)
+		}
+		data, err := json.Marshal(proc)
+		return json.RawMessage(data), err
+
+	case "update":
+		if params.Name == "" || len(params.Process) == 0 {
+			return nil, fmt.Errorf("update needs name and process")
+		}
+		d.mu.Lock()
+		current, ok := d.state.Processes[params.Name]
+		if !ok {
+			params.Base = nil // created (or re-created) by this update
+		}
+		proc, err := mergeProcess(current, params.Base, params.Process)
+		if err == nil {
+			d.state.Processes[params.Name] = proc
+			err = d.state.saveFile()
+		}
+		d.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		d.publish("process.updated", params.Name, proc)
+		return true, nil
+
+	case "delete":
+		d.mu.Lock()
+		_, ok := d.state.Processes[params.Name]
+		delete(d.state.Processes, params.Name)
+		err := d.state.saveFile()
+		d.mu.Unlock()
+		if ok {
+			d.publish("process.deleted", params.Name, nil)
+		}
+		return ok, err
+
+	case "bookmarks.get":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		data, err := json.Marshal(d.state.Bookmarks)
+		return json.RawMessage(data), err
+
+	case "bookmarks.set":
+		// Sets and removes the given bookmarks; the rest are other shells'
+		d.mu.Lock()
+		if d.state.Bookmarks == nil {
+			d.state.Bookmarks = make(map[string]Bookmark)
+		}
+		for name, bookmark := range params.Bookmarks {
+			d.state.Bookmarks[name] = bookmark
+		}
+		for _, name := range params.Removed {
+			delete(d.state.Bookmarks, name)
+		}
+		err := d.state.saveFile()
+		data, _ := json.Marshal(d.state.Bookmarks)
+		d.mu.Unlock()
+		d.publish("bookmarks.updated", "", json.RawMessage(data))
+		return true, err
+
+	case "resolutions.get":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		data, err := json.Marshal(d.state.Resolutions)
+		return json.RawMessage(data), err
+
+	case "resolutions.set":
+		d.mu.Lock()
+		if d.state.Resolutions == nil {
+			d.state.Resolutions = make(map[string]Resolution)
+		}
+		for subject, resolution := range params.Resolutions {
+			d.state.Resolutions[subject] = resolution
+		}
+		for _, subject := range params.Removed {
+			delete(d.state.Resolutions, subject)
+		}
+		err := d.state.saveFile()
+		d.mu.Unlock()
+		return true, err
+
+	case "dirs.get":
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		data, err := json.Marshal(d.state.Directories)
+		return json.RawMessage(data), err
+
+	case "dirs.update":
+		d.mu.Lock()
+		now := time.Now()
+		for _, dir := range params.Visits {
+			d.state.visitDirectory(dir, now)
+		}
+		for _, dir := range params.Forget {
+			delete(d.state.Directories, dir)
+		}
+		err := d.state.saveFile()
+		d.mu.Unlock()
+		return true, err
+
+	case "index.status":
+		return d.index.Status(), nil
+
+	case "index.rebuild":
+		d.index.requestRescan(true)
+		return true, nil
+
+	case "index.roots":
+		if len(params.Roots) == 0 {
+			return nil, fmt.Errorf("index.roots needs roots")
+		}
+		d.index.SetRoots(params.Roots)
+		d.index.requestRescan(false)
+		return true, nil
+
+	case "pulses.set":
+		if params.Pulse == nil {
+			return nil, fmt.Errorf("pulses.set needs name and pulse")
+		}
+		d.mu.Lock()
+		ok := d.state.SetPulse(params.Name, *params.Pulse)
+		err := d.state.saveFile()
+		d.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("process '%s' not found", params.Name)
+		}
+		d.publish("pulse.set", params.Name, params.Pulse)
+		return true, err
+
+	case "pulses.evaluate":
+		d.mu.Lock()
+		pulses, ok := d.state.EvaluatePulses(params.Name)
+		err := d.state.saveFile()
+		proc := d.state.Processes[params.Name]
+		d.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("process '%s' not found", params.Name)
+		}
+		d.publish("process.updated", params.Name, proc)
+		return map[string]interface{}{"pulses": pulses, "summary": PulseSummary(pulses)}, err
+
+	case "dns.status", "dns.start", "dns.stop":
+		return d.handleDNS(req.Method)
+
+	default:
+		return nil, fmt.Errorf("unknown method: %s", req.Method)
+	}
+}
+
+// mergeProcess applies an update to the stored process field by field:
+// only fields the client changed since base (the version it last saw) are
+// taken, so shells changing different fields of one process don't undo each
+// other. Without a base the update replaces the process.
+func mergeProcess(current Process, base, update json.RawMessage) (Process, error) {
+	var merged Process
+	if len(base) == 0 {
+		err := json.Unmarshal(update, &merged)
+		return merged, err
+	}
+
+	var baseFields, updateFields, fields map[string]json.RawMessage
+	if err := json.Unmarshal(base, &baseFields); err != nil {
+		return merged, err
+	}
+	if err := json.Unmarshal(update, &updateFields); err != nil {
+		return merged, err
+	}
+	currentData, err := json.Marshal(current)
+	if err != nil {
+		return merged, err
+	}
+	if err := json.Unmarshal(currentData, &fields); err != nil {
+		return merged, err
+	}
+
+	for key, value := range updateFields {
+		if string(baseFields[key]) != string(value) {
+			fields[key] = value
+		}
+	}
+	for key := range baseFields {
+		if _, ok := updateFields[key]; !ok {
+			delete(fields, key) // an omitempty field the client cleared
+		}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return merged, err
+	}
+	err = json.Unmarshal(data, &merged)
+	return merged, err
+}
+
+// handleDNS drives the DNS router when this build includes one
+func (d *Daemon) handleDNS(method string) (interface{}, error) {
+	if DaemonDNS == nil {
+		return nil, fmt.Errorf("DNS router not available in this build (use the dnsrouting build)")
+	}
+	router := DaemonDNS()
+
+	switch method {
+	case "dns.start":
+		if err := router.Start(); err != nil {
+			return nil, err
+		}
+		d.publish("dns.started", "", nil)
+	case "dns.stop":
+		if err := router.Stop(); err != nil {
+			return nil, err
+		}
+		d.publish("dns.stopped", "", nil)
+	}
+	return router.GetStats(), nil
+}
+
+// status describes the daemon for ping and 'iptp daemon status'
+func (d *Daemon) status() map[string]interface{} {
+	d.mu.Lock()
+	processes := len(d.state.Processes)
+	d.mu.Unlock()
+	d.subMu.Lock()
+	subscribers := len(d.subscribers)
+	d.subMu.Unlock()
+
+	return map[string]interface{}{
+		"pid":         os.Getpid(),
+		"socket":      d.listener.Addr().String(),
+		"state_file":  d.state.filepath,
+		"processes":   processes,
+		"subscribers": subscribers,
+		"started":     d.started.Format(time.RFC3339),
+		"dns":         DaemonDNS != nil,
+	}
+}
+
+// stream sends events to a subscribed connection until it goes away
+func (d *Daemon) stream(conn net.Conn, encoder *json.Encoder) {
+	events := make(chan DaemonEvent, 64)
+	d.subMu.Lock()
+	d.subscribers[events] = true
+	d.subMu.Unlock()
+
+	defer func() {
+		d.subMu.Lock()
+		delete(d.subscribers, events)
+		d.subMu.Unlock()
+	}()
+
+	// Notice the client hanging up even when no events arrive
+	gone := make(chan struct{})
+	go func() {
+		buf := make([]byte, 1)
+		for {
+			if _, err := conn.Read(buf); err != nil {
+				close(gone)
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event := <-events:
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// publish sends an event to every subscriber, dropping it for slow ones
+func (d *Daemon) publish(event, name string, data interface{}) {
+	ev := DaemonEvent{Event: event, Name: name, Data: data, Timestamp: time.Now().Format(time.RFC3339)}
+
+	d.subMu.Lock()
+	defer d.subMu.Unlock()
+	for ch := range d.subscribers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// ===== Client =====
+
+// DaemonClient talks to a running iptpd
+type DaemonClient struct {
+	conn    net.Conn
+	scanner *bufio.Scanner
+	nextID  int
+	mu      sync.Mutex
+}
+
+// DialDaemon connects to iptpd, failing fast when it isn't running
+func DialDaemon() (*DaemonClient, error) {
+	socketPath, err := getDaemonSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialOwnSocket(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &DaemonClient{conn: conn, scanner: scanner}, nil
+}
+
+// Call sends a request and decodes the result into result (if non-nil)
+func (c *DaemonClient) Call(method string, params interface{}, result interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	req := DaemonRequest{ID: c.nextID, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		req.Params = data
+	}
+
+	c.conn.SetDeadline(time.Now().Add(daemonCallTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		return err
+	}
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("iptpd closed the connection")
+	}
+
+	var resp DaemonResponse
+	if err := json.Unmarshal(c.scanner.Bytes(), &resp); err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("%s", resp.Error)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
+	}
+	return nil
+}
+
+// Subscribe switches the connection to event streaming
+// handler is called for each event until it returns false or the daemon exits
+func (c *DaemonClient) Subscribe(handler func(DaemonEvent) bool) error {
+	if err := c.Call("subscribe", nil, nil); err != nil {
+		return err
+	}
+	for c.scanner.Scan() {
+		var event DaemonEvent
+		if err := json.Unmarshal(c.scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if !handler(event) {
+			return nil
+		}
+	}
+	return c.scanner.Err()
+}
+
+// Close disconnects from the daemon
+func (c *DaemonClient) Close() error {
+	return c.conn.Close()
+}
+
+// ===== iptp daemon command =====
+
+// cmdDaemonNonInteractive handles 'iptp daemon run|start|stop|status|events'
+func cmdDaemonNonInteractive(state *State, process string, args []string) int {
+	sub := "status"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "run":
+		if err := RunDaemon(state.filepath); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "start":
+		if client, err := DialDaemon(); err == nil {
+			client.Close()
+			fmt.Println("iptpd is already running")
+			return 0
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		cmd := exec.Command(exe, "daemon", "run")
+		cmd.SysProcAttr = detachedProcAttr()
+		if err := cmd.Start(); err != nil {
+			fmt.Printf("✗ Cannot start iptpd: %v\n", err)
+			return 1
+		}
+		cmd.Process.Release()
+
+		// Wait for the socket to come up
+		for i := 0; i < 20; i++ {
+			time.Sleep(50 * time.Millisecond)
+			if client, err := DialDaemon(); err == nil {
+				client.Close()
+				socketPath, _ := getDaemonSocketPath()
+				fmt.Printf("✓ iptpd started (socket %s)\n", socketPath)
+				return 0
+			}
+		}
+		fmt.Println("✗ iptpd did not come up")
+		return 1
+
+	case "stop":
+		client, err := DialDaemon()
+		if err != nil {
+			fmt.Println("iptpd is not running")
+			return 0
+		}
+		defer client.Close()
+		if err := client.Call("shutdown", nil, nil); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		fmt.Println("✓ iptpd stopped")
+		return 0
+
+	case "status":
+		client, err := DialDaemon()
+		if err != nil {
+			fmt.Println("Status: ✗ STOPPED (shells use the state file directly)")
+			return 1
+		}
+		defer client.Close()
+		var status map[string]interface{}
+		if err := client.Call("ping", nil, &status); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		fmt.Println("Status: ✓ RUNNING")
+		keys := make([]string, 0, len(status))
+		for k := range status {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  %s: %v\n", k, status[k])
+		}
+		return 0
+
+	case "events":
+		client, err := DialDaemon()
+		if err != nil {
+			fmt.Println("iptpd is not running")
+			return 1
+		}
+		defer client.Close()
+		err = client.Subscribe(func(event DaemonEvent) bool {
+			data, _ := json.Marshal(event)
+			fmt.Println(string(data))
+			return true
+		})
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		return 0
+
+	default:
+		fmt.Println("Usage: iptp daemon [run|start|stop|status|events]")
+		return 1
+	}
+}
+
+// ===== State as a daemon client =====
+
+// Refresh reloads every process from iptpd
+func (s *State) Refresh() error {
+	if s.daemon == nil {
+		return nil
+	}
+	var processes map[string]Process
+	if err := s.daemon.Call("list", nil, &processes); err != nil {
+		return err
+	}
+	if processes == nil {
+		processes = make(map[string]Process)
+	}
+	var bookmarks map[string]Bookmark
+	if err := s.daemon.Call("bookmarks.get", nil, &bookmarks); err != nil {
+		return err
+	}
+
+	var resolutions map[string]Resolution
+	if err := s.daemon.Call("resolutions.get", nil, &resolutions); err != nil {
+		return err
+	}
+
+	var dirs map[string]DirVisit
+	if err := s.daemon.Call("dirs.get", nil, &dirs); err != nil {
+		return err
+	}
+
+	s.Processes = processes
+	s.Bookmarks = bookmarks
+	s.Resolutions = resolutions
+	s.Directories = dirs
+	s.markSaved()
+	return nil
+}
+
+// Sync pushes local changes to iptpd and picks up those of other shells
+// Without a daemon it does nothing
+func (s *State) Sync() {
+	if s.daemon == nil {
+		return
+	}
+	if err := s.Save(); err != nil || s.daemon == nil {
+		return
+	}
+	if err := s.Refresh(); err != nil {
+		s.daemon.Close()
+		s.daemon = nil
+	}
+}
+
+// saveDaemon sends only the processes changed since the last sync, so
+// shells working on different processes don't overwrite each other
+func (s *State) saveDaemon() error {
+	current := snapshotProcesses(s.Processes)
+
+	for name, data := range current {
+		if s.snapshot[name] == data {
+			continue
+		}
+		params := map[string]json.RawMessage{"process": json.RawMessage(data)}
+		params["name"], _ = json.Marshal(name)
+		if base, ok := s.snapshot[name]; ok {
+			params["base"] = json.RawMessage(base)
+		}
+		if err := s.daemon.Call("update", params, nil); err != nil {
+			return err
+		}
+	}
+	for name := range s.snapshot {
+		if _, ok := current[name]; !ok {
+			if err := s.daemon.Call("delete", map[string]string{"name": name}, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Bookmarks and resolutions go by name too: only those set or removed
+	// here, so another shell's new ones stay
+	bookmarks := snapshotEntries(s.Bookmarks)
+	if set, removed := entryChanges(bookmarks, s.bookmarkSnapshot); len(set) > 0 || len(removed) > 0 {
+		changed := make(map[string]Bookmark, len(set))
+		for _, name := range set {
+			changed[name] = s.Bookmarks[name]
+		}
+		params := map[string]interface{}{"bookmarks": changed, "removed": removed}
+		if err := s.daemon.Call("bookmarks.set", params, nil); err != nil {
+			return err
+		}
+		s.bookmarkSnapshot = bookmarks
+	}
+
+	resolutions := snapshotEntries(s.Resolutions)
+	if set, removed := entryChanges(resolutions, s.resolutionSnapshot); len(set) > 0 || len(removed) > 0 {
+		changed := make(map[string]Resolution, len(set))
+		for _, subject := range set {
+			changed[subject] = s.Resolutions[subject]
+		}
+		params := map[string]interface{}{"resolutions": changed, "removed": removed}
+		if err := s.daemon.Call("resolutions.set", params, nil); err != nil {
+			return err
+		}
+		s.resolutionSnapshot = resolutions
+	}
+
+	if len(s.dirVisits) > 0 || len(s.dirForgets) > 0 {
+		params := map[string][]string{"visits": s.dirVisits, "forget": s.dirForgets}
+		if err := s.daemon.Call("dirs.update", params, nil); err != nil {
+			return err
+		}
+	}
+
+	s.snapshot = current
+	return nil
+}
+
+// markSaved notes the processes, bookmarks and resolutions as they are now,
+// so the next save to iptpd sends only what changes after this
+func (s *State) markSaved() {
+	s.snapshot = snapshotProcesses(s.Processes)
+	s.bookmarkSnapshot = snapshotEntries(s.Bookmarks)
+	s.resolutionSnapshot = snapshotEntries(s.Resolutions)
+}
+
+// joinDaemon switches a state read from the file over to iptpd once it is
+// running, so shells started before the daemon stop writing the file
+// behind its back; their unsaved changes go to the daemon on this Save
+func (s *State) joinDaemon() {
+	if !daemonAllowed() {
+		return
+	}
+	if client, err := DialDaemon(); err == nil {
+		s.daemon = client
+	}
+}
+
+// daemonAllowed reports whether state may go through iptpd
+// IPTP_NO_DAEMON=1 forces direct file access
+func daemonAllowed() bool {
+	return os.Getenv("IPTP_NO_DAEMON") == ""
+}
+
+// snapshotJSON serializes a value for change detection
+func snapshotJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// snapshotEntries serializes each bookmark or resolution for change detection
+func snapshotEntries(v interface{}) map[string]string {
+	data, _ := json.Marshal(v)
+	var entries map[string]json.RawMessage
+	json.Unmarshal(data, &entries)
+	snapshot := make(map[string]string, len(entries))
+	for name, entry := range entries {
+		snapshot[name] = string(entry)
+	}
+	return snapshot
+}
+
+// entryChanges lists the bookmarks or resolutions added or changed, and
+// those removed, since the base snapshot
+func entryChanges(current, base map[string]string) (set, removed []string) {
+	for name, data := range current {
+		if old, ok := base[name]; !ok || old != data {
+			set = append(set, name)
+		}
+	}
+	for name := range base {
+		if _, ok := current[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	return set, removed
+}
+
+// snapshotProcesses serializes each process for change detection
+func snapshotProcesses(processes map[string]Process) map[string]string {
+	snapshot := make(map[string]string, len(processes))
+	for name, proc := range processes {
+		data, _ := json.Marshal(proc)
+		snapshot[name] = string(data)
+	}
+	return snapshot
+}
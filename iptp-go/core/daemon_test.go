@@ -0,0 +1,89 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeProcess(t *testing.T) {
+	base := Process{
+		Intention:  "working on the api",
+		CurrentDir: "/src/api",
+		History:    []string{"/src"},
+		Bookmarks:  []string{"api"},
+	}
+	marshal := func(p Process) json.RawMessage {
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		current func(p *Process) // another shell's changes, already stored
+		update  func(p *Process) // this client's changes since base
+		noBase  bool
+		want    func(p *Process)
+	}{
+		{
+			name:    "different fields are both kept",
+			current: func(p *Process) { p.Intention = "api v2" },
+			update:  func(p *Process) { p.CurrentDir = "/src/api/v2" },
+			want: func(p *Process) {
+				p.Intention = "api v2"
+				p.CurrentDir = "/src/api/v2"
+			},
+		},
+		{
+			name:    "the update wins on the same field",
+			current: func(p *Process) { p.CurrentDir = "/tmp" },
+			update:  func(p *Process) { p.CurrentDir = "/src/api/v2" },
+			want:    func(p *Process) { p.CurrentDir = "/src/api/v2" },
+		},
+		{
+			name:    "unchanged fields don't undo the stored ones",
+			current: func(p *Process) { p.History = []string{"/src", "/etc"} },
+			update:  func(p *Process) {},
+			want:    func(p *Process) { p.History = []string{"/src", "/etc"} },
+		},
+		{
+			name:    "a cleared omitempty field is removed",
+			current: func(p *Process) { p.Intention = "api v2" },
+			update:  func(p *Process) { p.Bookmarks = nil },
+			want: func(p *Process) {
+				p.Intention = "api v2"
+				p.Bookmarks = nil
+			},
+		},
+		{
+			name:    "without a base the update replaces the process",
+			current: func(p *Process) { p.Intention = "api v2" },
+			update:  func(p *Process) { p.CurrentDir = "/src/api/v2" },
+			noBase:  true,
+			want:    func(p *Process) { p.CurrentDir = "/src/api/v2" },
+		},
+	}
+	for _, tt := range tests {
+		current, update, want := base, base, base
+		current.History = append([]string(nil), base.History...)
+		tt.current(&current)
+		tt.update(&update)
+		tt.want(&want)
+
+		baseData := marshal(base)
+		if tt.noBase {
+			baseData = nil
+		}
+		got, err := mergeProcess(current, baseData, marshal(update))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(marshal(got), marshal(want)) {
+			t.Errorf("%s: merged %s, want %s", tt.name, marshal(got), marshal(want))
+		}
+	}
+}
@@ -0,0 +1,731 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Directory index
+//
+// Walking the tree on every goto/getmethere is slow and depth-limited, so
+// the directories under the index roots (default: home) are kept in a file.
+// iptpd keeps it current with filesystem notifications (Linux) and periodic
+// rescans; without iptpd an interactive shell rescans it when it is stale.
+// A rescan only re-reads directories whose mtime changed.
+
+// indexRescanInterval is how often the index is checked for changes
+const indexRescanInterval = 10 * time.Minute
+
+// indexSaveDelay batches notification updates before writing the file
+const indexSaveDelay = 5 * time.Second
+
+// indexFileHeader starts every index file
+const indexFileHeader = "# iptp directory index v1"
+
+// DirIndex is the set of directories under the index roots
+type DirIndex struct {
+	mu      sync.RWMutex
+	path    string
+	roots   []string
+	dirs    map[string]int64 // directory -> mtime (Unix nanoseconds)
+	built   time.Time
+	updated time.Time
+	dirty   bool
+
+	rulesHash   string // ignore rules the index was built with
+	configRoots bool   // roots come from search_roots in ~/.iptprc
+	rules       *IgnoreRules
+
+	building bool
+	watching int       // directories watched for changes (Linux, under iptpd)
+	requests chan bool // rescans asked of runIndexer; true rebuilds
+}
+
+// IndexStatus describes the index for 'index status' and iptpd
+type IndexStatus struct {
+	File        string   `json:"file"`
+	Roots       []string `json:"roots"`
+	Directories int      `json:"directories"`
+	Built       string   `json:"built,omitempty"`
+	Updated     string   `json:"updated,omitempty"`
+	Building    bool     `json:"building"`
+	Watching    int      `json:"watching"`
+}
+
+// getIndexFilePath returns where the directory index is kept
+// IPTP_INDEX overrides the location
+func getIndexFilePath() string {
+	if path := os.Getenv("IPTP_INDEX"); path != "" {
+		return path
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "iptp", "dirindex")
+}
+
+// defaultIndexRoots is indexed until roots are set with 'index roots add'
+func defaultIndexRoots() []string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{home}
+}
+
+// LoadDirIndex reads the index file; a missing file gives an empty index
+func LoadDirIndex() *DirIndex {
+	ix := &DirIndex{
+		path:     getIndexFilePath(),
+		roots:    defaultIndexRoots(),
+		dirs:     make(map[string]int64),
+		requests: make(chan bool, 1),
+	}
+
+	f, err := os.Open(ix.path)
+	if err != nil {
+		return ix.applySettings()
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var roots []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "root":
+			roots = append(roots, value)
+		case "built":
+			ix.built, _ = time.Parse(time.RFC3339, value)
+		case "updated":
+			ix.updated, _ = time.Parse(time.RFC3339, value)
+		case "rules":
+			ix.rulesHash = value
+		default:
+			if mtime, err := strconv.ParseInt(key, 10, 64); err == nil {
+				ix.dirs[value] = mtime
+			}
+		}
+	}
+	if roots != nil {
+		ix.roots = roots
+	}
+	return ix.applySettings()
+}
+
+// applySettings takes roots from search_roots in ~/.iptprc when set
+func (ix *DirIndex) applySettings() *DirIndex {
+	if roots := loadSearchSettings().Roots; len(roots) > 0 {
+		ix.roots = roots
+		ix.configRoots = true
+	}
+	return ix
+}
+
+// RulesChanged reports whether the index was built under other ignore rules
+func (ix *DirIndex) RulesChanged() bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.rulesHash != searchIgnoreRules().Hash()
+}
+
+// Save writes the index file (write then rename, so readers never see half)
+func (ix *DirIndex) Save() error {
+	ix.mu.RLock()
+	var b strings.Builder
+	b.WriteString(indexFileHeader + "\n")
+	for _, root := range ix.roots {
+		fmt.Fprintf(&b, "root\t%s\n", root)
+	}
+	fmt.Fprintf(&b, "built\t%s\n", ix.built.Format(time.RFC3339))
+	fmt.Fprintf(&b, "updated\t%s\n", ix.updated.Format(time.RFC3339))
+	fmt.Fprintf(&b, "rules\t%s\n", ix.rulesHash)
+	for dir, mtime := range ix.dirs {
+		fmt.Fprintf(&b, "%d\t%s\n", mtime, dir)
+	}
+	ix.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(ix.path), 0700); err != nil {
+		return err
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", ix.path, os.Getpid())
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	ix.mu.Lock()
+	ix.dirty = false
+	ix.mu.Unlock()
+	return nil
+}
+
+// Roots returns the directories the index covers
+func (ix *DirIndex) Roots() []string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return append([]string(nil), ix.roots...)
+}
+
+// SetRoots changes the roots; the next Rescan indexes new ones and drops
+// directories no longer under any root
+func (ix *DirIndex) SetRoots(roots []string) {
+	ix.mu.Lock()
+	ix.roots = roots
+	ix.dirty = true
+	ix.mu.Unlock()
+}
+
+// Len returns the number of indexed directories
+func (ix *DirIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.dirs)
+}
+
+// Stale reports whether the index has not been checked for a while
+func (ix *DirIndex) Stale() bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return time.Since(ix.updated) > indexRescanInterval
+}
+
+// Covers reports whether dir is under one of the roots
+func (ix *DirIndex) Covers(dir string) bool {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return underAnyRoot(dir, ix.roots)
+}
+
+// underAnyRoot reports whether dir is one of the roots or below one
+func underAnyRoot(dir string, roots []string) bool {
+	for _, root := range roots {
+		if isUnder(dir, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnder reports whether dir is root or below it
+func isUnder(dir, root string) bool {
+	if dir == root {
+		return true
+	}
+	if !strings.HasSuffix(root, string(os.PathSeparator)) {
+		root += string(os.PathSeparator)
+	}
+	return strings.HasPrefix(dir, root)
+}
+
+// Rebuild forgets everything and walks the roots again
+func (ix *DirIndex) Rebuild() {
+	ix.mu.Lock()
+	if ix.building {
+		ix.mu.Unlock()
+		return
+	}
+	ix.dirs = make(map[string]int64)
+	ix.mu.Unlock()
+	ix.Rescan()
+
+	ix.mu.Lock()
+	ix.built = ix.updated
+	ix.mu.Unlock()
+}
+
+// Rescan brings the index up to date: new roots are walked, directories
+// whose mtime changed are re-read, and vanished directories are dropped
+func (ix *DirIndex) Rescan() {
+	ix.mu.Lock()
+	if ix.building {
+		ix.mu.Unlock()
+		return
+	}
+	ix.building = true
+	roots := append([]string(nil), ix.roots...)
+
+	// Fresh rules, so edited .gitignore files take effect
+	settings := loadSearchSettings()
+	ix.rules = NewIgnoreRules(settings.Excludes, settings.Network)
+	ix.rulesHash = ix.rules.Hash()
+	rules := ix.rules
+
+	// Drop directories outside the roots, note roots not indexed yet
+	for dir := range ix.dirs {
+		if !underAnyRoot(dir, roots) {
+			delete(ix.dirs, dir)
+			ix.dirty = true
+		}
+	}
+	var walk []string
+	for _, root := range roots {
+		if _, ok := ix.dirs[root]; !ok {
+			walk = append(walk, root)
+		}
+	}
+	known := make(map[string]int64, len(ix.dirs))
+	for dir, mtime := range ix.dirs {
+		known[dir] = mtime
+	}
+	ix.mu.Unlock()
+
+	// Re-read directories that changed since they were indexed
+	children := make(map[string][]string)
+	for dir := range known {
+		parent := filepath.Dir(dir)
+		children[parent] = append(children[parent], dir)
+	}
+	for dir, mtime := range known {
+		info, err := os.Lstat(dir)
+		if err != nil || !info.IsDir() {
+			ix.removeTree(dir)
+			continue
+		}
+		if info.ModTime().UnixNano() == mtime {
+			continue
+		}
+		present := make(map[string]bool)
+		for _, sub := range readSubdirs(dir, rules) {
+			present[sub.path] = true
+			if _, ok := known[sub.path]; !ok {
+				walk = append(walk, sub.path)
+			}
+		}
+		for _, sub := range children[dir] {
+			if !present[sub] {
+				ix.removeTree(sub)
+			}
+		}
+		ix.set(dir, info.ModTime().UnixNano())
+	}
+
+	ix.addTrees(walk, rules)
+
+	ix.mu.Lock()
+	ix.building = false
+	ix.updated = time.Now()
+	if ix.built.IsZero() {
+		ix.built = ix.updated
+	}
+	ix.dirty = true
+	ix.mu.Unlock()
+}
+
+// set records a directory
+func (ix *DirIndex) set(dir string, mtime int64) {
+	ix.mu.Lock()
+	ix.dirs[dir] = mtime
+	ix.dirty = true
+	ix.mu.Unlock()
+}
+
+// removeTree drops a directory and everything below it
+func (ix *DirIndex) removeTree(dir string) []string {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	var removed []string
+	for d := range ix.dirs {
+		if isUnder(d, dir) {
+			delete(ix.dirs, d)
+			removed = append(removed, d)
+		}
+	}
+	if len(removed) > 0 {
+		ix.dirty = true
+	}
+	return removed
+}
+
+// addTrees walks directories concurrently and indexes them and their subtrees
+// Returns the directories added
+func (ix *DirIndex) addTrees(tops []string, rules *IgnoreRules) []string {
+	var added []string
+	walkDirs(tops, rules, func(dir string, mtime int64) {
+		ix.mu.Lock()
+		ix.dirs[dir] = mtime
+		ix.dirty = true
+		added = append(added, dir)
+		ix.mu.Unlock()
+	})
+	return added
+}
+
+// subdir is a child directory found by readSubdirs
+type subdir struct {
+	path  string
+	mtime int64
+}
+
+// readSubdirs lists the child directories of dir that the rules do not skip
+// (symlinks are not followed)
+func readSubdirs(dir string, rules *IgnoreRules) []subdir {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var subs []subdir
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if rules.Skip(path, info) {
+			continue
+		}
+		subs = append(subs, subdir{path, info.ModTime().UnixNano()})
+	}
+	return subs
+}
+
+// walkDirs visits tops and every directory below them that the rules do not
+// skip, using a pool of workers; visit is called concurrently
+func walkDirs(tops []string, rules *IgnoreRules, visit func(dir string, mtime int64)) {
+	var (
+		mu      sync.Mutex
+		cond    = sync.NewCond(&mu)
+		queue   []string
+		pending int
+	)
+	for _, top := range tops {
+		info, err := os.Lstat(top)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		visit(top, info.ModTime().UnixNano())
+		queue = append(queue, top)
+	}
+	pending = len(queue)
+
+	worker := func(wg *sync.WaitGroup) {
+		defer wg.Done()
+		for {
+			mu.Lock()
+			for len(queue) == 0 && pending > 0 {
+				cond.Wait()
+			}
+			if pending == 0 {
+				mu.Unlock()
+				return
+			}
+			dir := queue[len(queue)-1]
+			queue = queue[:len(queue)-1]
+			mu.Unlock()
+
+			subs := readSubdirs(dir, rules)
+			for _, sub := range subs {
+				visit(sub.path, sub.mtime)
+			}
+
+			mu.Lock()
+			for _, sub := range subs {
+				queue = append(queue, sub.path)
+			}
+			pending += len(subs) - 1
+			mu.Unlock()
+			cond.Broadcast()
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4*runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go worker(&wg)
+	}
+	wg.Wait()
+}
+
+// Candidates returns the indexed directories below under
+func (ix *DirIndex) Candidates(under string) []DirCandidate {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var candidates []DirCandidate
+	for dir, mtime := range ix.dirs {
+		if dir != under && isUnder(dir, under) {
+			candidates = append(candidates, DirCandidate{Dir: dir, Mtime: mtime})
+		}
+	}
+	return candidates
+}
+
+// runIndexer keeps the index current until stop is closed (run by iptpd)
+func (ix *DirIndex) runIndexer(stop <-chan struct{}) {
+	if ix.Len() == 0 || ix.RulesChanged() {
+		ix.Rebuild()
+	} else {
+		ix.Rescan()
+	}
+	ix.Save()
+
+	watcher := startDirWatcher(ix)
+	if watcher != nil {
+		defer watcher.Close()
+	}
+
+	rescan := time.NewTicker(indexRescanInterval)
+	defer rescan.Stop()
+	save := time.NewTicker(indexSaveDelay)
+	defer save.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case rebuild := <-ix.requests:
+			if rebuild {
+				ix.Rebuild()
+			} else {
+				ix.Rescan()
+			}
+			ix.Save()
+			if watcher != nil {
+				watcher.Sync()
+			}
+		case <-rescan.C:
+			ix.Rescan()
+			ix.Save()
+			if watcher != nil {
+				watcher.Sync()
+			}
+		case <-save.C:
+			ix.mu.RLock()
+			dirty := ix.dirty
+			ix.mu.RUnlock()
+			if dirty {
+				ix.Save()
+			}
+		}
+	}
+}
+
+// requestRescan asks runIndexer for a rescan (or rebuild) without waiting
+func (ix *DirIndex) requestRescan(rebuild bool) {
+	select {
+	case ix.requests <- rebuild:
+	default: // one is already queued
+	}
+}
+
+// Status describes the index
+func (ix *DirIndex) Status() IndexStatus {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	status := IndexStatus{
+		File:        ix.path,
+		Roots:       ix.roots,
+		Directories: len(ix.dirs),
+		Building:    ix.building,
+		Watching:    ix.watching,
+	}
+	if !ix.built.IsZero() {
+		status.Built = ix.built.Format(time.RFC3339)
+	}
+	if !ix.updated.IsZero() {
+		status.Updated = ix.updated.Format(time.RFC3339)
+	}
+	return status
+}
+
+// ===== Queries =====
+
+// indexCandidates returns the indexed directories below under
+// ok is false when there is no usable index and the caller should walk
+func indexCandidates(under string) (candidates []DirCandidate, ok bool) {
+	ix := LoadDirIndex()
+	if ix.Len() == 0 || ix.RulesChanged() || !ix.Covers(under) {
+		return nil, false
+	}
+	return ix.Candidates(under), true
+}
+
+// refreshIndexInBackground rescans a stale index for a shell without iptpd
+func refreshIndexInBackground() {
+	go func() {
+		ix := LoadDirIndex()
+		switch {
+		case ix.Len() == 0:
+			return
+		case ix.RulesChanged():
+			ix.Rebuild()
+		case ix.Stale():
+			ix.Rescan()
+		default:
+			return
+		}
+		ix.Save()
+	}()
+}
+
+// ===== Command =====
+
+// cmdIndexNonInteractive manages the directory index
+// index [status] | index rebuild | index roots [add|remove DIR...]
+func cmdIndexNonInteractive(state *State, process string, args []string) int {
+	sub := "status"
+	if len(args) > 0 {
+		sub = args[0]
+	}
+
+	switch sub {
+	case "status":
+		return indexStatus()
+	case "rebuild":
+		return indexRebuild()
+	case "roots":
+		return indexRoots(args[1:])
+	default:
+		fmt.Println("Usage: index [status] | index rebuild | index roots [add|remove DIR...]")
+		return 1
+	}
+}
+
+// indexStatus prints the index, asking iptpd when it maintains it
+func indexStatus() int {
+	status := LoadDirIndex().Status()
+	maintainer := "none (start iptpd, or a shell rescans it when stale)"
+	if client, err := DialDaemon(); err == nil {
+		defer client.Close()
+		var live IndexStatus
+		if client.Call("index.status", nil, &live) == nil {
+			status = live
+			maintainer = "iptpd"
+		}
+	}
+
+	fmt.Println("=== Directory Index ===")
+	fmt.Printf("  Directories: %d\n", status.Directories)
+	for _, root := range status.Roots {
+		fmt.Printf("  Root: %s\n", root)
+	}
+	if status.Built != "" {
+		fmt.Printf("  Built: %s\n", formatTimestamp(status.Built))
+	}
+	if status.Updated != "" {
+		fmt.Printf("  Updated: %s\n", formatTimestamp(status.Updated))
+	}
+	fmt.Printf("  Maintained by: %s\n", maintainer)
+	if status.Watching > 0 {
+		fmt.Printf("  Watching: %d directories\n", status.Watching)
+	}
+	if status.Building {
+		fmt.Println("  ⏳ Scan in progress")
+	}
+	fmt.Printf("  File: %s\n", status.File)
+	return 0
+}
+
+// indexRebuild rebuilds the index, in iptpd if it is running
+func indexRebuild() int {
+	if client, err := DialDaemon(); err == nil {
+		defer client.Close()
+		if err := client.Call("index.rebuild", nil, nil); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		fmt.Println("✓ iptpd is rebuilding the index (see 'index status')")
+		return 0
+	}
+
+	ix := LoadDirIndex()
+	fmt.Printf("Indexing %s...\n", strings.Join(ix.Roots(), ", "))
+	start := time.Now()
+	ix.Rebuild()
+	if err := ix.Save(); err != nil {
+		fmt.Printf("✗ Cannot write index: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Indexed %d directories in %.1fs\n", ix.Len(), time.Since(start).Seconds())
+	return 0
+}
+
+// indexRoots lists or changes the index roots
+func indexRoots(args []string) int {
+	ix := LoadDirIndex()
+	roots := ix.Roots()
+
+	if len(args) == 0 {
+		for _, root := range roots {
+			fmt.Println(root)
+		}
+		return 0
+	}
+	if len(args) < 2 || (args[0] != "add" && args[0] != "remove") {
+		fmt.Println("Usage: index roots [add|remove DIR...]")
+		return 1
+	}
+	if ix.configRoots {
+		fmt.Printf("✗ Roots are set by search_roots in %s\n", getConfigFilePath())
+		return 1
+	}
+
+	for _, arg := range args[1:] {
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		if args[0] == "add" {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				fmt.Printf("✗ Not a directory: %s\n", dir)
+				return 1
+			}
+			if !containsString(roots, dir) {
+				roots = append(roots, dir)
+			}
+			continue
+		}
+		kept := roots[:0]
+		for _, root := range roots {
+			if root != dir {
+				kept = append(kept, root)
+			}
+		}
+		if len(kept) == len(roots) {
+			fmt.Printf("✗ Not an index root: %s\n", dir)
+			return 1
+		}
+		roots = kept
+	}
+
+	if client, err := DialDaemon(); err == nil {
+		defer client.Close()
+		if err := client.Call("index.roots", map[string][]string{"roots": roots}, nil); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Index roots: %s (iptpd is updating the index)\n", strings.Join(roots, ", "))
+		return 0
+	}
+
+	ix.SetRoots(roots)
+	ix.Rescan()
+	if err := ix.Save(); err != nil {
+		fmt.Printf("✗ Cannot write index: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Index roots: %s (%d directories)\n", strings.Join(roots, ", "), ix.Len())
+	return 0
+}
@@ -1,18 +1,61 @@
-package main
+package core
 
 import (
 	"fmt"
 	"os"
 	"os/exec"
 	"runtime"
+	"time"
 )
 
 // ExecuteScript runs an external command or script in gobash context
-func ExecuteScript(parts []string) {
+// Returns the command's exit code (127 if it could not be started)
+func ExecuteScript(parts []string) int {
+	exitCode, _ := RunScript(parts)
+	return exitCode
+}
+
+// RunScript runs an external command like ExecuteScript and also reports
+// the wall time and resources it used (nil if it could not be started)
+func RunScript(parts []string) (int, *CommandRun) {
 	if len(parts) == 0 {
-		return
+		return 0, nil
+	}
+
+	cmd := newScriptCommand(parts)
+
+	// Connect to stdin/stdout/stderr
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if recordingPTY != nil && os.Stdin == recordingPTY {
+		cmd.SysProcAttr = sessionProcAttr()
+	}
+
+	// Run the command
+	start := time.Now()
+	err := cmd.Run()
+	wall := time.Since(start)
+
+	if err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			// Command couldn't be executed
+			fmt.Printf("✗ Error executing command: %v\n", err)
+			return 127, nil
+		}
+	}
+
+	run := newCommandRun(parts, wall, cmd.ProcessState)
+	if run.ExitCode != 0 && runtime.GOOS != "windows" {
+		// On Unix, we can get the exit code
+		fmt.Printf("Command exited with code %d\n", run.ExitCode)
 	}
 
+	return run.ExitCode, run
+}
+
+// newScriptCommand builds the exec.Cmd for an external command
+func newScriptCommand(parts []string) *exec.Cmd {
 	cmdName := parts[0]
 	args := parts[1:]
 
@@ -26,24 +69,7 @@ func ExecuteScript(parts []string) {
 		cmd.Env = append(cmd.Env, "GOBASH_CWD="+cwd)
 	}
 
-	// Connect to stdin/stdout/stderr
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	// Run the command
-	if err := cmd.Run(); err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			// Command ran but returned non-zero exit code
-			if runtime.GOOS != "windows" {
-				// On Unix, we can get the exit code
-				fmt.Printf("Command exited with code %d\n", exitErr.ExitCode())
-			}
-		} else {
-			// Command couldn't be executed
-			fmt.Printf("✗ Error executing command: %v\n", err)
-		}
-	}
+	return cmd
 }
 
 // IsScriptOrCommand checks if a command is likely a script or external command
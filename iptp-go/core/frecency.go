@@ -0,0 +1,253 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Frecency: every directory change recorded by UpdateDirectory adds a visit,
+// and 'goto foo bar' jumps to the best-ranked visited directory matching the
+// terms. Ranks decay as they grow, so directories you stopped using drop out.
+
+// frecencyMaxRank is the total rank at which all ranks are scaled down
+const frecencyMaxRank = 10000
+
+// gotoChoices is how many candidates 'goto -i' offers
+const gotoChoices = 10
+
+// DirVisit is a directory's entry in the frecency database
+type DirVisit struct {
+	Rank      float64 `json:"rank"`
+	LastVisit int64   `json:"last_visit"` // Unix seconds
+}
+
+// DirMatch is a visited directory matching a 'goto' query
+type DirMatch struct {
+	Dir   string
+	Score float64
+}
+
+// visitDirectory adds a visit to the frecency database
+func (s *State) visitDirectory(dir string, now time.Time) {
+	if dir == "" {
+		return
+	}
+	if s.Directories == nil {
+		s.Directories = make(map[string]DirVisit)
+	}
+	visit := s.Directories[dir]
+	visit.Rank++
+	visit.LastVisit = now.Unix()
+	s.Directories[dir] = visit
+
+	total := 0.0
+	for _, v := range s.Directories {
+		total += v.Rank
+	}
+	if total <= frecencyMaxRank {
+		return
+	}
+
+	// Age: scale everything down and drop what falls below one visit
+	factor := 0.9 * frecencyMaxRank / total
+	for d, v := range s.Directories {
+		v.Rank *= factor
+		if v.Rank < 1 {
+			delete(s.Directories, d)
+		} else {
+			s.Directories[d] = v
+		}
+	}
+}
+
+// recordVisit adds a visit and queues it for iptpd
+func (s *State) recordVisit(dir string) {
+	s.visitDirectory(dir, time.Now())
+	s.dirVisits = append(s.dirVisits, dir)
+}
+
+// ForgetDirectory removes a directory from the frecency database
+func (s *State) ForgetDirectory(dir string) bool {
+	if _, ok := s.Directories[dir]; !ok {
+		return false
+	}
+	delete(s.Directories, dir)
+	s.dirForgets = append(s.dirForgets, dir)
+	return true
+}
+
+// frecencyScore weighs a directory's rank by how recently it was visited
+func frecencyScore(visit DirVisit, now time.Time) float64 {
+	age := now.Sub(time.Unix(visit.LastVisit, 0))
+	switch {
+	case age < time.Hour:
+		return visit.Rank * 4
+	case age < 24*time.Hour:
+		return visit.Rank * 2
+	case age < 7*24*time.Hour:
+		return visit.Rank / 2
+	default:
+		return visit.Rank / 4
+	}
+}
+
+// QueryDirectories returns existing visited directories matching every term,
+// best first; the current directory is left out
+func (s *State) QueryDirectories(terms []string) []DirMatch {
+	now := time.Now()
+	cwd, _ := os.Getwd()
+
+	var matches []DirMatch
+	for dir, visit := range s.Directories {
+		if dir == cwd || !matchTerms(dir, terms) {
+			continue
+		}
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			continue
+		}
+		matches = append(matches, DirMatch{Dir: dir, Score: frecencyScore(visit, now)})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Dir < matches[j].Dir
+	})
+	return matches
+}
+
+// matchTerms reports whether the terms appear in dir in order (ignoring case),
+// with the last term in the final path element: 'src api' matches ~/src/api
+// but not ~/api/src
+func matchTerms(dir string, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	path := strings.ToLower(dir)
+	pos := 0
+	for _, term := range terms {
+		term = strings.ToLower(term)
+		i := strings.Index(path[pos:], term)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(term)
+	}
+	last := strings.ToLower(terms[len(terms)-1])
+	return strings.Contains(strings.ToLower(filepath.Base(dir)), last)
+}
+
+// isGotoPath reports whether a single goto argument names a path rather than
+// frecency terms: ~, @bookmarks, globs and existing directories
+func isGotoPath(arg string) bool {
+	if arg == "-" || strings.HasPrefix(arg, "~") || strings.HasPrefix(arg, bookmarkPrefix) ||
+		hasGlobMeta(arg) || filepath.IsAbs(arg) {
+		return true
+	}
+	return isDirectory(arg)
+}
+
+// isDirectory reports whether path is an existing directory
+func isDirectory(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// parseGotoFlags splits -i/--interactive and --forget from goto's arguments
+func parseGotoFlags(args []string) (interactive, forget bool, rest []string) {
+	for _, arg := range args {
+		switch arg {
+		case "-i", "--interactive":
+			interactive = true
+		case "--forget":
+			forget = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return interactive, forget, rest
+}
+
+// resolveGotoTarget turns goto's arguments into a directory
+// A single path argument is used as is; otherwise the arguments are frecency
+// terms, and with interactive the user picks among the best matches
+func resolveGotoTarget(state *State, args []string, interactive bool, reader *bufio.Reader) (string, error) {
+	if !interactive && len(args) == 1 && isGotoPath(args[0]) {
+		return resolveGotoPath(state, args[0])
+	}
+
+	var matches []DirMatch
+	if len(args) == 1 && hasGlobMeta(args[0]) && !isDirectory(args[0]) {
+		// goto -i '*api*': choose among the scored glob matches
+		currentDir, _ := os.Getwd()
+		matches = RankDirectories(args[0], currentDir, 3, state.Directories)
+	} else {
+		matches = state.QueryDirectories(args)
+	}
+	if len(matches) == 0 {
+		if len(args) == 1 {
+			// Not visited yet: let chdir report the missing directory
+			return resolveGotoPath(state, args[0])
+		}
+		return "", fmt.Errorf("No visited directory matches: %s", strings.Join(args, " "))
+	}
+	if !interactive {
+		return matches[0].Dir, nil
+	}
+	return chooseDirectory(reader, matches)
+}
+
+// chooseDirectory lets the user pick one of the best matches
+func chooseDirectory(reader *bufio.Reader, matches []DirMatch) (string, error) {
+	if len(matches) > gotoChoices {
+		matches = matches[:gotoChoices]
+	}
+	for i, match := range matches {
+		fmt.Printf("  %d) %-50s %6.1f\n", i+1, match.Dir, match.Score)
+	}
+
+	fmt.Print("\nEnter number (or 'q' to quit): ")
+	choice, _ := reader.ReadString('\n')
+	choice = strings.TrimSpace(choice)
+
+	var selected int
+	if choice == "" {
+		selected = 1
+	} else if choice == "q" || choice == "Q" {
+		return "", fmt.Errorf("Cancelled")
+	} else if _, err := fmt.Sscanf(choice, "%d", &selected); err != nil || selected < 1 || selected > len(matches) {
+		return "", fmt.Errorf("Invalid selection")
+	}
+	return matches[selected-1].Dir, nil
+}
+
+// forgetDirectories removes directories (default: the current one) from the
+// frecency database
+func forgetDirectories(state *State, args []string) int {
+	if len(args) == 0 {
+		cwd, _ := os.Getwd()
+		args = []string{cwd}
+	}
+
+	status := 0
+	for _, arg := range args {
+		dir, err := filepath.Abs(arg)
+		if err != nil {
+			dir = arg
+		}
+		if state.ForgetDirectory(dir) {
+			fmt.Printf("✓ Forgot %s\n", dir)
+		} else {
+			fmt.Printf("✗ Not a visited directory: %s\n", dir)
+			status = 1
+		}
+	}
+	state.Save()
+	return status
+}
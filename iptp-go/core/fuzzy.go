@@ -0,0 +1,325 @@
+package core
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Scored directory matching
+//
+// A pattern with *, ? or [...] is a glob: without a '/' it must match the
+// directory's name, with one the path below the search base ('**' spans
+// directories, 'src/**/api*'). Anything else is a fuzzy query, like fzf:
+// the characters must appear in order in the path, ending in the final
+// element. Matches are scored for contiguous runs, word boundaries, shallow
+// depth and recent use, and are case-insensitive unless the pattern has an
+// upper-case letter.
+
+// Fuzzy scoring weights, after fzf
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+	bonusSlash        = 10 // first character of a path element
+	bonusBoundary     = 8  // after - _ . or space
+	bonusCamel        = 7  // upper case after lower case
+	bonusConsecutive  = 4
+	penaltyDepth      = 2 // per directory below the search base
+)
+
+// scoreNone marks an impossible alignment
+const scoreNone = math.MinInt32 / 2
+
+// DirCandidate is a directory a search may return
+type DirCandidate struct {
+	Dir   string
+	Mtime int64 // Unix nanoseconds, 0 if unknown
+}
+
+// dirQuery is a parsed search pattern
+type dirQuery struct {
+	glob          string   // glob pattern, "" for a fuzzy query
+	globPath      bool     // glob contains '/' and matches the relative path
+	terms         [][]rune // fuzzy terms (or the glob's literal characters)
+	caseSensitive bool
+}
+
+// newDirQuery parses a search pattern (relative to the search base)
+func newDirQuery(pattern string) dirQuery {
+	q := dirQuery{caseSensitive: strings.IndexFunc(pattern, unicode.IsUpper) >= 0}
+	if !q.caseSensitive {
+		pattern = strings.ToLower(pattern)
+	}
+
+	if hasGlobMeta(pattern) {
+		q.glob = strings.Trim(pattern, "/")
+		q.globPath = strings.Contains(q.glob, "/")
+		literal := strings.Map(func(r rune) rune {
+			if strings.ContainsRune("*?[]/!", r) {
+				return ' '
+			}
+			return r
+		}, q.glob)
+		pattern = literal
+	}
+	for _, term := range strings.Fields(pattern) {
+		q.terms = append(q.terms, []rune(term))
+	}
+	return q
+}
+
+// splitGlobBase splits a pattern into the literal directory it starts in and
+// the rest: /etc/*nginx* searches /etc for *nginx*, src/*/api searches ./src
+func splitGlobBase(pattern, startDir string) (base, rest string) {
+	if !hasGlobMeta(pattern) {
+		return startDir, pattern
+	}
+	base = startDir
+	if filepath.IsAbs(pattern) {
+		base = string(filepath.Separator)
+	}
+	elements := strings.Split(filepath.ToSlash(pattern), "/")
+	for i, element := range elements {
+		if element == "" {
+			continue
+		}
+		if hasGlobMeta(element) {
+			return base, strings.Join(elements[i:], "/")
+		}
+		base = filepath.Join(base, element)
+	}
+	return base, ""
+}
+
+// score rates rel (the candidate's path below the search base)
+func (q dirQuery) score(rel string) (int, bool) {
+	folded := rel
+	if !q.caseSensitive {
+		folded = strings.ToLower(rel)
+	}
+
+	if q.glob != "" {
+		target := filepath.Base(folded)
+		if q.globPath {
+			target = folded
+		}
+		if !globMatch(q.glob, target) {
+			return 0, false
+		}
+	}
+
+	original := []rune(rel)
+	text := []rune(folded)
+	nameStart := strings.LastIndex(rel, "/") + 1
+	nameStart = len([]rune(rel[:nameStart]))
+
+	total := 0
+	for i, term := range q.terms {
+		// A fuzzy query must end in the directory's own name
+		minEnd := 0
+		if q.glob == "" && i == len(q.terms)-1 {
+			minEnd = nameStart
+		}
+		s, ok := fuzzyScore(term, text, original, minEnd)
+		if !ok {
+			if q.glob != "" {
+				continue // the glob matched; literals only add to the score
+			}
+			return 0, false
+		}
+		total += s
+	}
+	return total, true
+}
+
+// fuzzyScore finds the best alignment of term as a subsequence of text whose
+// last character is at or after minEnd (original keeps the case for bonuses)
+func fuzzyScore(term, text, original []rune, minEnd int) (int, bool) {
+	n, m := len(text), len(term)
+	if m == 0 {
+		return 0, true
+	}
+	if m > n || !isSubsequence(term, text) {
+		return 0, false
+	}
+
+	prev := make([]int, n) // best score with term[i-1] matched at each position
+	cur := make([]int, n)
+	// Bonus of the first character of the run ending at each position; the
+	// rest of a contiguous run keeps it, as in fzf, so "api" at the start of
+	// an element beats a-p-i spread over three word boundaries
+	prevRun := make([]int, n)
+	curRun := make([]int, n)
+	for i := 0; i < m; i++ {
+		gap := scoreNone // best of prev[k] with a gap before position j
+		for j := 0; j < n; j++ {
+			if i > 0 && j >= 2 {
+				gap = max(gap+scoreGapExtension, prev[j-2]+scoreGapStart)
+			}
+			cur[j] = scoreNone
+			if text[j] != term[i] {
+				continue
+			}
+			bonus := charBonus(original, j)
+			if i == 0 {
+				cur[j] = scoreMatch + 2*bonus
+				curRun[j] = bonus
+				continue
+			}
+			if gap > scoreNone/2 {
+				cur[j] = gap + scoreMatch + bonus
+				curRun[j] = bonus
+			}
+			if j >= 1 && prev[j-1] > scoreNone/2 {
+				run := max(bonus, prevRun[j-1], bonusConsecutive)
+				if s := prev[j-1] + scoreMatch + run; s > cur[j] {
+					cur[j] = s
+					curRun[j] = max(bonus, prevRun[j-1])
+				}
+			}
+		}
+		prev, cur = cur, prev
+		prevRun, curRun = curRun, prevRun
+	}
+
+	result := scoreNone
+	for j := minEnd; j < n; j++ {
+		result = max(result, prev[j])
+	}
+	return result, result > scoreNone/2
+}
+
+// isSubsequence is the cheap test before scoring
+func isSubsequence(term, text []rune) bool {
+	i := 0
+	for _, r := range text {
+		if i < len(term) && r == term[i] {
+			i++
+		}
+	}
+	return i == len(term)
+}
+
+// charBonus rewards matching at the start of a word
+func charBonus(text []rune, j int) int {
+	if j == 0 {
+		return bonusSlash
+	}
+	switch prev := text[j-1]; {
+	case prev == '/':
+		return bonusSlash
+	case prev == '-' || prev == '_' || prev == '.' || prev == ' ':
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(text[j]):
+		return bonusCamel
+	}
+	return 0
+}
+
+// recencyBonus favours directories visited (frecency) or changed lately
+func recencyBonus(candidate DirCandidate, visits map[string]DirVisit, now time.Time) int {
+	bonus := 0
+	if visit, ok := visits[candidate.Dir]; ok {
+		bonus += int(math.Min(frecencyScore(visit, now), 40))
+	}
+	if candidate.Mtime > 0 {
+		switch age := now.Sub(time.Unix(0, candidate.Mtime)); {
+		case age < 24*time.Hour:
+			bonus += 6
+		case age < 7*24*time.Hour:
+			bonus += 3
+		}
+	}
+	return bonus
+}
+
+// rankCandidates scores candidates below base, best first
+func rankCandidates(q dirQuery, base string, candidates []DirCandidate, visits map[string]DirVisit) []DirMatch {
+	now := time.Now()
+	var matches []DirMatch
+	for _, candidate := range candidates {
+		rel, err := filepath.Rel(base, candidate.Dir)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		s, ok := q.score(rel)
+		if !ok {
+			continue
+		}
+		s -= penaltyDepth * strings.Count(rel, "/")
+		s += recencyBonus(candidate, visits, now)
+		matches = append(matches, DirMatch{Dir: candidate.Dir, Score: float64(s)})
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if len(matches[i].Dir) != len(matches[j].Dir) {
+			return len(matches[i].Dir) < len(matches[j].Dir)
+		}
+		return matches[i].Dir < matches[j].Dir
+	})
+	return matches
+}
+
+// walkCandidates lists directories below base down to maxDepth, skipping
+// what the ignore rules exclude
+func walkCandidates(base string, maxDepth int) []DirCandidate {
+	var candidates []DirCandidate
+	rules := searchIgnoreRules()
+	filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil // Skip errors
+		}
+		if path == base {
+			return nil
+		}
+		if rules.Skip(path, info) {
+			return filepath.SkipDir
+		}
+		rel, _ := filepath.Rel(base, path)
+		if strings.Count(rel, string(os.PathSeparator)) > maxDepth {
+			return filepath.SkipDir
+		}
+		candidates = append(candidates, DirCandidate{Dir: path, Mtime: info.ModTime().UnixNano()})
+		return nil
+	})
+	return candidates
+}
+
+// RankDirectories finds directories below startDir matching pattern, best
+// first. The directory index answers at any depth; without it (or when it
+// has no match yet) the tree is walked down to maxDepth.
+func RankDirectories(pattern, startDir string, maxDepth int, visits map[string]DirVisit) []DirMatch {
+	base, rest := splitGlobBase(pattern, startDir)
+	if rest == "" {
+		return nil
+	}
+	q := newDirQuery(rest)
+
+	if candidates, ok := indexCandidates(base); ok {
+		if matches := rankCandidates(q, base, candidates, visits); len(matches) > 0 {
+			return matches
+		}
+	}
+	return rankCandidates(q, base, walkCandidates(base, maxDepth), visits)
+}
+
+// matchedDirs returns the directories of the best matches
+func matchedDirs(matches []DirMatch, limit int) []string {
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	dirs := make([]string, len(matches))
+	for i, match := range matches {
+		dirs[i] = match.Dir
+	}
+	return dirs
+}
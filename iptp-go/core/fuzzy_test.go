@@ -0,0 +1,121 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDirQueryMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"api", "src/api", true},
+		{"api", "src/apps/internal", true}, // the last character is in the name
+		{"api", "api/docs", false},         // must end in the last element
+		{"sapi", "src/api", true},
+		{"ipa", "src/api", false},
+		{"src api", "src/api", true},
+		{"api src", "src/api", false},
+		{"API", "src/api", false}, // upper case: case-sensitive
+		{"Api", "src/Api", true},
+		{"api", "src/API", true},
+		{"*api*", "src/my-api-v2", true},
+		{"*api*", "api/src", false}, // without '/' the glob matches the name
+		{"src/*/api", "src/v1/api", true},
+		{"src/*/api", "src/v1/v2/api", false},
+		{"src/**/api", "src/v1/v2/api", true},
+		{"[ab]pi", "src/bpi", true},
+	}
+	for _, tt := range tests {
+		if _, ok := newDirQuery(tt.pattern).score(tt.rel); ok != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.rel, ok, tt.want)
+		}
+	}
+}
+
+func TestDirQueryScoreOrder(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		better, worse string
+	}{
+		{"api", "src/api", "src/a-p-i"},          // contiguous over scattered
+		{"api", "src/api", "src/rapid"},          // start of an element over the middle
+		{"cfg", "my-cfg", "mycfgs"},              // word boundary after '-'
+		{"ms", "src/MyService", "src/mintsauce"}, // camel case
+		{"web", "web", "www/ebooks"},             // fewer gaps
+	}
+	for _, tt := range tests {
+		q := newDirQuery(tt.pattern)
+		better, ok1 := q.score(tt.better)
+		worse, ok2 := q.score(tt.worse)
+		if !ok1 || !ok2 {
+			t.Errorf("%q: %q matched %v, %q matched %v", tt.pattern, tt.better, ok1, tt.worse, ok2)
+			continue
+		}
+		if better <= worse {
+			t.Errorf("%q: %q scored %d, not above %q at %d", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	base := filepath.FromSlash("/work")
+	dir := func(rel string) string { return filepath.Join(base, filepath.FromSlash(rel)) }
+	candidates := func(rels ...string) []DirCandidate {
+		var list []DirCandidate
+		for _, rel := range rels {
+			list = append(list, DirCandidate{Dir: dir(rel)})
+		}
+		return list
+	}
+	recent := map[string]DirVisit{
+		dir("old/deep/api"): {Rank: 20, LastVisit: time.Now().Unix()},
+	}
+
+	tests := []struct {
+		pattern    string
+		candidates []DirCandidate
+		visits     map[string]DirVisit
+		want       []string
+	}{
+		{"api", candidates("api", "src/api", "rapid", "docs"), nil, []string{"api", "src/api", "rapid"}},
+		{"api", candidates("old/deep/api", "src/api"), nil, []string{"src/api", "old/deep/api"}},
+		{"api", candidates("old/deep/api", "src/api"), recent, []string{"old/deep/api", "src/api"}},
+		{"api", candidates(".", "../api", "api"), nil, []string{"api"}}, // only below base
+		{"*-v?", candidates("api-v1", "api-v2", "api-v10"), nil, []string{"api-v1", "api-v2"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, match := range rankCandidates(newDirQuery(tt.pattern), base, tt.candidates, tt.visits) {
+			rel, _ := filepath.Rel(base, match.Dir)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q ranked %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSplitGlobBase(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		base, rest string
+	}{
+		{"api", "start", "api"},
+		{"*api*", "start", "*api*"},
+		{"src/*/api", "start/src", "*/api"},
+		{"src/lib/**/x?", "start/src/lib", "**/x?"},
+		{"/etc/*nginx*", "/etc", "*nginx*"},
+		{"src/lib", "start", "src/lib"},
+	}
+	for _, tt := range tests {
+		base, rest := splitGlobBase(tt.pattern, "start")
+		if filepath.ToSlash(base) != tt.base || rest != tt.rest {
+			t.Errorf("splitGlobBase(%q) = %q, %q, want %q, %q", tt.pattern, base, rest, tt.base, tt.rest)
+		}
+	}
+}
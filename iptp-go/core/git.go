@@ -0,0 +1,536 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Git state
+//
+// The branch, detached HEAD, upstream and stash count are read straight from
+// the repository's files, so they cost nothing and need no git binary, and
+// that is all a directory change reads. The work tree comparison (changed
+// and untracked files) and the ahead/behind counts come from 'git status':
+// the interactive shell runs it in the background after each command and
+// sets the pulses at a later prompt; 'git-status' and 'list' run it when
+// asked. Until then, or when it fails, the dirty, ahead and behind pulses
+// are U rather than a guess.
+
+// gitStatusTimeout bounds one 'git status' run
+const gitStatusTimeout = 5 * time.Second
+
+// gitStatusWorkers caps the 'git status' runs 'list' makes at once
+const gitStatusWorkers = 4
+
+// Git pulse names
+const (
+	pulseGitBranch   = "git branch"
+	pulseGitDetached = "git detached"
+	pulseGitDirty    = "git dirty"
+	pulseGitAhead    = "git ahead"
+	pulseGitBehind   = "git behind"
+	pulseGitStash    = "git stash"
+)
+
+// GitStatus is the state of the repository containing a directory
+type GitStatus struct {
+	Root      string `json:"root"`
+	Branch    string `json:"branch"` // "" when detached
+	Commit    string `json:"commit"` // "" on a branch with no commits yet
+	Detached  bool   `json:"detached"`
+	Upstream  string `json:"upstream"` // e.g. origin/main, "" if none
+	Ahead     int    `json:"ahead"`
+	Behind    int    `json:"behind"`
+	SyncKnown bool   `json:"sync_known"` // Ahead and Behind are valid
+	Changed   int    `json:"changed"`    // staged, unstaged and conflicted
+	Untracked int    `json:"untracked"`
+	Known     bool   `json:"known"` // Changed and Untracked are valid
+	Stashes   int    `json:"stashes"`
+}
+
+// Dirty reports uncommitted work, including untracked files
+func (st GitStatus) Dirty() bool {
+	return st.Changed+st.Untracked > 0
+}
+
+// shortCommit abbreviates a commit id like git does
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// Summary is a one-line status: main ↑1 ↓2 ●3 …4 ⚑1, or ✓ when clean
+func (st GitStatus) Summary() string {
+	parts := []string{st.Branch}
+	if st.Detached {
+		parts[0] = "detached@" + shortCommit(st.Commit)
+	}
+	if st.Ahead > 0 {
+		parts = append(parts, fmt.Sprintf("↑%d", st.Ahead))
+	}
+	if st.Behind > 0 {
+		parts = append(parts, fmt.Sprintf("↓%d", st.Behind))
+	}
+	switch {
+	case !st.Known:
+		parts = append(parts, "?")
+	case !st.Dirty():
+		parts = append(parts, "✓")
+	default:
+		if st.Changed > 0 {
+			parts = append(parts, fmt.Sprintf("●%d", st.Changed))
+		}
+		if st.Untracked > 0 {
+			parts = append(parts, fmt.Sprintf("…%d", st.Untracked))
+		}
+	}
+	if st.Stashes > 0 {
+		parts = append(parts, fmt.Sprintf("⚑%d", st.Stashes))
+	}
+	return strings.Join(parts, " ")
+}
+
+// findGitDir finds the repository containing dir: its work tree root and
+// git directory (worktrees and submodules use a "gitdir: PATH" file)
+func findGitDir(dir string) (root, gitDir string, ok bool) {
+	for {
+		gitPath := filepath.Join(dir, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			if !info.IsDir() {
+				data, err := os.ReadFile(gitPath)
+				if err != nil {
+					return "", "", false
+				}
+				gitPath = strings.TrimSpace(strings.TrimPrefix(string(data), "gitdir:"))
+				if !filepath.IsAbs(gitPath) {
+					gitPath = filepath.Join(dir, gitPath)
+				}
+			}
+			return dir, gitPath, true
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// gitCommonDir returns the directory shared by all worktrees (refs, config)
+func gitCommonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return common
+}
+
+// readGitRef resolves a ref like refs/heads/main from its loose file or
+// packed-refs; "" if it does not exist
+func readGitRef(gitDir, commonDir, ref string) string {
+	for _, dir := range []string{gitDir, commonDir} {
+		if data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ref))); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+
+	f, err := os.Open(filepath.Join(commonDir, "packed-refs"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if commit, name, ok := strings.Cut(scanner.Text(), " "); ok && name == ref {
+			return commit
+		}
+	}
+	return ""
+}
+
+// gitUpstream reads a branch's upstream from the repository config
+// Returns its display name (origin/main) and ref (refs/remotes/origin/main)
+func gitUpstream(commonDir, branch string) (name, ref string) {
+	f, err := os.Open(filepath.Join(commonDir, "config"))
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	section := fmt.Sprintf(`[branch "%s"]`, branch)
+	inSection := false
+	var remote, merge string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = line == section
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !inSection || !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "remote":
+			remote = strings.TrimSpace(value)
+		case "merge":
+			merge = strings.TrimSpace(value)
+		}
+	}
+	if remote == "" || merge == "" {
+		return "", ""
+	}
+
+	short := strings.TrimPrefix(merge, "refs/heads/")
+	if remote == "." {
+		return short, merge // tracks a local branch
+	}
+	return remote + "/" + short, "refs/remotes/" + remote + "/" + short
+}
+
+// countLines counts the lines of a file (0 if missing)
+func countLines(file string) int {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+	return strings.Count(string(data), "\n")
+}
+
+// ReadGitStatus reads the state of the repository containing dir from its
+// files; the work tree counts are left unknown (see readWorktreeStatus)
+func ReadGitStatus(dir string) (GitStatus, bool) {
+	root, gitDir, ok := findGitDir(dir)
+	if !ok {
+		return GitStatus{}, false
+	}
+	commonDir := gitCommonDir(gitDir)
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return GitStatus{}, false
+	}
+	st := GitStatus{Root: root}
+	if ref := strings.TrimSpace(string(head)); strings.HasPrefix(ref, "ref: ") {
+		ref = strings.TrimPrefix(ref, "ref: ")
+		st.Branch = strings.TrimPrefix(ref, "refs/heads/")
+		st.Commit = readGitRef(gitDir, commonDir, ref)
+	} else {
+		st.Detached = true
+		st.Commit = ref
+	}
+	st.Stashes = countLines(filepath.Join(commonDir, "logs", "refs", "stash"))
+
+	if !st.Detached {
+		var upstreamRef string
+		st.Upstream, upstreamRef = gitUpstream(commonDir, st.Branch)
+		// Same commit as the upstream: in sync without asking git
+		if st.Upstream != "" && st.Commit != "" && readGitRef(gitDir, commonDir, upstreamRef) == st.Commit {
+			st.SyncKnown = true
+		}
+	}
+
+	return st, true
+}
+
+// readFullGitStatus reads the repository and runs 'git status' on it
+func readFullGitStatus(dir string) (GitStatus, bool) {
+	st, ok := ReadGitStatus(dir)
+	if ok {
+		readWorktreeStatus(&st, dir)
+	}
+	return st, ok
+}
+
+// readWorktreeStatus fills in changed/untracked files and ahead/behind
+// from 'git status --porcelain=v2 --branch'
+func readWorktreeStatus(st *GitStatus, dir string) {
+	ctx, cancel := context.WithTimeout(context.Background(), gitStatusTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain=v2", "--branch")
+	cmd.Dir = dir
+	// A status check must not take index.lock from under the user's git
+	cmd.Env = append(os.Environ(), "GIT_OPTIONAL_LOCKS=0")
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# branch.ab "):
+			fields := strings.Fields(line)
+			if len(fields) == 4 {
+				st.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+				st.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+				st.SyncKnown = true
+			}
+		case strings.HasPrefix(line, "1 "), strings.HasPrefix(line, "2 "), strings.HasPrefix(line, "u "):
+			st.Changed++
+		case strings.HasPrefix(line, "? "):
+			st.Untracked++
+		}
+	}
+	st.Known = true
+}
+
+// gitPulses refreshes the git pulses for a process now in dir from the
+// repository files
+// Outside a repository "git branch" turns N and the rest U, if the process
+// was in one before
+func gitPulses(pulses []Pulse, dir string) []Pulse {
+	st, ok := ReadGitStatus(dir)
+	return statusPulses(pulses, st, ok)
+}
+
+// statusPulses merges a status read by ReadGitStatus into pulses
+func statusPulses(pulses []Pulse, st GitStatus, ok bool) []Pulse {
+	if !ok {
+		for _, pulse := range pulses {
+			if pulse.Name == pulseGitBranch && pulse.TV == "Y" {
+				pulses = mergePulse(pulses, Pulse{Name: pulseGitBranch, TV: "N"})
+				for _, name := range []string{pulseGitDetached, pulseGitDirty, pulseGitAhead, pulseGitBehind, pulseGitStash} {
+					pulses = mergePulse(pulses, Pulse{Name: name, TV: "U"})
+				}
+				return pulses
+			}
+		}
+		return pulses
+	}
+
+	for _, pulse := range st.Pulses() {
+		pulses = mergePulse(pulses, pulse)
+	}
+	return pulses
+}
+
+// Pulses describes the status as trivalent pulses
+func (st GitStatus) Pulses() []Pulse {
+	pulses := []Pulse{
+		{Name: pulseGitBranch, TV: "Y", Response: st.Branch},
+		{Name: pulseGitDetached, TV: "N", Response: shortCommit(st.Commit)},
+	}
+	if st.Detached {
+		pulses[0].Response = shortCommit(st.Commit)
+		pulses[1].TV = "Y"
+	}
+
+	dirty := Pulse{Name: pulseGitDirty, TV: "U"}
+	if st.Known {
+		dirty.TV = trivalent(st.Dirty())
+		dirty.Response = fmt.Sprintf("%d changed, %d untracked", st.Changed, st.Untracked)
+	}
+
+	ahead := Pulse{Name: pulseGitAhead, TV: "U"}
+	behind := Pulse{Name: pulseGitBehind, TV: "U"}
+	if st.Upstream != "" && st.SyncKnown {
+		ahead = Pulse{Name: pulseGitAhead, TV: trivalent(st.Ahead > 0), Response: fmt.Sprintf("%d ahead of %s", st.Ahead, st.Upstream)}
+		behind = Pulse{Name: pulseGitBehind, TV: trivalent(st.Behind > 0), Response: fmt.Sprintf("%d behind %s", st.Behind, st.Upstream)}
+	}
+
+	stash := Pulse{Name: pulseGitStash, TV: trivalent(st.Stashes > 0), Response: strconv.Itoa(st.Stashes)}
+	return append(pulses, dirty, ahead, behind, stash)
+}
+
+// trivalent turns a known condition into Y or N
+func trivalent(condition bool) string {
+	if condition {
+		return "Y"
+	}
+	return "N"
+}
+
+// SetGitStatus sets a process's git pulses from a full status of dir
+// Returns false if the process is gone or has moved on from dir
+func (s *State) SetGitStatus(processName, dir string, st GitStatus, ok bool) bool {
+	proc, exists := s.Processes[processName]
+	if !exists || proc.CurrentDir != dir {
+		return false
+	}
+	proc.Pulses = statusPulses(proc.Pulses, st, ok)
+	s.Processes[processName] = proc
+	return true
+}
+
+// gitRefresh is the result of a background 'git status' for the shell
+type gitRefresh struct {
+	process string
+	dir     string
+	status  GitStatus
+}
+
+// refreshGitInBackground starts a full status read of the current
+// directory, one at a time; applyGitRefresh picks up the result
+func (sh *Shell) refreshGitInBackground() {
+	if sh.gitRefresh != nil {
+		return
+	}
+	dir, _ := os.Getwd()
+	st, ok := ReadGitStatus(dir)
+	if !ok {
+		return
+	}
+
+	done := make(chan gitRefresh, 1)
+	sh.gitRefresh = done
+	process := sh.currentProcess
+	go func() {
+		readWorktreeStatus(&st, dir)
+		done <- gitRefresh{process: process, dir: dir, status: st}
+	}()
+}
+
+// applyGitRefresh sets the git pulses from a finished background read
+// Runs on the shell's goroutine, so the state is never touched concurrently
+func (sh *Shell) applyGitRefresh() {
+	if sh.gitRefresh == nil {
+		return
+	}
+	select {
+	case result := <-sh.gitRefresh:
+		sh.gitRefresh = nil
+		before, _ := sh.state.GetProcess(result.process)
+		pulses := snapshotJSON(before.Pulses)
+		if sh.state.SetGitStatus(result.process, result.dir, result.status, true) {
+			if after, _ := sh.state.GetProcess(result.process); snapshotJSON(after.Pulses) != pulses {
+				sh.state.Save()
+			}
+		}
+	default:
+	}
+}
+
+// readGitStatuses reads the repositories of many directories, running at
+// most gitStatusWorkers 'git status' at a time
+func readGitStatuses(dirs []string) map[string]GitStatus {
+	unique := make(map[string]bool)
+	for _, dir := range dirs {
+		if dir != "" {
+			unique[dir] = true
+		}
+	}
+
+	statuses := make(map[string]GitStatus)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, gitStatusWorkers)
+	for dir := range unique {
+		wg.Add(1)
+		go func(dir string) {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			if st, ok := readFullGitStatus(dir); ok {
+				mu.Lock()
+				statuses[dir] = st
+				mu.Unlock()
+			}
+		}(dir)
+	}
+	wg.Wait()
+	return statuses
+}
+
+// processGitStatuses reads the repository of every process's directory
+func processGitStatuses(state *State) map[string]GitStatus {
+	var dirs []string
+	for _, name := range state.ListProcesses() {
+		proc, _ := state.GetProcess(name)
+		dirs = append(dirs, proc.CurrentDir)
+	}
+	return readGitStatuses(dirs)
+}
+
+// gitMarker is the summary shown after a process in 'list'
+func gitMarker(statuses map[string]GitStatus, dir string) string {
+	if st, ok := statuses[dir]; ok {
+		return " [" + st.Summary() + "]"
+	}
+	return ""
+}
+
+// printGitStatus shows a repository's status in full
+func printGitStatus(st GitStatus) {
+	fmt.Printf("=== Git: %s ===\n", st.Root)
+	branch := st.Branch
+	if st.Detached {
+		branch = "(detached at " + shortCommit(st.Commit) + ")"
+	} else if st.Commit == "" {
+		branch += " (no commits yet)"
+	}
+	fmt.Printf("  Branch:   %s\n", branch)
+
+	switch {
+	case st.Upstream == "":
+		fmt.Println("  Upstream: (none)")
+	case st.SyncKnown:
+		fmt.Printf("  Upstream: %s, %d ahead, %d behind\n", st.Upstream, st.Ahead, st.Behind)
+	default:
+		fmt.Printf("  Upstream: %s (unknown)\n", st.Upstream)
+	}
+
+	if st.Known {
+		fmt.Printf("  Changes:  %d changed, %d untracked\n", st.Changed, st.Untracked)
+	} else {
+		fmt.Println("  Changes:  unknown (git status failed or timed out)")
+	}
+	fmt.Printf("  Stashes:  %d\n", st.Stashes)
+}
+
+// cmdGitStatusNonInteractive handles 'git-status': refresh the current
+// process's git pulses and show them, or summarize every process with --all
+func cmdGitStatusNonInteractive(state *State, process string, args []string) int {
+	if len(args) > 0 && (args[0] == "--all" || args[0] == "-a") {
+		statuses := processGitStatuses(state)
+		names := state.ListProcesses()
+		sort.Strings(names)
+
+		fmt.Println("=== Git Status of Processes ===")
+		shown := 0
+		for _, name := range names {
+			proc, _ := state.GetProcess(name)
+			if st, ok := statuses[proc.CurrentDir]; ok {
+				fmt.Printf("  → %-20s %-30s %s\n", name, st.Summary(), proc.CurrentDir)
+				shown++
+			}
+		}
+		if shown == 0 {
+			fmt.Println("  (no process is in a git repository)")
+		}
+		return 0
+	}
+	if len(args) > 0 {
+		fmt.Println("Usage: git-status [--all]")
+		return 1
+	}
+
+	dir, _ := os.Getwd()
+	st, ok := readFullGitStatus(dir)
+	if state.SetGitStatus(process, dir, st, ok) {
+		state.Save()
+	}
+	if !ok {
+		fmt.Println("✗ Not in a git repository")
+		return 1
+	}
+	printGitStatus(st)
+	return 0
+}
@@ -0,0 +1,404 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Word is one argument of a command line after quote removal
+// Pattern holds the same text with quoted glob characters escaped, so it
+// can be expanded without touching anything the user quoted
+type Word struct {
+	Text    string
+	Pattern string
+	Quoted  bool // some part of the word was quoted or escaped
+	Glob    bool // contains unquoted *, ?, [ or {
+}
+
+// globMeta are the characters that make an unquoted word a pattern
+const globMeta = "*?[{"
+
+// globEscape are the characters escaped in Pattern when quoted
+const globEscape = "*?[]{},~\\"
+
+// SplitCommandLine splits a line into words like a POSIX shell
+// 'single' quotes are literal, "double" quotes allow \" and \\,
+// and a backslash outside quotes escapes the next character, except on
+// Windows, where it separates paths (cd C:\src\app)
+func SplitCommandLine(line string) ([]Word, error) {
+	return splitCommandLine(line, runtime.GOOS == "windows")
+}
+
+// splitCommandLine is SplitCommandLine with Windows backslashes or not
+func splitCommandLine(line string, windows bool) ([]Word, error) {
+	var words []Word
+	var text, pattern strings.Builder
+	inWord, quoted, glob := false, false, false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, Word{
+				Text:    text.String(),
+				Pattern: pattern.String(),
+				Quoted:  quoted,
+				Glob:    glob,
+			})
+		}
+		text.Reset()
+		pattern.Reset()
+		inWord, quoted, glob = false, false, false
+	}
+
+	// literal adds a character that must not be treated as a pattern
+	literal := func(c byte) {
+		text.WriteByte(c)
+		if strings.IndexByte(globEscape, c) >= 0 {
+			pattern.WriteByte('\\')
+		}
+		pattern.WriteByte(c)
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t':
+			endWord()
+		case c == '\\' && windows:
+			// A path separator; patterns always use /
+			inWord = true
+			text.WriteByte(c)
+			pattern.WriteByte('/')
+		case c == '\\':
+			inWord, quoted = true, true
+			if i+1 < len(line) {
+				i++
+				literal(line[i])
+			}
+		case c == '\'':
+			inWord, quoted = true, true
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			for j := i + 1; j <= i+end; j++ {
+				literal(line[j])
+			}
+			i += end + 1
+		case c == '"':
+			inWord, quoted = true, true
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '"' {
+					closed = true
+					break
+				}
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte(`"\$`, line[i+1]) >= 0 {
+					i++
+				}
+				literal(line[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+		default:
+			inWord = true
+			if strings.IndexByte(globMeta, c) >= 0 {
+				glob = true
+			}
+			text.WriteByte(c)
+			pattern.WriteByte(c)
+		}
+	}
+	endWord()
+
+	return words, nil
+}
+
+// wordTexts returns the quote-removed text of each word
+func wordTexts(words []Word) []string {
+	texts := make([]string, len(words))
+	for i, w := range words {
+		texts[i] = w.Text
+	}
+	return texts
+}
+
+// Glob no-match modes, set with glob_nomatch in ~/.iptprc
+const (
+	GlobNoMatchKeep  = "keep"  // pass the pattern through unchanged (bash default)
+	GlobNoMatchError = "error" // refuse to run the command (zsh default)
+	GlobNoMatchDrop  = "drop"  // remove the argument (bash nullglob)
+)
+
+// ExpandWords performs tilde, brace and glob expansion on unquoted words
+// relative to the current directory
+func ExpandWords(words []Word, noMatch string) ([]string, error) {
+	var args []string
+	for _, w := range words {
+		pattern := w.Pattern
+		if strings.HasPrefix(w.Text, "~") && !strings.HasPrefix(pattern, "\\~") &&
+			(len(pattern) == 1 || pattern[1] == '/') {
+			if home, err := os.UserHomeDir(); err == nil {
+				pattern = escapeGlob(filepath.ToSlash(home)) + pattern[1:]
+				if !w.Glob {
+					args = append(args, home+w.Text[1:])
+					continue
+				}
+			}
+		}
+
+		if !w.Glob {
+			args = append(args, w.Text)
+			continue
+		}
+
+		for _, alt := range ExpandBraces(pattern) {
+			if !hasGlobMeta(alt) || !validGlob(alt) {
+				args = append(args, unescapeGlob(alt))
+				continue
+			}
+
+			matches := GlobPattern(alt)
+			if len(matches) > 0 {
+				args = append(args, matches...)
+				continue
+			}
+
+			switch noMatch {
+			case GlobNoMatchError:
+				return nil, fmt.Errorf("no matches found: %s", unescapeGlob(alt))
+			case GlobNoMatchDrop:
+			default:
+				args = append(args, unescapeGlob(alt))
+			}
+		}
+	}
+	return args, nil
+}
+
+// ExpandBraces expands {a,b} alternatives (nested braces allowed)
+// Escaped braces and braces without a top-level comma are left alone
+func ExpandBraces(pattern string) []string {
+	open, close := -1, -1
+	depth := 0
+	var commas []int
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				open = i
+				commas = commas[:0]
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				if len(commas) > 0 {
+					close = i
+					break
+				}
+				open = -1
+			}
+		}
+		if close != -1 {
+			break
+		}
+	}
+
+	if open == -1 || close == -1 {
+		return []string{pattern}
+	}
+
+	prefix, suffix := pattern[:open], pattern[close+1:]
+	var results []string
+	start := open + 1
+	for _, end := range append(commas, close) {
+		for _, expanded := range ExpandBraces(prefix + pattern[start:end] + suffix) {
+			results = append(results, expanded)
+		}
+		start = end + 1
+	}
+	return results
+}
+
+// GlobPattern matches a pattern against the filesystem
+// Supports *, ?, [...] within a path element and ** for any number of
+// directories. Leading dots must be matched explicitly, as in sh.
+// Patterns use / between elements and \ for escapes on every platform;
+// elements are matched with path.Match, since filepath.Match treats \ as a
+// separator on Windows
+func GlobPattern(pattern string) []string {
+	base := "."
+	rest := pattern
+	if filepath.IsAbs(pattern) {
+		base = string(os.PathSeparator)
+		rest = strings.TrimLeft(pattern, "/")
+		if vol := filepath.VolumeName(pattern); vol != "" {
+			base = vol + string(os.PathSeparator)
+			rest = strings.TrimLeft(pattern[len(vol):], `/\`)
+		}
+	}
+
+	segments := strings.Split(rest, "/")
+	if segments[len(segments)-1] == "**" {
+		// A trailing ** matches every file and directory below
+		segments = append(segments, "*")
+	}
+	seen := make(map[string]bool)
+	var matches []string
+	globSegments(base, "", segments, func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			matches = append(matches, path)
+		}
+	})
+
+	sort.Strings(matches)
+	return matches
+}
+
+// globSegments walks one pattern segment at a time
+// dir is the directory on disk; display is the path shown to the command
+func globSegments(dir, display string, segments []string, emit func(string)) {
+	if len(segments) == 0 {
+		if display != "" {
+			emit(display)
+		}
+		return
+	}
+
+	segment, rest := segments[0], segments[1:]
+	join := func(name string) string {
+		if display == "" {
+			if dir == "." {
+				return name
+			}
+			return filepath.Join(dir, name)
+		}
+		return display + "/" + name
+	}
+
+	// Empty segments come from doubled or trailing slashes
+	if segment == "" {
+		if len(rest) == 0 && display != "" {
+			emit(display + "/")
+			return
+		}
+		globSegments(dir, display, rest, emit)
+		return
+	}
+
+	if segment == "**" {
+		// Zero directories
+		globSegments(dir, display, rest, emit)
+		// One or more directories
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			globSegments(filepath.Join(dir, entry.Name()), join(entry.Name()), segments, emit)
+		}
+		return
+	}
+
+	if !hasGlobMeta(segment) {
+		name := unescapeGlob(segment)
+		path := filepath.Join(dir, name)
+		if _, err := os.Lstat(path); err == nil {
+			globSegments(path, join(name), rest, emit)
+		}
+		return
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(segment, ".") {
+			continue
+		}
+		if ok, _ := path.Match(segment, name); !ok {
+			continue
+		}
+		if len(rest) > 0 && !isDirEntry(dir, entry) {
+			continue
+		}
+		globSegments(filepath.Join(dir, name), join(name), rest, emit)
+	}
+}
+
+// isDirEntry reports whether an entry is a directory, following symlinks
+func isDirEntry(dir string, entry os.DirEntry) bool {
+	if entry.IsDir() {
+		return true
+	}
+	if entry.Type()&os.ModeSymlink != 0 {
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		return err == nil && info.IsDir()
+	}
+	return false
+}
+
+// hasGlobMeta reports whether a pattern has unescaped *, ? or [
+func hasGlobMeta(pattern string) bool {
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
+
+// validGlob reports whether a pattern is well formed; a lone [ is literal
+func validGlob(pattern string) bool {
+	_, err := path.Match(pattern, "")
+	return err == nil
+}
+
+// escapeGlob escapes pattern characters in a literal string
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(globEscape, s[i]) >= 0 {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// unescapeGlob removes pattern escapes, giving the literal text
+func unescapeGlob(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+		}
+		b.WriteByte(pattern[i])
+	}
+	return b.String()
+}
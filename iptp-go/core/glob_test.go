@@ -0,0 +1,233 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandBraces(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"plain", []string{"plain"}},
+		{"{a,b}", []string{"a", "b"}},
+		{"x{a,b,c}y", []string{"xay", "xby", "xcy"}},
+		{"{a,b}{1,2}", []string{"a1", "a2", "b1", "b2"}},
+		{"{a,{b,c}}d", []string{"ad", "bd", "cd"}},
+		{"src/{api,web}/*.go", []string{"src/api/*.go", "src/web/*.go"}},
+		{"{a,}", []string{"a", ""}},
+		{"{single}", []string{"{single}"}},
+		{"{}", []string{"{}"}},
+		{"{a,b", []string{"{a,b"}},
+		{"a,b}", []string{"a,b}"}},
+		{`\{a,b}`, []string{`\{a,b}`}},
+		{`{a\,b}`, []string{`{a\,b}`}},
+		{`{a\,b,c}`, []string{`a\,b`, "c"}},
+		{"{x}{a,b}", []string{"{x}a", "{x}b"}},
+	}
+	for _, tt := range tests {
+		if got := ExpandBraces(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandBraces(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestGlobEscaping(t *testing.T) {
+	tests := []struct {
+		literal string
+		escaped string
+	}{
+		{"plain.txt", "plain.txt"},
+		{"*.go", `\*.go`},
+		{"what?", `what\?`},
+		{"[draft] notes", `\[draft\] notes`},
+		{"{a,b}", `\{a\,b\}`},
+		{"~user", `\~user`},
+		{`back\slash`, `back\\slash`},
+	}
+	for _, tt := range tests {
+		escaped := escapeGlob(tt.literal)
+		if escaped != tt.escaped {
+			t.Errorf("escapeGlob(%q) = %q, want %q", tt.literal, escaped, tt.escaped)
+		}
+		if hasGlobMeta(escaped) {
+			t.Errorf("hasGlobMeta(%q) = true for an escaped string", escaped)
+		}
+		if got := unescapeGlob(escaped); got != tt.literal {
+			t.Errorf("unescapeGlob(%q) = %q, want %q", escaped, got, tt.literal)
+		}
+	}
+}
+
+func TestHasGlobMeta(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"plain", false},
+		{"*.go", true},
+		{"file?", true},
+		{"[ab]", true},
+		{`\*.go`, false},
+		{`\\*.go`, true},
+		{"{a,b}", false}, // braces are expanded before globbing
+	}
+	for _, tt := range tests {
+		if got := hasGlobMeta(tt.pattern); got != tt.want {
+			t.Errorf("hasGlobMeta(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []Word
+	}{
+		{"ls  -la\t/tmp", []Word{
+			{Text: "ls", Pattern: "ls"},
+			{Text: "-la", Pattern: "-la"},
+			{Text: "/tmp", Pattern: "/tmp"},
+		}},
+		{"rm *.log", []Word{
+			{Text: "rm", Pattern: "rm"},
+			{Text: "*.log", Pattern: "*.log", Glob: true},
+		}},
+		{`cat '*.log' "a b" c\ d`, []Word{
+			{Text: "cat", Pattern: "cat"},
+			{Text: "*.log", Pattern: `\*.log`, Quoted: true},
+			{Text: "a b", Pattern: "a b", Quoted: true},
+			{Text: "c d", Pattern: "c d", Quoted: true},
+		}},
+		{`echo "say \"hi\"" 'it''s'`, []Word{
+			{Text: "echo", Pattern: "echo"},
+			{Text: `say "hi"`, Pattern: `say "hi"`, Quoted: true},
+			{Text: "its", Pattern: "its", Quoted: true},
+		}},
+		{`ls "src"/*.go \[x]`, []Word{
+			{Text: "ls", Pattern: "ls"},
+			{Text: "src/*.go", Pattern: "src/*.go", Quoted: true, Glob: true},
+			{Text: "[x]", Pattern: `\[x]`, Quoted: true},
+		}},
+		{`echo "" x`, []Word{
+			{Text: "echo", Pattern: "echo"},
+			{Text: "", Pattern: "", Quoted: true},
+			{Text: "x", Pattern: "x"},
+		}},
+	}
+	for _, tt := range tests {
+		words, err := splitCommandLine(tt.line, false)
+		if err != nil {
+			t.Errorf("SplitCommandLine(%q): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("SplitCommandLine(%q) = %+v, want %+v", tt.line, words, tt.words)
+		}
+	}
+
+	for _, line := range []string{`echo 'open`, `echo "open`} {
+		if _, err := SplitCommandLine(line); err == nil {
+			t.Errorf("SplitCommandLine(%q) succeeded, want an unterminated quote error", line)
+		}
+	}
+}
+
+func TestSplitCommandLineWindows(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []Word
+	}{
+		{`cd C:\src\app`, []Word{
+			{Text: "cd", Pattern: "cd"},
+			{Text: `C:\src\app`, Pattern: "C:/src/app"},
+		}},
+		{`dir C:\src\*.go`, []Word{
+			{Text: "dir", Pattern: "dir"},
+			{Text: `C:\src\*.go`, Pattern: "C:/src/*.go", Glob: true},
+		}},
+		{`type "C:\Program Files\a.txt" '\\server\share'`, []Word{
+			{Text: "type", Pattern: "type"},
+			{Text: `C:\Program Files\a.txt`, Pattern: `C:\\Program Files\\a.txt`, Quoted: true},
+			{Text: `\\server\share`, Pattern: `\\\\server\\share`, Quoted: true},
+		}},
+		{`echo a\ b`, []Word{
+			{Text: "echo", Pattern: "echo"},
+			{Text: `a\`, Pattern: "a/"},
+			{Text: "b", Pattern: "b"},
+		}},
+	}
+	for _, tt := range tests {
+		words, err := splitCommandLine(tt.line, true)
+		if err != nil {
+			t.Errorf("splitCommandLine(%q, windows): %v", tt.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("splitCommandLine(%q, windows) = %+v, want %+v", tt.line, words, tt.words)
+		}
+	}
+}
+
+func TestExpandWords(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.go", "[x].txt", "c d.txt", ".hidden.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "c.go"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	tests := []struct {
+		line    string
+		noMatch string
+		want    []string
+		err     bool
+	}{
+		{"*.go", GlobNoMatchKeep, []string{"a.go", "b.go"}, false},
+		{"'*.go'", GlobNoMatchKeep, []string{"*.go"}, false},
+		{"**/*.go", GlobNoMatchKeep, []string{"a.go", "b.go", "sub/c.go"}, false},
+		{"{a,sub/c}.go", GlobNoMatchKeep, []string{"a.go", "sub/c.go"}, false},
+		{"[[]x].txt", GlobNoMatchKeep, []string{"[x].txt"}, false},
+		{`\[x].txt`, GlobNoMatchKeep, []string{"[x].txt"}, false},
+		{"c?d.txt", GlobNoMatchKeep, []string{"c d.txt"}, false},
+		{"*.rs", GlobNoMatchKeep, []string{"*.rs"}, false},
+		{"*.rs", GlobNoMatchDrop, nil, false},
+		{"*.rs", GlobNoMatchError, nil, true},
+		{"[", GlobNoMatchError, []string{"["}, false},
+	}
+	for _, tt := range tests {
+		words, err := splitCommandLine(tt.line, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ExpandWords(words, tt.noMatch)
+		if (err != nil) != tt.err {
+			t.Errorf("ExpandWords(%q, %s) error = %v, want error %v", tt.line, tt.noMatch, err, tt.err)
+			continue
+		}
+		for i := range got {
+			got[i] = filepath.ToSlash(got[i])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandWords(%q, %s) = %q, want %q", tt.line, tt.noMatch, got, tt.want)
+		}
+	}
+}
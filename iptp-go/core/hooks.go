@@ -0,0 +1,479 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Directory hooks
+//
+// A directory may carry .iptp/enter and .iptp/leave sh scripts. When a
+// process moves into the directory (or below it) the enter hook runs; when it
+// moves out, the leave hook does. Variables a hook exports (or unsets) are
+// applied to the shell, and pulses it writes to $IPTP_PULSES_FILE (JSON lines,
+// as for plugins) are set on the process.
+//
+// Hooks come with checked-out code, so none runs until it is allowed with
+// 'hook allow'. Trust is bound to a hash of the hook's path and contents:
+// editing an allowed hook requires allowing it again.
+
+// hookDirName holds a directory's hooks
+const hookDirName = ".iptp"
+
+// hookEvents are the hook files, in the order 'hook allow' reports them
+var hookEvents = []string{"enter", "leave"}
+
+// hookTimeout stops a hook that hangs
+const hookTimeout = 30 * time.Second
+
+// hookSkipEnv are variables a hook's environment changes by itself
+var hookSkipEnv = map[string]bool{
+	"_": true, "PWD": true, "OLDPWD": true, "SHLVL": true,
+	"IPTP_HOOK": true, "IPTP_HOOK_DIR": true, "IPTP_PROCESS": true, "IPTP_INTENTION": true,
+	"IPTP_PULSES_FILE": true,
+}
+
+// envName is a variable name hooks may set
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// hookTrust is an allowlist decision about one hook file
+type hookTrust struct {
+	Action string // "allow" or "deny"
+	Hash   string
+}
+
+// HookAllowlist records which hook files may run
+type HookAllowlist struct {
+	path    string
+	entries map[string]hookTrust // by hook file path
+}
+
+// getHookAllowPath returns where hook decisions are kept
+// IPTP_HOOKS_ALLOW overrides the location
+func getHookAllowPath() string {
+	if path := os.Getenv("IPTP_HOOKS_ALLOW"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "iptp", "hooks.allow")
+}
+
+// LoadHookAllowlist reads the allowlist; a missing file is an empty list
+// Lines look like: allow|deny <TAB> sha256 <TAB> path
+func LoadHookAllowlist() *HookAllowlist {
+	list := &HookAllowlist{path: getHookAllowPath(), entries: make(map[string]hookTrust)}
+	f, err := os.Open(list.path)
+	if err != nil {
+		return list
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) == 3 && (fields[0] == "allow" || fields[0] == "deny") {
+			list.entries[fields[2]] = hookTrust{Action: fields[0], Hash: fields[1]}
+		}
+	}
+	return list
+}
+
+// Save writes the allowlist, readable only by the user
+func (l *HookAllowlist) Save() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return err
+	}
+
+	paths := make([]string, 0, len(l.entries))
+	for path := range l.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	for _, path := range paths {
+		entry := l.entries[path]
+		fmt.Fprintf(&b, "%s\t%s\t%s\n", entry.Action, entry.Hash, path)
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// hashHookFile hashes a hook's path and contents
+func hashHookFile(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.New()
+	sum.Write([]byte(file))
+	sum.Write([]byte{0})
+	sum.Write(data)
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+// Status reports whether a hook file may run:
+// "allowed", "denied", "changed" (edited since the decision) or "unknown"
+func (l *HookAllowlist) Status(file string) string {
+	entry, ok := l.entries[file]
+	if !ok {
+		return "unknown"
+	}
+	hash, err := hashHookFile(file)
+	if err != nil || hash != entry.Hash {
+		return "changed"
+	}
+	if entry.Action == "deny" {
+		return "denied"
+	}
+	return "allowed"
+}
+
+// Decide records an allow or deny decision for the file's current contents
+func (l *HookAllowlist) Decide(file, action string) error {
+	hash, err := hashHookFile(file)
+	if err != nil {
+		return err
+	}
+	l.entries[file] = hookTrust{Action: action, Hash: hash}
+	return nil
+}
+
+// hookFile returns the path of a directory's hook for an event
+func hookFile(dir, event string) string {
+	return filepath.Join(dir, hookDirName, event)
+}
+
+// hookDirs lists dir and its ancestors that have hooks, innermost first
+func hookDirs(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	var dirs []string
+	for d := filepath.Clean(dir); ; {
+		for _, event := range hookEvents {
+			if info, err := os.Stat(hookFile(d, event)); err == nil && !info.IsDir() {
+				dirs = append(dirs, d)
+				break
+			}
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dirs
+		}
+		d = parent
+	}
+}
+
+// runDirectoryHooks runs the leave hooks of directories the process left and
+// the enter hooks of those it entered, then saves the pulses they set
+func runDirectoryHooks(state *State, process, oldDir, newDir string) {
+	before := hookDirs(oldDir)
+	after := hookDirs(newDir)
+	if len(before) == 0 && len(after) == 0 {
+		return
+	}
+
+	ran := false
+	var list *HookAllowlist
+	for _, dir := range before { // innermost first
+		if !containsString(after, dir) {
+			if list == nil {
+				list = LoadHookAllowlist()
+			}
+			ran = runHook(list, state, process, dir, "leave") || ran
+		}
+	}
+	for i := len(after) - 1; i >= 0; i-- { // outermost first
+		if dir := after[i]; !containsString(before, dir) {
+			if list == nil {
+				list = LoadHookAllowlist()
+			}
+			ran = runHook(list, state, process, dir, "enter") || ran
+		}
+	}
+	if ran {
+		state.Save()
+	}
+}
+
+// runHook runs one hook if it exists and is allowed
+// Returns true if it ran
+func runHook(list *HookAllowlist, state *State, process, dir, event string) bool {
+	file := hookFile(dir, event)
+	if _, err := os.Stat(file); err != nil {
+		return false
+	}
+
+	switch list.Status(file) {
+	case "denied":
+		return false
+	case "unknown":
+		fmt.Printf("✗ %s is not allowed to run: review it, then 'hook allow %s'\n", file, dir)
+		return false
+	case "changed":
+		fmt.Printf("✗ %s changed since it was allowed: review it, then 'hook allow %s'\n", file, dir)
+		return false
+	}
+
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		fmt.Printf("✗ Cannot run %s: hooks need sh\n", file)
+		return false
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Printf("✗ Cannot run %s: %v\n", file, err)
+		return false
+	}
+
+	reply, err := os.CreateTemp("", "iptp-pulses-*.jsonl")
+	if err != nil {
+		fmt.Printf("✗ Cannot create hook reply file: %v\n", err)
+		return false
+	}
+	reply.Close()
+	defer os.Remove(reply.Name())
+
+	// Source the hook, then let iptp report the resulting environment
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	proc, _ := state.GetProcess(process)
+	cmd := exec.CommandContext(ctx, shell, "-c", `. "$1" >&2 && exec "$2" __hookenv`, "iptp-hook", file, self)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"IPTP_HOOK="+event,
+		"IPTP_HOOK_DIR="+dir,
+		"IPTP_PROCESS="+process,
+		"IPTP_INTENTION="+proc.Intention,
+		"IPTP_PULSES_FILE="+reply.Name(),
+	)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Printf("✗ %s timed out after %s\n", file, hookTimeout)
+		} else {
+			fmt.Printf("✗ %s failed: %v\n", file, err)
+		}
+		return false
+	}
+
+	var env []string
+	if err := json.Unmarshal(output, &env); err != nil {
+		fmt.Printf("✗ %s: cannot read its environment: %v\n", file, err)
+		return false
+	}
+	applyHookEnv(file, os.Environ(), env)
+
+	pulses, err := readPluginPulses(reply.Name())
+	if err != nil {
+		fmt.Printf("✗ Ignoring hook pulses: %v\n", err)
+	}
+	for _, pulse := range pulses {
+		state.SetPulse(process, pulse)
+	}
+	return true
+}
+
+// applyHookEnv applies the variables a hook exported or unset, in this
+// process and, through the init wrapper, in the calling shell
+func applyHookEnv(file string, before, after []string) {
+	old := envMap(before)
+	updated := envMap(after)
+
+	var changes []string
+	for name, value := range updated {
+		if hookSkipEnv[name] || !envName.MatchString(name) {
+			continue
+		}
+		if current, ok := old[name]; ok && current == value {
+			continue
+		}
+		os.Setenv(name, value)
+		if hasParentShell() && !parentAction("export", name+"="+value) {
+			fmt.Printf("✗ %s: cannot export %s to the calling shell\n", file, name)
+		}
+		changes = append(changes, "+"+name)
+	}
+	for name := range old {
+		if _, ok := updated[name]; ok || hookSkipEnv[name] || !envName.MatchString(name) {
+			continue
+		}
+		os.Unsetenv(name)
+		if hasParentShell() {
+			parentAction("unset", name)
+		}
+		changes = append(changes, "-"+name)
+	}
+
+	if len(changes) > 0 {
+		sort.Strings(changes)
+		fmt.Printf("iptp: %s %s\n", displayDir(file), strings.Join(changes, " "))
+	}
+}
+
+// envMap indexes NAME=value pairs
+func envMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			m[name] = value
+		}
+	}
+	return m
+}
+
+// cmdHookEnvNonInteractive prints the environment as JSON for runHook
+func cmdHookEnvNonInteractive(state *State, process string, args []string) int {
+	data, _ := json.Marshal(os.Environ())
+	os.Stdout.Write(data)
+	return 0
+}
+
+// hookTarget finds the hook files 'hook allow|deny' acts on: PATH's hooks if
+// it is a directory, PATH itself if it is a file, else the nearest hooks
+// above the current directory
+func hookTarget(args []string) (dir string, files []string, err error) {
+	if len(args) > 0 {
+		path, err := filepath.Abs(args[0])
+		if err != nil {
+			return "", nil, err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", nil, err
+		}
+		if !info.IsDir() {
+			return filepath.Dir(filepath.Dir(path)), []string{path}, nil
+		}
+		dir = path
+	} else {
+		cwd, _ := os.Getwd()
+		dirs := hookDirs(cwd)
+		if len(dirs) == 0 {
+			return "", nil, fmt.Errorf("No %s/enter or %s/leave here or above", hookDirName, hookDirName)
+		}
+		dir = dirs[0]
+	}
+
+	for _, event := range hookEvents {
+		if _, err := os.Stat(hookFile(dir, event)); err == nil {
+			files = append(files, hookFile(dir, event))
+		}
+	}
+	if len(files) == 0 {
+		return "", nil, fmt.Errorf("No hooks in %s", filepath.Join(dir, hookDirName))
+	}
+	return dir, files, nil
+}
+
+// cmdHookNonInteractive handles 'hook allow|deny [PATH]' and 'hook list'
+func cmdHookNonInteractive(state *State, process string, args []string) int {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	list := LoadHookAllowlist()
+
+	switch args[0] {
+	case "list":
+		return listHooks(list)
+
+	case "allow", "deny":
+		action := args[0]
+		dir, files, err := hookTarget(args[1:])
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		for _, file := range files {
+			if err := list.Decide(file, action); err != nil {
+				fmt.Printf("✗ %v\n", err)
+				return 1
+			}
+		}
+		if err := list.Save(); err != nil {
+			fmt.Printf("✗ Cannot save %s: %v\n", list.path, err)
+			return 1
+		}
+		for _, file := range files {
+			if action == "allow" {
+				fmt.Printf("✓ Allowed %s\n", file)
+			} else {
+				fmt.Printf("✓ Denied %s\n", file)
+			}
+		}
+
+		// Like entering it now, if we are already inside
+		cwd, _ := os.Getwd()
+		if action == "allow" && isUnder(cwd, dir) && containsString(files, hookFile(dir, "enter")) {
+			if runHook(list, state, process, dir, "enter") {
+				state.Save()
+			}
+		}
+		return 0
+
+	default:
+		fmt.Println("Usage: hook allow|deny [DIR|FILE] | hook list")
+		return 1
+	}
+}
+
+// listHooks shows every decision, and hooks around the current directory
+// that have none yet
+func listHooks(list *HookAllowlist) int {
+	files := make([]string, 0, len(list.entries))
+	for file := range list.entries {
+		files = append(files, file)
+	}
+	cwd, _ := os.Getwd()
+	for _, dir := range hookDirs(cwd) {
+		for _, event := range hookEvents {
+			file := hookFile(dir, event)
+			if _, known := list.entries[file]; !known {
+				if _, err := os.Stat(file); err == nil {
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	if len(files) == 0 {
+		fmt.Println("No hooks allowed or denied")
+		return 0
+	}
+	sort.Strings(files)
+
+	marks := map[string]string{"allowed": "✓", "denied": "✗", "changed": "!", "unknown": "?"}
+	fmt.Println("=== Directory Hooks ===")
+	for _, file := range files {
+		status := list.Status(file)
+		if _, err := os.Stat(file); err != nil {
+			status = "missing"
+		}
+		mark, ok := marks[status]
+		if !ok {
+			mark = "-"
+		}
+		fmt.Printf("  %s %-8s %s\n", mark, status, file)
+	}
+	return 0
+}
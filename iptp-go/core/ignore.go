@@ -0,0 +1,331 @@
+package core
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Ignore rules for directory search
+//
+// goto, getmethere and the directory index skip directories matched by:
+//   - defaultExcludes (dependency trees, VCS metadata, build caches)
+//   - search_exclude in ~/.iptprc (comma-separated, .gitignore syntax)
+//   - .iptpignore files in the directory and its parents, and .gitignore
+//     files up to the top of the git work tree (the directory holding .git)
+//
+// and do not descend into network mounts unless search_network = true.
+// Later and deeper rules win, and '!' re-includes, as in git.
+
+// defaultExcludes are skipped everywhere unless re-included with '!'
+var defaultExcludes = []string{
+	".git", ".hg", ".svn", "node_modules", "vendor", "bower_components",
+	"__pycache__", ".venv", ".tox", ".mypy_cache", ".pytest_cache",
+	".cache", ".npm", ".yarn", ".gradle", ".m2", ".cargo", ".rustup",
+	".next", ".nuxt", ".terraform", ".Trash", "**/Library/Caches",
+}
+
+// ignorePattern is one line of an ignore file
+type ignorePattern struct {
+	glob     string // without '!', leading and trailing '/'
+	negate   bool
+	anchored bool // contains '/': matched against the path from the file's directory
+}
+
+// ignoreSet is the rules in effect in one directory
+type ignoreSet struct {
+	parent      *ignoreSet
+	dir         string
+	gitPatterns []ignorePattern // from dir's .gitignore
+	patterns    []ignorePattern // from dir's .iptpignore, read after .gitignore
+	repoRoot    bool            // dir holds .git, so .gitignore files above it don't apply below
+	dev         uint64          // device of dir, to notice mount points
+}
+
+// IgnoreRules decides which directories searches skip
+// Ignore files are read once per directory and cached
+type IgnoreRules struct {
+	global  []ignorePattern
+	network bool
+
+	mu   sync.Mutex
+	sets map[string]*ignoreSet
+}
+
+// SearchSettings are the search_* keys of ~/.iptprc
+type SearchSettings struct {
+	Roots    []string // search_roots
+	Excludes []string // search_exclude
+	Network  bool     // search_network
+}
+
+var (
+	searchSettingsOnce sync.Once
+	searchSettings     SearchSettings
+)
+
+// loadSearchSettings reads the search settings from the rc file once
+func loadSearchSettings() SearchSettings {
+	searchSettingsOnce.Do(func() {
+		config, err := LoadConfig(getConfigFilePath())
+		if err != nil {
+			return
+		}
+		if value, ok := config.Get("search_roots"); ok {
+			for _, root := range splitList(value) {
+				if expanded, err := ExpandPath(root); err == nil {
+					searchSettings.Roots = append(searchSettings.Roots, expanded)
+				}
+			}
+		}
+		if value, ok := config.Get("search_exclude"); ok {
+			searchSettings.Excludes = splitList(value)
+		}
+		if value, _ := config.Get("search_network"); value == "true" {
+			searchSettings.Network = true
+		}
+	})
+	return searchSettings
+}
+
+// splitList splits a comma-separated setting, dropping blanks
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+var (
+	ignoreRulesOnce sync.Once
+	ignoreRules     *IgnoreRules
+)
+
+// searchIgnoreRules returns the rules shared by every search in this process
+func searchIgnoreRules() *IgnoreRules {
+	ignoreRulesOnce.Do(func() {
+		settings := loadSearchSettings()
+		ignoreRules = NewIgnoreRules(settings.Excludes, settings.Network)
+	})
+	return ignoreRules
+}
+
+// NewIgnoreRules builds rules from the defaults plus extra exclude patterns
+func NewIgnoreRules(excludes []string, network bool) *IgnoreRules {
+	r := &IgnoreRules{network: network, sets: make(map[string]*ignoreSet)}
+	for _, line := range append(append([]string(nil), defaultExcludes...), excludes...) {
+		if p, ok := parseIgnoreLine(line); ok {
+			r.global = append(r.global, p)
+		}
+	}
+	return r
+}
+
+// Hash identifies the global rules; an index built under other rules is rebuilt
+func (r *IgnoreRules) Hash() string {
+	h := sha256.New()
+	for _, p := range r.global {
+		fmt.Fprintf(h, "%v\n", p)
+	}
+	fmt.Fprintf(h, "network=%v\n", r.network)
+	return fmt.Sprintf("%x", h.Sum(nil))[:16]
+}
+
+// parseIgnoreLine parses one .gitignore line
+func parseIgnoreLine(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // \! and \# match literally
+	}
+	line = strings.TrimSuffix(line, "/") // every search result is a directory
+	if strings.HasPrefix(line, "/") {
+		p.anchored = true
+		line = line[1:]
+	} else if strings.Contains(line, "/") {
+		p.anchored = !strings.HasPrefix(line, "**/")
+		line = strings.TrimPrefix(line, "**/")
+	}
+	if line == "" {
+		return ignorePattern{}, false
+	}
+	p.glob = line
+	return p, true
+}
+
+// readIgnoreFile parses one ignore file, nil if there is none
+func readIgnoreFile(path string) []ignorePattern {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []ignorePattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if p, ok := parseIgnoreLine(scanner.Text()); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// setFor returns the rules in effect in dir, reading ignore files of dir
+// and its parents on first use
+func (r *IgnoreRules) setFor(dir string) *ignoreSet {
+	r.mu.Lock()
+	set, ok := r.sets[dir]
+	r.mu.Unlock()
+	if ok {
+		return set
+	}
+
+	var parent *ignoreSet
+	if up := filepath.Dir(dir); up != dir {
+		parent = r.setFor(up)
+	}
+	set = &ignoreSet{
+		parent:      parent,
+		dir:         dir,
+		gitPatterns: readIgnoreFile(filepath.Join(dir, ".gitignore")),
+		patterns:    readIgnoreFile(filepath.Join(dir, ".iptpignore")),
+	}
+	if _, err := os.Lstat(filepath.Join(dir, ".git")); err == nil {
+		set.repoRoot = true
+	}
+	if info, err := os.Lstat(dir); err == nil {
+		set.dev = deviceOf(info)
+	}
+
+	r.mu.Lock()
+	r.sets[dir] = set
+	r.mu.Unlock()
+	return set
+}
+
+// Ignored reports whether the rules exclude directory dir
+func (r *IgnoreRules) Ignored(dir string) bool {
+	parent := filepath.Dir(dir)
+	if parent == dir {
+		return false
+	}
+
+	// The deepest ignore file with a matching pattern decides; like git,
+	// .gitignore files only count inside the work tree
+	inRepo := true
+	for set := r.setFor(parent); set != nil; set = set.parent {
+		rel, err := filepath.Rel(set.dir, dir)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if ignored, matched := matchIgnorePatterns(set.patterns, rel); matched {
+			return ignored
+		}
+		if inRepo {
+			if ignored, matched := matchIgnorePatterns(set.gitPatterns, rel); matched {
+				return ignored
+			}
+		}
+		if set.repoRoot {
+			inRepo = false
+		}
+	}
+
+	ignored, _ := matchIgnorePatterns(r.global, filepath.ToSlash(dir))
+	return ignored
+}
+
+// Skip reports whether a search should leave out a directory it found:
+// ignored, or the top of a network mount
+func (r *IgnoreRules) Skip(dir string, info os.FileInfo) bool {
+	if r.Ignored(dir) {
+		return true
+	}
+	if r.network {
+		return false
+	}
+	parent := r.setFor(filepath.Dir(dir))
+	return deviceOf(info) != parent.dev && isNetworkFS(dir)
+}
+
+// matchIgnorePatterns applies patterns in order, the last match winning
+// rel is slash-separated; unanchored patterns match any trailing part of it
+func matchIgnorePatterns(patterns []ignorePattern, rel string) (ignored, matched bool) {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		p := patterns[i]
+		if p.anchored {
+			if !globMatch(p.glob, strings.TrimPrefix(rel, "/")) {
+				continue
+			}
+		} else if !matchTail(p.glob, rel) {
+			continue
+		}
+		return !p.negate, true
+	}
+	return false, false
+}
+
+// matchTail matches glob against the last len(glob) path elements of rel,
+// so 'node_modules' and 'Library/Caches' match at any depth
+func matchTail(glob, rel string) bool {
+	segments := strings.Split(strings.TrimPrefix(rel, "/"), "/")
+	n := strings.Count(glob, "/") + 1
+	if n > len(segments) {
+		return false
+	}
+	return globMatch(glob, strings.Join(segments[len(segments)-n:], "/"))
+}
+
+// globMatch matches a slash-separated path against a glob: '*', '?' and
+// '[...]' stay within one element, '**' spans any number of elements
+func globMatch(glob, name string) bool {
+	return matchSegments(strings.Split(glob, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches glob elements against path elements
+func matchSegments(glob, name []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(glob[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(glob[0], name[0]); err != nil || !ok {
+			return false
+		}
+		glob, name = glob[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// searchRoots returns where searches without a starting point look: search_roots
+// from ~/.iptprc, otherwise the given fallback
+func searchRoots(fallback []string) []string {
+	if roots := loadSearchSettings().Roots; len(roots) > 0 {
+		return roots
+	}
+	return fallback
+}
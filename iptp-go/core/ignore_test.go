@@ -0,0 +1,143 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseIgnoreLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ignorePattern
+		ok   bool
+	}{
+		{"", ignorePattern{}, false},
+		{"# comment", ignorePattern{}, false},
+		{"/", ignorePattern{}, false},
+		{"build", ignorePattern{glob: "build"}, true},
+		{"build/  ", ignorePattern{glob: "build"}, true},
+		{"!keep", ignorePattern{glob: "keep", negate: true}, true},
+		{`\!bang`, ignorePattern{glob: "!bang"}, true},
+		{`\#hash`, ignorePattern{glob: "#hash"}, true},
+		{"/out", ignorePattern{glob: "out", anchored: true}, true},
+		{"docs/gen", ignorePattern{glob: "docs/gen", anchored: true}, true},
+		{"**/Library/Caches", ignorePattern{glob: "Library/Caches"}, true},
+		{"!/dist/", ignorePattern{glob: "dist", negate: true, anchored: true}, true},
+	}
+	for _, tt := range tests {
+		got, ok := parseIgnoreLine(tt.line)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseIgnoreLine(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatchIgnorePatterns(t *testing.T) {
+	var patterns []ignorePattern
+	for _, line := range []string{"build", "*.tmp", "/out", "docs/gen", "**/Library/Caches", "logs", "!logs", "cache*", "!cache-keep", "a/**/z"} {
+		p, _ := parseIgnoreLine(line)
+		patterns = append(patterns, p)
+	}
+
+	tests := []struct {
+		rel              string
+		ignored, matched bool
+	}{
+		{"build", true, true},
+		{"src/build", true, true},
+		{"builds", false, false},
+		{"x.tmp", true, true},
+		{"src/x.tmp", true, true},
+		{"out", true, true},
+		{"src/out", false, false}, // anchored to the file's directory
+		{"docs/gen", true, true},
+		{"src/docs/gen", false, false},
+		{"home/me/Library/Caches", true, true},
+		{"logs", false, true}, // re-included by the later !logs
+		{"cache-old", true, true},
+		{"cache-keep", false, true},
+		{"a/z", true, true},
+		{"a/b/c/z", true, true},
+		{"b/a/z", false, false},
+		{"src", false, false},
+	}
+	for _, tt := range tests {
+		ignored, matched := matchIgnorePatterns(patterns, tt.rel)
+		if ignored != tt.ignored || matched != tt.matched {
+			t.Errorf("%q: ignored %v, matched %v, want %v, %v", tt.rel, ignored, matched, tt.ignored, tt.matched)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		glob, name string
+		want       bool
+	}{
+		{"a*", "abc", true},
+		{"a*", "a/bc", false}, // * stays within an element
+		{"a/*", "a/bc", true},
+		{"**", "a/b/c", true},
+		{"**/c", "c", true},
+		{"a/**/c", "a/b/b/c", true},
+		{"a/**", "a", true},
+		{"a/**/c", "a/b/d", false},
+		{"[ab]?", "bz", true},
+		{"[", "[", false}, // bad patterns never match
+	}
+	for _, tt := range tests {
+		if got := globMatch(tt.glob, tt.name); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.glob, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreRulesIgnored(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "dist\n/tmp\n")
+	write("web/.gitignore", "!dist\ngenerated\n")
+	write("web/.iptpignore", "!generated\n")
+	write("api/.iptpignore", "fixtures/large\n")
+	write(".iptpignore", "scratch\n")
+	write("repo/.git/HEAD", "ref: refs/heads/main\n")
+	write("repo/.gitignore", "out\n")
+
+	rules := NewIgnoreRules([]string{"*.bak", "!vendor"}, true)
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"src", false},
+		{"node_modules", true}, // a default exclude
+		{"src/node_modules", true},
+		{"vendor", false}, // default re-included by search_exclude
+		{"old.bak", true}, // search_exclude
+		{"dist", true},    // .gitignore
+		{"api/dist", true},
+		{"web/dist", false}, // re-included by the deeper .gitignore
+		{"tmp", true},
+		{"api/tmp", false},       // /tmp is anchored to the root
+		{"web/generated", false}, // .iptpignore is read after .gitignore
+		{"api/fixtures/large", true},
+		{"api/fixtures", false},
+		{"repo/out", true},
+		{"repo/dist", false},       // the .gitignore above the work tree doesn't apply
+		{"repo/src/scratch", true}, // .iptpignore applies from any parent
+	}
+	for _, tt := range tests {
+		dir := filepath.Join(root, filepath.FromSlash(tt.rel))
+		if got := rules.Ignored(dir); got != tt.want {
+			t.Errorf("Ignored(%s) = %v, want %v", tt.rel, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Parent-shell integration
+//
+// A child process cannot change its parent's directory, so 'iptp init'
+// prints a wrapper function that runs iptp with IPTP_ACTIONS_FILE set.
+// Commands append actions to that file ("cd DIR", "process NAME") and the
+// wrapper applies them in the calling shell once iptp exits.
+
+// actionsFileEnv names the file the shell wrapper reads actions from
+const actionsFileEnv = "IPTP_ACTIONS_FILE"
+
+// hasParentShell reports whether iptp was run through the 'iptp init' wrapper
+func hasParentShell() bool {
+	return os.Getenv(actionsFileEnv) != ""
+}
+
+// parentAction asks the wrapper to run an action in the calling shell
+// Returns false when there is no wrapper to do it
+func parentAction(kind, value string) bool {
+	path := os.Getenv(actionsFileEnv)
+	if path == "" || strings.ContainsAny(value, "\r\n") {
+		return false
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s %s\n", kind, value)
+	return err == nil
+}
+
+// moveParent changes the calling shell's directory, recording it in the process
+// and running the directory hooks (oldDir "" moves without adding to history)
+func moveParent(state *State, process, newDir, oldDir string) {
+	state.UpdateDirectory(process, newDir, oldDir)
+	state.Save()
+
+	if !parentAction("cd", newDir) {
+		fmt.Println("  (run eval \"$(iptp init bash)\" so iptp can move your shell)")
+	}
+
+	leaving := oldDir
+	if leaving == "" {
+		leaving, _ = os.Getwd()
+	}
+	runDirectoryHooks(state, process, leaving, newDir)
+}
+
+// cmdChpwdNonInteractive records a directory change made by the calling shell
+// Called by the 'iptp init' hook: iptp __chpwd NEWDIR [OLDDIR]
+func cmdChpwdNonInteractive(state *State, process string, args []string) int {
+	if len(args) == 0 {
+		return 1
+	}
+	oldDir := ""
+	if len(args) > 1 {
+		oldDir = args[1]
+	}
+	state.UpdateDirectory(process, args[0], oldDir)
+	state.Save()
+	runDirectoryHooks(state, process, oldDir, args[0])
+	return 0
+}
+
+// cmdInitNonInteractive prints the integration script for a shell
+// eval "$(iptp init bash)" in ~/.bashrc, likewise for zsh;
+// iptp init fish | source in config.fish
+func cmdInitNonInteractive(state *State, process string, args []string) int {
+	shell := ""
+	if len(args) > 0 {
+		shell = args[0]
+	}
+
+	switch shell {
+	case "bash":
+		fmt.Print(initPOSIX + initBash)
+	case "zsh":
+		fmt.Print(initPOSIX + initZsh)
+	case "fish":
+		fmt.Print(initFish)
+	default:
+		fmt.Println("Usage: iptp init bash|zsh|fish")
+		fmt.Println()
+		fmt.Println("  bash: add  eval \"$(iptp init bash)\"  to ~/.bashrc")
+		fmt.Println("  zsh:  add  eval \"$(iptp init zsh)\"   to ~/.zshrc")
+		fmt.Println("  fish: add  iptp init fish | source    to ~/.config/fish/config.fish")
+		return 1
+	}
+	return 0
+}
+
+// initPOSIX is the wrapper shared by bash and zsh
+const initPOSIX = `# iptp shell integration
+export iptp_PROCESS="${iptp_PROCESS:-shell_$$}"
+__iptp_pwd="$PWD"
+
+iptp() {
+    local __iptp_actions __iptp_status __iptp_line
+    __iptp_actions="$(mktemp "${TMPDIR:-/tmp}/iptp-actions.XXXXXX")" || { command iptp "$@"; return; }
+    IPTP_ACTIONS_FILE="$__iptp_actions" command iptp "$@"
+    __iptp_status=$?
+    __iptp_busy=1
+    while IFS= read -r __iptp_line; do
+        case "$__iptp_line" in
+            "cd "*) builtin cd -- "${__iptp_line#cd }" ;;
+            "process "*) export iptp_PROCESS="${__iptp_line#process }" ;;
+            "export "*) __iptp_line="${__iptp_line#export }"; export "${__iptp_line%%=*}=${__iptp_line#*=}" ;;
+            "unset "*) unset "${__iptp_line#unset }" ;;
+        esac
+    done < "$__iptp_actions"
+    rm -f "$__iptp_actions"
+    __iptp_pwd="$PWD"
+    __iptp_busy=
+    return $__iptp_status
+}
+
+# Records cd's made in this shell and runs directory hooks; iptp records
+# its own moves itself
+__iptp_chpwd() {
+    if [ -z "$__iptp_busy" ] && [ "$PWD" != "$__iptp_pwd" ]; then
+        iptp __chpwd "$PWD" "$__iptp_pwd"
+        __iptp_pwd="$PWD"
+    fi
+}
+`
+
+const initBash = `
+case ";${PROMPT_COMMAND:-};" in
+    *";__iptp_chpwd;"*) ;;
+    *) PROMPT_COMMAND="__iptp_chpwd${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+
+__iptp_complete() {
+    local IFS=$'\n'
+    COMPREPLY=($(command iptp __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F __iptp_complete iptp
+`
+
+const initZsh = `
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd __iptp_chpwd
+
+_iptp() {
+    local -a candidates
+    candidates=("${(@f)$(command iptp __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    compadd -a candidates
+}
+(( $+functions[compdef] )) && compdef _iptp iptp
+`
+
+const initFish = `# iptp shell integration
+set -q iptp_PROCESS; or set -gx iptp_PROCESS shell_$fish_pid
+set -g __iptp_pwd $PWD
+
+function iptp
+    set -l actions (mktemp (set -q TMPDIR; and echo $TMPDIR; or echo /tmp)/iptp-actions.XXXXXX)
+    or begin
+        command iptp $argv
+        return
+    end
+    IPTP_ACTIONS_FILE=$actions command iptp $argv
+    set -l iptp_status $status
+    set -g __iptp_busy 1
+    while read -l line
+        switch $line
+            case 'cd *'
+                builtin cd -- (string sub -s 4 -- $line)
+            case 'process *'
+                set -gx iptp_PROCESS (string sub -s 9 -- $line)
+            case 'export *'
+                set -l kv (string split -m 1 = -- (string sub -s 8 -- $line))
+                set -gx $kv[1] $kv[2]
+            case 'unset *'
+                set -e (string sub -s 7 -- $line)
+        end
+    end < $actions
+    rm -f $actions
+    set -g __iptp_pwd $PWD
+    set -e __iptp_busy
+    return $iptp_status
+end
+
+function __iptp_chpwd --on-variable PWD
+    if not set -q __iptp_busy; and test "$PWD" != "$__iptp_pwd"
+        iptp __chpwd $PWD $__iptp_pwd
+        set -g __iptp_pwd $PWD
+    end
+end
+
+complete -c iptp -f -a '(command iptp __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`
@@ -0,0 +1,272 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Structured intentions
+//
+// Besides the raw text, a named process keeps what its intention says: the
+// action verb and subject (from the grammar, see intention.go), a ticket
+// reference (ABC-123, #42), #tags and a due date ("by friday"). Tickets, tags
+// and due dates are taken out of the phrase before the grammar sees it, so
+// "fix the login bug ABC-123 #backend by friday" is named login_bug.
+
+// Intent is the structured form of an intention
+type Intent struct {
+	Verb    string   `json:"verb"`
+	Subject string   `json:"subject"`
+	Ticket  string   `json:"ticket"`
+	Tags    []string `json:"tags"`
+	Due     string   `json:"due"` // YYYY-MM-DD, "" if none
+}
+
+var (
+	// ticketPattern matches JIRA-style keys (ABC-123) and issue numbers (#42)
+	ticketPattern = regexp.MustCompile(`(?:^|\s)([A-Z][A-Z0-9]+-[0-9]+|#[0-9]+)\b`)
+
+	// tagPattern matches #tags; they start with a letter
+	tagPattern = regexp.MustCompile(`(?:^|\s)#([A-Za-z][A-Za-z0-9_-]*)`)
+
+	// duePattern matches "by friday", "due tomorrow", "before 2026-11-02", ...
+	duePattern = regexp.MustCompile(`(?i)(?:^|\s)(?:by|due|before|until)\s+(next\s+)?(today|tonight|tomorrow|eod|eow|end of (?:the )?(?:day|week|month)|monday|tuesday|wednesday|thursday|friday|saturday|sunday|mon|tue|wed|thu|fri|sat|sun|\d{4}-\d{2}-\d{2})\b`)
+)
+
+// weekdays by name and abbreviation
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+	"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday,
+	"saturday": time.Saturday, "sun": time.Sunday, "mon": time.Monday,
+	"tue": time.Tuesday, "wed": time.Wednesday, "thu": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseIntent extracts the structured fields of an intention and the process
+// name it gives; relative due dates are resolved against now
+func ParseIntent(intention string, now time.Time) (Intent, IntentionMatch) {
+	intent := Intent{Tags: []string{}}
+	text := intention
+
+	if m := duePattern.FindStringSubmatchIndex(text); m != nil {
+		next := m[2] >= 0
+		word := strings.ToLower(strings.Join(strings.Fields(text[m[4]:m[5]]), " "))
+		if due, ok := resolveDue(word, next, now); ok {
+			intent.Due = due.Format("2006-01-02")
+			text = text[:m[0]] + " " + text[m[1]:]
+		}
+	}
+
+	if m := ticketPattern.FindStringSubmatch(text); m != nil {
+		intent.Ticket = m[1]
+		text = strings.Replace(text, m[1], " ", 1)
+	}
+
+	for _, m := range tagPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(m[1])
+		if !containsString(intent.Tags, tag) {
+			intent.Tags = append(intent.Tags, tag)
+		}
+	}
+	text = tagPattern.ReplaceAllString(text, " ")
+
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		// Nothing but markers: name it after the ticket or the first tag
+		name := intent.Ticket
+		if name == "" && len(intent.Tags) > 0 {
+			name = intent.Tags[0]
+		}
+		return intent, IntentionMatch{Name: sanitizeProcessName(name), Rule: "markers only"}
+	}
+
+	match := matchIntention(text)
+	intent.Verb = strings.ToLower(match.Verb)
+	intent.Subject = match.Subject
+	return intent, match
+}
+
+// resolveDue turns a due phrase into a date
+// Weekdays are the next one from today (today included); "next" adds a week
+func resolveDue(word string, next bool, now time.Time) (time.Time, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var due time.Time
+	switch {
+	case word == "today" || word == "tonight" || word == "eod" || strings.HasSuffix(word, " day"):
+		due = today
+	case word == "tomorrow":
+		due = today.AddDate(0, 0, 1)
+	case word == "eow" || strings.HasSuffix(word, " week"):
+		due = today.AddDate(0, 0, (int(time.Friday)-int(today.Weekday())+7)%7)
+	case strings.HasSuffix(word, " month"):
+		due = time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location())
+	default:
+		if day, ok := weekdays[word]; ok {
+			due = today.AddDate(0, 0, (int(day)-int(today.Weekday())+7)%7)
+			break
+		}
+		date, err := time.ParseInLocation("2006-01-02", word, now.Location())
+		if err != nil {
+			return time.Time{}, false
+		}
+		return date, true
+	}
+
+	if next {
+		due = due.AddDate(0, 0, 7)
+	}
+	return due, true
+}
+
+// Overdue reports whether the due date has passed
+func (i Intent) Overdue(now time.Time) bool {
+	return i.Due != "" && i.Due < now.Format("2006-01-02")
+}
+
+// Empty reports whether nothing structured was found
+func (i Intent) Empty() bool {
+	return i.Verb == "" && i.Subject == "" && i.Ticket == "" && len(i.Tags) == 0 && i.Due == ""
+}
+
+// printIntent shows the structured fields under 'state'
+func printIntent(intent Intent) {
+	if intent.Verb != "" {
+		fmt.Printf("  Verb:    %s\n", intent.Verb)
+	}
+	if intent.Subject != "" {
+		fmt.Printf("  Subject: %s\n", intent.Subject)
+	}
+	if intent.Ticket != "" {
+		fmt.Printf("  Ticket:  %s\n", intent.Ticket)
+	}
+	if len(intent.Tags) > 0 {
+		fmt.Printf("  Tags:    #%s\n", strings.Join(intent.Tags, " #"))
+	}
+	if intent.Due != "" {
+		due := intent.Due
+		if date, err := time.Parse("2006-01-02", intent.Due); err == nil {
+			due += " (" + date.Format("Mon") + ")"
+		}
+		if intent.Overdue(time.Now()) {
+			due += " — overdue"
+		}
+		fmt.Printf("  Due:     %s\n", due)
+	}
+}
+
+// intentMarker is the ticket, tags and due date shown after a process in 'list'
+func intentMarker(intent Intent) string {
+	var parts []string
+	if intent.Ticket != "" {
+		parts = append(parts, intent.Ticket)
+	}
+	for _, tag := range intent.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	if intent.Due != "" {
+		if intent.Overdue(time.Now()) {
+			parts = append(parts, "overdue "+intent.Due)
+		} else {
+			parts = append(parts, "due "+intent.Due)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(parts, " ") + "]"
+}
+
+// IntentFilter selects processes in 'list' by their intention
+type IntentFilter struct {
+	Tags    []string
+	Ticket  string
+	Verb    string
+	Subject string
+	Due     string // overdue, today, week, or a date: due on or before it
+}
+
+// parseIntentFilter removes --tag, --ticket, --verb, --subject and --due
+// from args
+func parseIntentFilter(args []string) (IntentFilter, []string, error) {
+	var filter IntentFilter
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := strings.Cut(args[i], "=")
+		switch flag {
+		case "--tag", "--ticket", "--verb", "--subject", "--due":
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return filter, nil, fmt.Errorf("%s needs a value", flag)
+			}
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "--tag":
+			for _, tag := range strings.Split(value, ",") {
+				filter.Tags = append(filter.Tags, strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#")))
+			}
+		case "--ticket":
+			filter.Ticket = value
+		case "--verb":
+			filter.Verb = strings.ToLower(value)
+		case "--subject":
+			filter.Subject = strings.ToLower(value)
+		case "--due":
+			if _, err := time.Parse("2006-01-02", value); err != nil && value != "overdue" && value != "today" && value != "week" {
+				return filter, nil, fmt.Errorf("--due takes overdue, today, week or YYYY-MM-DD")
+			}
+			filter.Due = value
+		}
+	}
+	return filter, rest, nil
+}
+
+// Empty reports whether the filter lets every process through
+func (f IntentFilter) Empty() bool {
+	return len(f.Tags) == 0 && f.Ticket == "" && f.Verb == "" && f.Subject == "" && f.Due == ""
+}
+
+// Match reports whether an intent passes the filter
+func (f IntentFilter) Match(intent Intent, now time.Time) bool {
+	for _, tag := range f.Tags {
+		if !containsString(intent.Tags, tag) {
+			return false
+		}
+	}
+	if f.Ticket != "" && !strings.EqualFold(f.Ticket, intent.Ticket) {
+		return false
+	}
+	if f.Verb != "" && f.Verb != intent.Verb {
+		return false
+	}
+	if f.Subject != "" && !strings.Contains(strings.ToLower(intent.Subject), f.Subject) {
+		return false
+	}
+
+	if f.Due == "" {
+		return true
+	}
+	if intent.Due == "" {
+		return false
+	}
+	today := now.Format("2006-01-02")
+	switch f.Due {
+	case "overdue":
+		return intent.Overdue(now)
+	case "today":
+		return intent.Due <= today
+	case "week":
+		return intent.Due <= now.AddDate(0, 0, 7).Format("2006-01-02")
+	default:
+		return intent.Due <= f.Due
+	}
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// useBuiltinGrammar points the grammar file somewhere that doesn't exist
+func useBuiltinGrammar(t *testing.T) {
+	t.Helper()
+	t.Setenv("IPTP_GRAMMAR", filepath.Join(t.TempDir(), "intentions.rules"))
+}
+
+func TestParseIntent(t *testing.T) {
+	useBuiltinGrammar(t)
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC) // a Wednesday
+
+	tests := []struct {
+		intention string
+		name      string
+		intent    Intent
+	}{
+		{
+			"fix the login bug ABC-123 #backend by friday",
+			"login_bug",
+			Intent{Verb: "fix", Subject: "login bug", Ticket: "ABC-123", Tags: []string{"backend"}, Due: "2026-10-16"},
+		},
+		{
+			"working on payments #42 #Ops #ops due tomorrow",
+			"payments",
+			Intent{Verb: "working", Subject: "payments", Ticket: "#42", Tags: []string{"ops"}, Due: "2026-10-15"},
+		},
+		{
+			"review the release notes before next monday",
+			"release_notes",
+			Intent{Verb: "review", Subject: "release notes", Tags: []string{}, Due: "2026-10-26"},
+		},
+		{
+			"write docs until 2026-11-02",
+			"docs",
+			Intent{Verb: "write", Subject: "docs", Tags: []string{}, Due: "2026-11-02"},
+		},
+		{
+			"deploy by wednesday",
+			"deploy",
+			Intent{Subject: "deploy", Tags: []string{}, Due: "2026-10-14"},
+		},
+		{
+			"ship it by end of the month",
+			"ship_it",
+			Intent{Subject: "ship it", Tags: []string{}, Due: "2026-10-31"},
+		},
+		{
+			"OPS-7 #infra",
+			"OPS-7",
+			Intent{Ticket: "OPS-7", Tags: []string{"infra"}},
+		},
+		{
+			"stand by the door", // not a date: left in the phrase
+			"stand_by_door",
+			Intent{Subject: "stand by door", Tags: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		intent, match := ParseIntent(tt.intention, now)
+		if match.Name != tt.name {
+			t.Errorf("ParseIntent(%q) named %q, want %q", tt.intention, match.Name, tt.name)
+		}
+		if !reflect.DeepEqual(intent, tt.intent) {
+			t.Errorf("ParseIntent(%q) = %+v, want %+v", tt.intention, intent, tt.intent)
+		}
+	}
+}
@@ -0,0 +1,504 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Intention grammar
+//
+// 'name INTENTION' turns a phrase into a process name with an ordered list
+// of rules read from a user-editable file. Each rule is a regular expression
+// and a template over its captures; the first rule that matches wins, and
+// stop words are dropped from the result. Rules are grouped in language
+// packs, and 'use' picks the packs that apply. Without a file the built-in
+// grammar below is used; 'grammar init' writes it out for editing.
+
+// defaultGrammar is used when there is no grammar file
+const defaultGrammar = `# iptp intention grammar
+#
+# 'name INTENTION' tries the rules in order; the first whose pattern matches
+# names the process after its template, with stop words dropped. Without a
+# match, the last few words that are not stop words are used.
+#
+#   use PACK...                     packs in use (default: all of them)
+#   [PACK]                          the lines below belong to PACK
+#   stop WORD...                    words left out of names
+#   rule NAME: PATTERN => TEMPLATE  PATTERN is a case-insensitive Go regexp,
+#                                   TEMPLATE refers to captures as $1 or ${name};
+#                                   a (?P<verb>...) capture is the action verb
+#   test PHRASE => NAME             checked by 'grammar test'
+#
+# Lines before the first [PACK] are used whatever packs are in use.
+
+use en
+
+[en]
+stop the a an my our their this that these some its to for of at with into about
+rule working-on: ^(?:(?:i am|i'm|we are|we're) )?(?P<verb>working) (?:in|on) (?P<subject>.+)$ => ${subject}
+rule want-to-work: ^(?:i|we) (?:want|need|have) to (?P<verb>work) on (?P<subject>.+)$ => ${subject}
+rule session: ^(.+) session$ => $1
+rule doing: ^(?P<verb>\w+ing) (?:on |in |into |up |through )?(?P<subject>.+)$ => ${subject}
+rule imperative: ^(?P<verb>fix|debug|build|refactor|review|write|investigate|deploy|test|update|clean up|look into) (?P<subject>.+)$ => ${subject}
+test I am working on authentication => authentication
+test working in api => api
+test debugging nginx configuration => nginx_configuration
+test refactoring the payment service => payment_service
+test api session => api
+test fix the login bug => login_bug
+
+[de]
+stop der die das den dem des ein eine einen einem mein meine meinen am im an zum zur
+rule arbeite-an: ^(?:ich|wir) (?P<verb>arbeite|arbeiten) (?:an|in|am|im) (?P<subject>.+)$ => ${subject}
+test ich arbeite am Zahlungsdienst => Zahlungsdienst
+
+[fr]
+stop le la les l un une mon ma mes du de des sur
+rule travaille-sur: ^(?:je|nous) (?P<verb>travaille|travaillons) (?:sur|dans|à) (?P<subject>.+)$ => ${subject}
+test je travaille sur le service de paiement => service_paiement
+`
+
+// fallbackWords is how many words the fallback keeps
+const fallbackWords = 3
+
+// IntentionRule is one rule of the grammar
+type IntentionRule struct {
+	Name     string
+	Pack     string // "" for rules outside any pack
+	Pattern  *regexp.Regexp
+	Template string
+	Line     int
+}
+
+// IntentionTest is a sample phrase and the name it should give
+type IntentionTest struct {
+	Phrase string
+	Want   string
+	Pack   string
+	Line   int
+}
+
+// Grammar is a parsed intention grammar
+type Grammar struct {
+	Path  string // "" for the built-in grammar
+	Use   []string
+	Packs []string // all packs, in file order
+	Rules []IntentionRule
+	Stop  map[string][]string // stop words by pack
+	Tests []IntentionTest
+}
+
+// IntentionMatch explains how a phrase became a process name
+type IntentionMatch struct {
+	Name    string
+	Rule    string // rule name, or "single word" / "fallback"
+	Pack    string
+	Line    int
+	Capture string   // the template filled in, before stop words are dropped
+	Dropped []string // stop words left out
+	Verb    string   // the rule's (?P<verb>...) capture, if any
+	Subject string   // Capture without the stop words
+}
+
+// getGrammarPath returns the grammar file: $IPTP_GRAMMAR, or intentions.rules
+// in the user config directory
+func getGrammarPath() string {
+	if path := os.Getenv("IPTP_GRAMMAR"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "iptp", "intentions.rules")
+}
+
+// ParseGrammar reads a grammar; path is only used in error messages
+func ParseGrammar(text, path string) (*Grammar, error) {
+	grammar := &Grammar{Path: path, Stop: make(map[string][]string)}
+	where := path
+	if where == "" {
+		where = "built-in grammar"
+	}
+
+	pack := ""
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			pack = strings.TrimSpace(line[1 : len(line)-1])
+			if !containsString(grammar.Packs, pack) {
+				grammar.Packs = append(grammar.Packs, pack)
+			}
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch keyword {
+		case "use":
+			grammar.Use = append(grammar.Use, strings.Fields(rest)...)
+
+		case "stop":
+			for _, word := range strings.Fields(rest) {
+				grammar.Stop[pack] = append(grammar.Stop[pack], strings.ToLower(word))
+			}
+
+		case "rule":
+			name, spec, ok := strings.Cut(rest, ":")
+			pattern, template, ok2 := strings.Cut(spec, "=>")
+			if !ok || !ok2 || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("%s:%d: expected rule NAME: PATTERN => TEMPLATE", where, lineNo)
+			}
+			re, err := regexp.Compile("(?i)" + strings.TrimSpace(pattern))
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", where, lineNo, err)
+			}
+			grammar.Rules = append(grammar.Rules, IntentionRule{
+				Name:     strings.TrimSpace(name),
+				Pack:     pack,
+				Pattern:  re,
+				Template: strings.TrimSpace(template),
+				Line:     lineNo,
+			})
+
+		case "test":
+			phrase, want, ok := strings.Cut(rest, "=>")
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: expected test PHRASE => NAME", where, lineNo)
+			}
+			grammar.Tests = append(grammar.Tests, IntentionTest{
+				Phrase: strings.TrimSpace(phrase),
+				Want:   strings.TrimSpace(want),
+				Pack:   pack,
+				Line:   lineNo,
+			})
+
+		default:
+			return nil, fmt.Errorf("%s:%d: unknown line: %s", where, lineNo, line)
+		}
+	}
+
+	for _, pack := range grammar.Use {
+		if !containsString(grammar.Packs, pack) {
+			return nil, fmt.Errorf("%s: use %s: no such pack", where, pack)
+		}
+	}
+	return grammar, nil
+}
+
+// Active reports whether a pack's rules and stop words apply
+func (g *Grammar) Active(pack string) bool {
+	return pack == "" || len(g.Use) == 0 || containsString(g.Use, pack)
+}
+
+// stopWords returns the stop words of the packs in use
+func (g *Grammar) stopWords() map[string]bool {
+	words := make(map[string]bool)
+	for pack, list := range g.Stop {
+		if g.Active(pack) {
+			for _, word := range list {
+				words[word] = true
+			}
+		}
+	}
+	return words
+}
+
+// Match turns an intention into a process name, explaining how
+func (g *Grammar) Match(intention string) IntentionMatch {
+	intention = strings.Join(strings.Fields(intention), " ")
+
+	// A single word is already a name
+	if !strings.Contains(intention, " ") {
+		return IntentionMatch{Name: sanitizeProcessName(intention), Rule: "single word", Capture: intention, Subject: intention}
+	}
+
+	stop := g.stopWords()
+	for _, rule := range g.Rules {
+		if !g.Active(rule.Pack) {
+			continue
+		}
+		submatches := rule.Pattern.FindStringSubmatchIndex(intention)
+		if submatches == nil {
+			continue
+		}
+		capture := string(rule.Pattern.ExpandString(nil, rule.Template, intention, submatches))
+		if strings.TrimSpace(capture) == "" {
+			continue
+		}
+		words, dropped := dropStopWords(strings.Fields(capture), stop)
+		match := IntentionMatch{
+			Name:    sanitizeProcessName(strings.Join(words, " ")),
+			Rule:    rule.Name,
+			Pack:    rule.Pack,
+			Line:    rule.Line,
+			Capture: capture,
+			Dropped: dropped,
+			Subject: strings.Join(words, " "),
+		}
+		if i := rule.Pattern.SubexpIndex("verb"); i > 0 && submatches[2*i] >= 0 {
+			match.Verb = intention[submatches[2*i]:submatches[2*i+1]]
+		}
+		return match
+	}
+
+	// No rule matched: the last few words that carry meaning
+	words, dropped := dropStopWords(strings.Fields(intention), stop)
+	subject := strings.Join(words, " ")
+	if len(words) > fallbackWords {
+		words = words[len(words)-fallbackWords:]
+	}
+	return IntentionMatch{
+		Name:    sanitizeProcessName(strings.Join(words, " ")),
+		Rule:    "fallback",
+		Capture: intention,
+		Dropped: dropped,
+		Subject: subject,
+	}
+}
+
+// dropStopWords removes stop words, unless nothing else would be left
+func dropStopWords(words []string, stop map[string]bool) ([]string, []string) {
+	var kept, dropped []string
+	for _, word := range words {
+		if stop[strings.ToLower(strings.Trim(word, ".,;:!?'\""))] {
+			dropped = append(dropped, word)
+		} else {
+			kept = append(kept, word)
+		}
+	}
+	if len(kept) == 0 {
+		return words, nil
+	}
+	return kept, dropped
+}
+
+// grammarCache keeps the compiled grammar until its file changes
+var grammarCache struct {
+	sync.Mutex
+	path     string
+	modTime  time.Time
+	grammar  *Grammar
+	err      error
+	reported bool // err was shown to the user
+}
+
+// LoadGrammar returns the grammar in use, the built-in one if there is no
+// grammar file. The file is only parsed again when it changes.
+func LoadGrammar() (*Grammar, error) {
+	path := getGrammarPath()
+	var modTime time.Time
+	if info, err := os.Stat(path); err == nil {
+		modTime = info.ModTime()
+	}
+
+	grammarCache.Lock()
+	defer grammarCache.Unlock()
+	if grammarCache.grammar != nil && grammarCache.path == path && grammarCache.modTime.Equal(modTime) {
+		return grammarCache.grammar, grammarCache.err
+	}
+
+	grammar, err := ParseGrammar(defaultGrammar, "")
+	if err != nil {
+		panic(err) // the built-in grammar is known to parse
+	}
+	if !modTime.IsZero() {
+		if data, readErr := os.ReadFile(path); readErr != nil {
+			err = readErr
+		} else if userGrammar, parseErr := ParseGrammar(string(data), path); parseErr != nil {
+			err = parseErr
+		} else {
+			grammar = userGrammar
+		}
+	}
+
+	grammarCache.path, grammarCache.modTime = path, modTime
+	grammarCache.grammar, grammarCache.err = grammar, err
+	grammarCache.reported = false
+	return grammar, err
+}
+
+// matchIntention matches a phrase against the grammar in use
+func matchIntention(intention string) IntentionMatch {
+	grammar, err := LoadGrammar()
+	if err != nil {
+		grammarCache.Lock()
+		if !grammarCache.reported {
+			fmt.Fprintf(os.Stderr, "✗ Intention grammar: %v (using the built-in grammar)\n", err)
+			grammarCache.reported = true
+		}
+		grammarCache.Unlock()
+	}
+	return grammar.Match(intention)
+}
+
+// parseExplainFlag strips --explain from the arguments of 'name'
+func parseExplainFlag(args []string) (bool, []string) {
+	explain := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--explain" {
+			explain = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	return explain, rest
+}
+
+// printIntentionMatch shows which rule named the process
+func printIntentionMatch(match IntentionMatch) {
+	switch match.Rule {
+	case "single word", "fallback", "markers only":
+		fmt.Printf("  Rule:      %s\n", match.Rule)
+	default:
+		pack := match.Pack
+		if pack == "" {
+			pack = "no pack"
+		}
+		fmt.Printf("  Rule:      %s (%s, line %d)\n", match.Rule, pack, match.Line)
+	}
+	if match.Capture != "" {
+		fmt.Printf("  Captured:  %s\n", match.Capture)
+	}
+	if len(match.Dropped) > 0 {
+		fmt.Printf("  Dropped:   %s\n", strings.Join(match.Dropped, " "))
+	}
+	fmt.Printf("  Name:      %s\n", match.Name)
+}
+
+// cmdGrammar handles 'grammar', 'grammar init' and 'grammar test'
+func cmdGrammar(args []string) int {
+	if len(args) == 0 {
+		return showGrammar()
+	}
+
+	switch args[0] {
+	case "init":
+		return initGrammar()
+	case "test":
+		if len(args) > 1 {
+			phrase := strings.Join(args[1:], " ")
+			intent, match := ParseIntent(phrase, time.Now())
+			fmt.Println(phrase)
+			printIntentionMatch(match)
+			printIntent(intent)
+			return 0
+		}
+		return testGrammar()
+	default:
+		fmt.Println("Usage: grammar [init|test [PHRASE...]]")
+		return 1
+	}
+}
+
+// showGrammar lists the rules in use
+func showGrammar() int {
+	grammar, err := LoadGrammar()
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		fmt.Println("  (using the built-in grammar)")
+	}
+
+	source := grammar.Path
+	if source == "" {
+		source = "built-in (run 'grammar init' to edit it at " + getGrammarPath() + ")"
+	}
+	fmt.Println("=== Intention Grammar ===")
+	fmt.Printf("  File:  %s\n", source)
+
+	var packs []string
+	for _, pack := range grammar.Packs {
+		if grammar.Active(pack) {
+			packs = append(packs, pack)
+		} else {
+			packs = append(packs, "("+pack+")")
+		}
+	}
+	fmt.Printf("  Packs: %s\n", strings.Join(packs, " "))
+
+	stop := make([]string, 0)
+	for word := range grammar.stopWords() {
+		stop = append(stop, word)
+	}
+	sort.Strings(stop)
+	fmt.Printf("  Stop:  %s\n", strings.Join(stop, " "))
+
+	fmt.Println("\n=== Rules (in order) ===")
+	for _, rule := range grammar.Rules {
+		if !grammar.Active(rule.Pack) {
+			continue
+		}
+		pattern := strings.TrimPrefix(rule.Pattern.String(), "(?i)")
+		fmt.Printf("  %-14s %s => %s\n", rule.Name, pattern, rule.Template)
+	}
+	return 0
+}
+
+// initGrammar writes the built-in grammar to the grammar file for editing
+func initGrammar() int {
+	path := getGrammarPath()
+	if _, err := os.Stat(path); err == nil {
+		fmt.Printf("✗ %s already exists\n", path)
+		return 1
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(path, []byte(defaultGrammar), 0644); err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ Wrote %s\n", path)
+	return 0
+}
+
+// testGrammar checks the grammar's test phrases
+func testGrammar() int {
+	grammar, err := LoadGrammar()
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return 1
+	}
+
+	passed, failed, skipped := 0, 0, 0
+	for _, test := range grammar.Tests {
+		if !grammar.Active(test.Pack) {
+			skipped++
+			continue
+		}
+		match := grammar.Match(test.Phrase)
+		if match.Name == test.Want {
+			passed++
+			fmt.Printf("  ✓ %-45s → %s (%s)\n", test.Phrase, match.Name, match.Rule)
+			continue
+		}
+		failed++
+		fmt.Printf("  ✗ %-45s → %s (%s), want %s (line %d)\n",
+			test.Phrase, match.Name, match.Rule, test.Want, test.Line)
+	}
+
+	fmt.Printf("\n%d passed, %d failed", passed, failed)
+	if skipped > 0 {
+		fmt.Printf(", %d skipped (packs not in use)", skipped)
+	}
+	fmt.Println()
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
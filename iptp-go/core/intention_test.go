@@ -0,0 +1,108 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestBuiltinGrammarTests runs the built-in grammar's own tests, each with
+// its pack in use
+func TestBuiltinGrammarTests(t *testing.T) {
+	grammar, err := ParseGrammar(defaultGrammar, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range grammar.Tests {
+		grammar.Use = []string{test.Pack}
+		if got := grammar.Match(test.Phrase).Name; got != test.Want {
+			t.Errorf("line %d: %q named %q, want %q", test.Line, test.Phrase, got, test.Want)
+		}
+	}
+}
+
+func TestGrammarMatch(t *testing.T) {
+	grammar, err := ParseGrammar(defaultGrammar, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		phrase  string
+		name    string
+		rule    string
+		verb    string
+		dropped []string
+	}{
+		{"api", "api", "single word", "", nil},
+		{"  working   on   the   api  ", "api", "working-on", "working", []string{"the"}},
+		{"I'm working on my dotfiles", "dotfiles", "working-on", "working", []string{"my"}},
+		{"fix the login bug", "login_bug", "imperative", "fix", []string{"the"}},
+		{"debugging nginx configuration", "nginx_configuration", "doing", "debugging", nil},
+		{"clean up the old release branches", "old_release_branches", "imperative", "clean up", []string{"the"}},
+		{"notes from the quarterly planning meeting", "quarterly_planning_meeting", "fallback", "", []string{"the"}},
+		{"the a an", "the_a_an", "fallback", "", nil}, // only stop words: all kept
+	}
+	for _, tt := range tests {
+		match := grammar.Match(tt.phrase)
+		if match.Name != tt.name || match.Rule != tt.rule || match.Verb != tt.verb {
+			t.Errorf("Match(%q) = %q by %q (verb %q), want %q by %q (verb %q)",
+				tt.phrase, match.Name, match.Rule, match.Verb, tt.name, tt.rule, tt.verb)
+		}
+		if !reflect.DeepEqual(match.Dropped, tt.dropped) {
+			t.Errorf("Match(%q) dropped %q, want %q", tt.phrase, match.Dropped, tt.dropped)
+		}
+	}
+}
+
+func TestGrammarPacks(t *testing.T) {
+	const text = `
+stop always
+[en]
+stop the
+rule en-on: ^on (.+)$ => $1
+[de]
+stop der
+rule de-an: ^an (.+)$ => $1
+`
+	tests := []struct {
+		use    string
+		phrase string
+		name   string
+		rule   string
+	}{
+		{"", "an der always api", "api", "de-an"},
+		{"use en", "an der always api", "an_der_api", "fallback"},
+		{"use en", "on the always api", "api", "en-on"},
+		{"use de", "on the api", "on_the_api", "fallback"},
+		{"use de", "an der api", "api", "de-an"},
+		{"use en de", "an the der api", "api", "de-an"},
+	}
+	for _, tt := range tests {
+		grammar, err := ParseGrammar(tt.use+"\n"+text, "test.rules")
+		if err != nil {
+			t.Fatalf("%q: %v", tt.use, err)
+		}
+		if match := grammar.Match(tt.phrase); match.Name != tt.name || match.Rule != tt.rule {
+			t.Errorf("%q: Match(%q) = %q by %q, want %q by %q", tt.use, tt.phrase, match.Name, match.Rule, tt.name, tt.rule)
+		}
+	}
+}
+
+func TestParseGrammarErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"rule broken", "test.rules:1: expected rule NAME: PATTERN => TEMPLATE"},
+		{"rule x: ^(a$ => $1", "test.rules:1: error parsing regexp: missing closing ): `(?i)^(a$`"},
+		{"\ntest no arrow", "test.rules:2: expected test PHRASE => NAME"},
+		{"[en]\nsay hello", "test.rules:2: unknown line: say hello"},
+		{"use fr\n[en]", "test.rules: use fr: no such pack"},
+	}
+	for _, tt := range tests {
+		_, err := ParseGrammar(tt.text, "test.rules")
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("ParseGrammar(%q) error = %v, want %q", tt.text, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Main runs iptp: the daemon when installed as iptpd, one command when given
+// arguments, and the interactive shell otherwise. Builds that add commands
+// (dnsrouting) register them in init functions and then call Main.
+func Main() {
+	// Initialize state
+	stateFile := getStateFilePath()
+
+	// Installed as iptpd (a link to iptp), run the daemon
+	if strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe") == "iptpd" {
+		os.Exit(cmdDaemonNonInteractive(NewState(stateFile), "", []string{"run"}))
+	}
+
+	// Talk to iptpd if it is running, otherwise use the state file
+	state, err := OpenState(stateFile)
+	if err != nil {
+		// Create new state if doesn't exist
+		state = NewState(stateFile)
+	}
+
+	// Check if running as a command (e.g., iptp goto /path)
+	if len(os.Args) > 1 {
+		// Command mode
+		exitCode := ExecuteCommand(state, os.Args[1:])
+		os.Exit(exitCode)
+	}
+
+	// Interactive REPL mode
+	// Commands run inside the shell must not act on the shell that started it
+	os.Unsetenv(actionsFileEnv)
+
+	fmt.Println("🚀 iptp- IPTP Shell Process Manager")
+	fmt.Println("   Type 'help' for commands, 'exit' to quit")
+	fmt.Println()
+
+	// Load rc file settings (prompt template etc.)
+	config, err := LoadConfig(getConfigFilePath())
+	if err != nil {
+		config = NewConfig()
+	}
+
+	shell := NewShell(state, config)
+	shell.Run()
+}
+
+// getStateFilePath returns the path to the state file
+// Works on Windows, macOS, and Linux
+func getStateFilePath() string {
+	if os.Getenv("GOOS") == "windows" {
+		return os.Getenv("TEMP") + "\\iptp_state.json"
+	}
+	return "/tmp/iptp_state.json"
+}
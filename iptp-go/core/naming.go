@@ -0,0 +1,317 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Process names: collisions, rename, rm and archive
+//
+// Naming a shell after an intention can land on a name another process
+// already has. Rather than overwrite it, 'name' offers to attach to that
+// process, take a suffixed name (auth-2) or take it over.
+// name_collision = ask (default), attach, suffix or takeover in ~/.iptprc;
+// without a terminal to ask on, ask means suffix.
+//
+// A process open in another iptp shell, or running a 'spawn' session, can't
+// be renamed, removed or archived from elsewhere. Archived processes keep
+// their state but are left out of list, getmethere and intention lookups.
+
+// nameCollision is what to do when a name is taken
+type nameCollision int
+
+const (
+	collisionNone nameCollision = iota // the name is free, or already ours
+	collisionAttach
+	collisionSuffix
+	collisionTakeOver
+	collisionCancel
+)
+
+// otherShells returns the open iptp shells using a process, except this one
+func otherShells(name string) []LiveShell {
+	var shells []LiveShell
+	for _, shell := range ListLiveShells() {
+		if shell.Process == name && shell.PID != os.Getpid() {
+			shells = append(shells, shell)
+		}
+	}
+	return shells
+}
+
+// suffixedName returns name-2, name-3, ... whichever is free first
+func suffixedName(state *State, name string) string {
+	for n := 2; ; n++ {
+		candidate := name + "-" + strconv.Itoa(n)
+		if _, taken := state.GetProcess(candidate); !taken {
+			return candidate
+		}
+	}
+}
+
+// checkNameCollision decides what to do when naming the shell's process
+// (self) name with intention. A process with the same intention and no other
+// shell on it is simply resumed.
+func checkNameCollision(reader *bufio.Reader, config *Config, state *State, self, name, intention string, interactive bool) nameCollision {
+	existing, taken := state.GetProcess(name)
+	if !taken || name == self {
+		return collisionNone
+	}
+	shells := otherShells(name)
+	if len(shells) == 0 && existing.Intention == intention && existing.Archived == "" {
+		return collisionNone
+	}
+
+	mode := "ask"
+	if config != nil {
+		if value, ok := config.Get("name_collision"); ok {
+			mode = value
+		}
+	}
+	switch mode {
+	case "attach":
+		return collisionAttach
+	case "takeover":
+		return collisionTakeOver
+	case "suffix":
+		return collisionSuffix
+	}
+	if !interactive {
+		return collisionSuffix
+	}
+
+	where := displayDir(existing.CurrentDir)
+	if len(shells) > 0 {
+		where += fmt.Sprintf(", open in shell %d", shells[0].PID)
+	}
+	if existing.Archived != "" {
+		where += ", archived"
+	}
+	fmt.Printf("'%s' is taken: %s (%s)\n", name, existing.Intention, where)
+	fmt.Printf("[a]ttach to it, [s]uffix as %s, [t]ake it over, or [c]ancel? [s] ", suffixedName(state, name))
+
+	answer, _ := reader.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "a", "attach":
+		return collisionAttach
+	case "", "s", "suffix":
+		return collisionSuffix
+	case "t", "take", "takeover":
+		return collisionTakeOver
+	default:
+		return collisionCancel
+	}
+}
+
+// processBusy reports why a process can't be changed from this shell
+func processBusy(state *State, name string) error {
+	proc, ok := state.GetProcess(name)
+	if !ok {
+		return fmt.Errorf("Process '%s' not found", name)
+	}
+	if shells := otherShells(name); len(shells) > 0 {
+		return fmt.Errorf("'%s' is open in shell %d (switch that shell to another process first)", name, shells[0].PID)
+	}
+	if sessionAlive(proc) {
+		return fmt.Errorf("'%s' has a running session (end it with 'sessions kill %s' first)", name, name)
+	}
+	return nil
+}
+
+// RenameProcess moves a process to a new name
+func (s *State) RenameProcess(oldName, newName string) error {
+	proc, ok := s.Processes[oldName]
+	if !ok {
+		return fmt.Errorf("Process '%s' not found", oldName)
+	}
+	if _, taken := s.Processes[newName]; taken {
+		return fmt.Errorf("Process '%s' already exists", newName)
+	}
+
+	proc.Pulses = mergePulse(proc.Pulses, Pulse{Name: "process named", TV: "Y", Response: newName})
+	proc.Timestamp = time.Now().Format(time.RFC3339)
+	delete(s.Processes, oldName)
+	s.Processes[newName] = proc
+	return nil
+}
+
+// RemoveProcess forgets a process
+func (s *State) RemoveProcess(name string) bool {
+	if _, ok := s.Processes[name]; !ok {
+		return false
+	}
+	delete(s.Processes, name)
+	return true
+}
+
+// SetArchived archives or unarchives a process
+func (s *State) SetArchived(name string, archived bool) bool {
+	proc, ok := s.Processes[name]
+	if !ok {
+		return false
+	}
+	proc.Archived = ""
+	if archived {
+		proc.Archived = time.Now().Format(time.RFC3339)
+	}
+	s.Processes[name] = proc
+	return true
+}
+
+// cmdRename handles 'rename OLD NEW' (or 'rename NEW' for this process)
+// Returns the new name, "" on failure
+func cmdRename(state *State, process string, args []string) string {
+	if len(args) == 1 {
+		args = []string{process, args[0]}
+	}
+	if len(args) != 2 {
+		fmt.Println("Usage: rename [OLD] NEW")
+		return ""
+	}
+
+	oldName, newName := args[0], sanitizeProcessName(args[1])
+	if oldName != process {
+		if err := processBusy(state, oldName); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return ""
+		}
+	} else if proc, ok := state.GetProcess(oldName); ok && sessionAlive(proc) {
+		fmt.Printf("✗ '%s' has a running session (end it with 'sessions kill %s' first)\n", oldName, oldName)
+		return ""
+	}
+	if err := state.RenameProcess(oldName, newName); err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return ""
+	}
+	state.Save()
+
+	fmt.Printf("✓ Renamed %s → %s\n", oldName, newName)
+	return newName
+}
+
+// cmdRmNonInteractive handles 'iptp rm NAME...'
+func cmdRmNonInteractive(state *State, process string, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: iptp rm NAME...")
+		return 1
+	}
+
+	status := 0
+	for _, name := range args {
+		if name == process {
+			fmt.Printf("✗ '%s' is this shell's process (name the shell something else first)\n", name)
+			status = 1
+			continue
+		}
+		if err := processBusy(state, name); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			status = 1
+			continue
+		}
+		state.RemoveProcess(name)
+		fmt.Printf("✓ Removed %s\n", name)
+	}
+	state.Save()
+	return status
+}
+
+// cmdArchive handles 'archive NAME...' and 'unarchive NAME...'
+func cmdArchive(state *State, process string, args []string, archive bool) int {
+	if len(args) == 0 {
+		if archive {
+			fmt.Println("Usage: archive NAME...")
+		} else {
+			fmt.Println("Usage: unarchive NAME...")
+		}
+		return 1
+	}
+
+	status := 0
+	for _, name := range args {
+		if archive && name == process {
+			fmt.Printf("✗ '%s' is this shell's process (name the shell something else first)\n", name)
+			status = 1
+			continue
+		}
+		if err := processBusy(state, name); archive && err != nil {
+			fmt.Printf("✗ %v\n", err)
+			status = 1
+			continue
+		}
+		if !state.SetArchived(name, archive) {
+			fmt.Printf("✗ Process '%s' not found\n", name)
+			status = 1
+			continue
+		}
+		if archive {
+			fmt.Printf("✓ Archived %s\n", name)
+		} else {
+			fmt.Printf("✓ Unarchived %s\n", name)
+		}
+	}
+	state.Save()
+	return status
+}
+
+// archivedError is shown when jumping to an archived process
+func archivedError(name string) string {
+	return fmt.Sprintf("✗ '%s' is archived (unarchive %s to use it again)", name, name)
+}
+
+// parseArchivedFlags removes --archived and --all from the arguments of
+// 'list': archived processes are hidden, shown alone or shown with the rest
+func parseArchivedFlags(args []string) (archived, all bool, rest []string) {
+	for _, arg := range args {
+		switch arg {
+		case "--archived":
+			archived = true
+		case "--all":
+			all = true
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return archived, all, rest
+}
+
+// listArchived reports whether 'list' shows a process
+func listArchived(proc Process, archived, all bool) bool {
+	return all || archived == (proc.Archived != "")
+}
+
+// archivedMarker annotates archived processes in 'list'
+func archivedMarker(proc Process) string {
+	if proc.Archived == "" {
+		return ""
+	}
+	return " ▪ archived " + formatTimestamp(proc.Archived)
+}
+
+// printArchivedHint says how many processes 'list' left out
+func printArchivedHint(state *State) {
+	hidden := 0
+	for _, name := range state.ListProcesses() {
+		if proc, _ := state.GetProcess(name); proc.Archived != "" {
+			hidden++
+		}
+	}
+	if hidden > 0 {
+		fmt.Printf("  (%d archived: list --archived)\n", hidden)
+	}
+}
+
+// attachNonInteractive makes the calling shell a process and takes it there
+func attachNonInteractive(state *State, name string) int {
+	state.SetArchived(name, false)
+	proc, _ := state.GetProcess(name)
+	parentAction("process", name)
+
+	fmt.Printf("✓ Attached to %s @ %s\n", name, proc.CurrentDir)
+	oldDir, _ := os.Getwd()
+	moveParent(state, name, proc.CurrentDir, oldDir)
+	return 0
+}
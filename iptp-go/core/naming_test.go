@@ -0,0 +1,82 @@
+package core
+
+import (
+	"testing"
+)
+
+func TestSuffixedName(t *testing.T) {
+	tests := []struct {
+		taken []string
+		name  string
+		want  string
+	}{
+		{[]string{"api"}, "api", "api-2"},
+		{[]string{"api", "api-2"}, "api", "api-3"},
+		{[]string{"api", "api-3"}, "api", "api-2"}, // the first free one
+		{[]string{"api", "api-2", "api-2-2"}, "api-2", "api-2-3"},
+		{[]string{}, "web", "web-2"},
+	}
+	for _, tt := range tests {
+		state := &State{Processes: make(map[string]Process)}
+		for _, name := range tt.taken {
+			state.Processes[name] = Process{}
+		}
+		if got := suffixedName(state, tt.name); got != tt.want {
+			t.Errorf("suffixedName(%q) with %q taken = %q, want %q", tt.name, tt.taken, got, tt.want)
+		}
+	}
+}
+
+func TestRenameProcess(t *testing.T) {
+	tests := []struct {
+		from, to string
+		err      string
+	}{
+		{"api", "backend", ""},
+		{"api", "web", "Process 'web' already exists"},
+		{"missing", "backend", "Process 'missing' not found"},
+		{"api", "api", "Process 'api' already exists"},
+	}
+	for _, tt := range tests {
+		state := &State{Processes: map[string]Process{
+			"api": {
+				Intention:  "working on the api",
+				CurrentDir: "/src/api",
+				Pulses: []Pulse{
+					{Name: "process named", TV: "Y", Response: "api"},
+					{Name: "git dirty", TV: "N"},
+				},
+			},
+			"web": {Intention: "web"},
+		}}
+
+		err := state.RenameProcess(tt.from, tt.to)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("RenameProcess(%q, %q) error = %v, want %q", tt.from, tt.to, err, tt.err)
+			}
+			if len(state.Processes) != 2 || state.Processes["api"].Intention != "working on the api" {
+				t.Errorf("RenameProcess(%q, %q) changed the state on error", tt.from, tt.to)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RenameProcess(%q, %q): %v", tt.from, tt.to, err)
+			continue
+		}
+
+		if _, ok := state.Processes[tt.from]; ok {
+			t.Errorf("RenameProcess(%q, %q) kept the old name", tt.from, tt.to)
+		}
+		proc, ok := state.Processes[tt.to]
+		if !ok || proc.Intention != "working on the api" || proc.CurrentDir != "/src/api" {
+			t.Fatalf("RenameProcess(%q, %q) moved %+v", tt.from, tt.to, proc)
+		}
+		if len(proc.Pulses) != 2 || proc.Pulses[0].Response != tt.to || proc.Pulses[1].Name != "git dirty" {
+			t.Errorf("RenameProcess(%q, %q) left pulses %+v", tt.from, tt.to, proc.Pulses)
+		}
+		if proc.Timestamp == "" {
+			t.Errorf("RenameProcess(%q, %q) did not touch the timestamp", tt.from, tt.to)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Navigation history and the directory stack
+//
+// Each process keeps a browser-style history: History holds the directories
+// behind the cursor (nearest last) and Forward those ahead of it after a
+// 'back' (nearest last). Moving anywhere new records the directory left in
+// History and drops Forward. 'cd -' returns to the previous directory as a
+// new move, so repeating it toggles like in bash.
+//
+// pushd/popd keep a separate per-process stack. Both live in the state, so
+// they survive restarts and follow the process through 'jump'.
+
+// dirMover changes directory for a command: the iptp shell itself, or the
+// calling shell through the init wrapper. With record, the directory left is
+// added to the history (and the forward list is dropped).
+type dirMover func(dir string, record bool) bool
+
+// Navigate moves a process's cursor steps back (negative) or forward and
+// returns the directory there; cwd is where the shell is now
+func (s *State) Navigate(processName, cwd string, steps int) (string, error) {
+	process := s.Processes[processName]
+	from, to := &process.History, &process.Forward
+	direction := "back"
+	if steps > 0 {
+		from, to = to, from
+		direction = "forward"
+	} else {
+		steps = -steps
+	}
+
+	if len(*from) == 0 {
+		if direction == "back" {
+			return "", fmt.Errorf("No history available")
+		}
+		return "", fmt.Errorf("Nothing to go forward to")
+	}
+	if steps > len(*from) {
+		return "", fmt.Errorf("Only %d step(s) %s available", len(*from), direction)
+	}
+	target := (*from)[len(*from)-steps]
+	if !isDirectory(target) {
+		return "", fmt.Errorf("Cannot change directory: %s no longer exists", target)
+	}
+
+	// Everything passed over goes to the other side, nearest last
+	*to = append(*to, cwd)
+	for i := len(*from) - 1; i > len(*from)-steps; i-- {
+		*to = append(*to, (*from)[i])
+	}
+	*from = (*from)[:len(*from)-steps]
+
+	s.Processes[processName] = process
+	return target, nil
+}
+
+// parseSteps reads the optional N of 'back N' and 'forward N'
+func parseSteps(args []string) (int, bool) {
+	if len(args) == 0 {
+		return 1, true
+	}
+	n, err := strconv.Atoi(args[0])
+	return n, err == nil && n >= 1 && len(args) == 1
+}
+
+// cmdNavigate handles 'back [N]' (direction -1) and 'forward [N]' (1)
+func cmdNavigate(state *State, process string, move dirMover, args []string, direction int) int {
+	steps, ok := parseSteps(args)
+	if !ok {
+		if direction < 0 {
+			fmt.Println("Usage: back [N]")
+		} else {
+			fmt.Println("Usage: forward [N]")
+		}
+		return 1
+	}
+
+	cwd, _ := os.Getwd()
+	target, err := state.Navigate(process, cwd, direction*steps)
+	if err != nil {
+		fmt.Println(err)
+		state.Save()
+		return 1
+	}
+	if !move(target, false) {
+		return 1
+	}
+
+	if direction < 0 {
+		fmt.Printf("✓ Back to: %s\n", target)
+	} else {
+		fmt.Printf("✓ Forward to: %s\n", target)
+	}
+	return 0
+}
+
+// cmdPreviousDir handles 'cd -': back to the directory before this one
+func cmdPreviousDir(state *State, process string, move dirMover) int {
+	proc, _ := state.GetProcess(process)
+	if len(proc.History) == 0 {
+		fmt.Println("✗ No previous directory")
+		return 1
+	}
+
+	prevDir := proc.History[len(proc.History)-1]
+	if !move(prevDir, true) {
+		return 1
+	}
+	fmt.Println(prevDir)
+	return 0
+}
+
+// cmdPushd handles 'pushd DIR' (push the current directory, go to DIR) and
+// 'pushd' (swap the current directory with the top of the stack)
+func cmdPushd(state *State, process string, move dirMover, args []string) int {
+	cwd, _ := os.Getwd()
+	proc, _ := state.GetProcess(process)
+
+	var target string
+	if len(args) == 0 {
+		if len(proc.Stack) == 0 {
+			fmt.Println("✗ pushd: no other directory")
+			return 1
+		}
+		target = proc.Stack[len(proc.Stack)-1]
+	} else {
+		path, err := resolveGotoPath(state, args[0])
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		if target, err = filepath.Abs(path); err != nil || !isDirectory(target) {
+			fmt.Printf("✗ pushd: %s: No such directory\n", args[0])
+			return 1
+		}
+	}
+
+	if !move(target, true) {
+		return 1
+	}
+
+	proc, _ = state.GetProcess(process)
+	if len(args) == 0 {
+		proc.Stack = append(append([]string{}, proc.Stack[:len(proc.Stack)-1]...), cwd)
+	} else {
+		proc.Stack = append(append([]string{}, proc.Stack...), cwd)
+	}
+	state.Processes[process] = proc
+	state.Save()
+
+	printDirStack(state, process, target)
+	return 0
+}
+
+// cmdPopd handles 'popd': go to the top of the stack and remove it
+func cmdPopd(state *State, process string, move dirMover) int {
+	proc, _ := state.GetProcess(process)
+	if len(proc.Stack) == 0 {
+		fmt.Println("✗ popd: directory stack empty")
+		return 1
+	}
+
+	top := proc.Stack[len(proc.Stack)-1]
+	if !move(top, true) {
+		return 1
+	}
+
+	proc, _ = state.GetProcess(process)
+	proc.Stack = proc.Stack[:len(proc.Stack)-1]
+	state.Processes[process] = proc
+	state.Save()
+
+	printDirStack(state, process, top)
+	return 0
+}
+
+// printDirStack prints the stack on one line, the current directory first
+// (after pushd/popd the calling shell only moves once iptp exits)
+func printDirStack(state *State, process, cwd string) {
+	proc, _ := state.GetProcess(process)
+
+	dirs := []string{displayDir(cwd)}
+	for i := len(proc.Stack) - 1; i >= 0; i-- {
+		dirs = append(dirs, displayDir(proc.Stack[i]))
+	}
+	fmt.Println(strings.Join(dirs, " "))
+}
+
+// cmdDirs handles 'dirs' (the stack), 'dirs -v' (numbered, with the
+// back/forward history around the cursor) and 'dirs -c' (clear the stack)
+func cmdDirs(state *State, process string, args []string) int {
+	cwd, _ := os.Getwd()
+	if len(args) == 0 {
+		printDirStack(state, process, cwd)
+		return 0
+	}
+
+	proc, _ := state.GetProcess(process)
+	switch args[0] {
+	case "-c":
+		if _, ok := state.GetProcess(process); ok {
+			proc.Stack = nil
+			state.Processes[process] = proc
+			state.Save()
+		}
+		return 0
+
+	case "-v":
+		fmt.Println("=== Directory Stack ===")
+		fmt.Printf("  %2d  %s\n", 0, displayDir(cwd))
+		for i := len(proc.Stack) - 1; i >= 0; i-- {
+			fmt.Printf("  %2d  %s\n", len(proc.Stack)-i, displayDir(proc.Stack[i]))
+		}
+
+		fmt.Println("\n=== History ===")
+		for i, dir := range proc.History {
+			fmt.Printf("  back %-3d %s\n", len(proc.History)-i, displayDir(dir))
+		}
+		fmt.Printf("  →        %s\n", displayDir(cwd))
+		for i := len(proc.Forward) - 1; i >= 0; i-- {
+			fmt.Printf("  fwd  %-3d %s\n", len(proc.Forward)-i, displayDir(proc.Forward[i]))
+		}
+		return 0
+
+	default:
+		fmt.Println("Usage: dirs [-v|-c]")
+		return 1
+	}
+}
+
+// parentMover moves the calling shell through the init wrapper
+func parentMover(state *State, process string) dirMover {
+	return func(dir string, record bool) bool {
+		if !isDirectory(dir) {
+			fmt.Printf("✗ Cannot change directory: %s no longer exists\n", dir)
+			return false
+		}
+		oldDir := ""
+		if record {
+			oldDir, _ = os.Getwd()
+		}
+		moveParent(state, process, dir, oldDir)
+		return true
+	}
+}
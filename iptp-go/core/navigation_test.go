@@ -0,0 +1,139 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNavigate(t *testing.T) {
+	root := t.TempDir()
+	dirs := make(map[string]string)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		dirs[name] = filepath.Join(root, name)
+		if err := os.Mkdir(dirs[name], 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := func(names ...string) []string {
+		list := []string{}
+		for _, name := range names {
+			list = append(list, dirs[name])
+		}
+		return list
+	}
+
+	// The shell visited a, b, c and is now in d
+	tests := []struct {
+		name             string
+		steps            int
+		history, forward []string
+		cwd              string
+		target           string
+		wantHistory      []string
+		wantForward      []string
+		err              string
+	}{
+		{
+			name: "back one", steps: -1,
+			history: paths("a", "b", "c"), cwd: dirs["d"],
+			target: dirs["c"], wantHistory: paths("a", "b"), wantForward: paths("d"),
+		},
+		{
+			name: "back two", steps: -2,
+			history: paths("a", "b", "c"), cwd: dirs["d"],
+			target: dirs["b"], wantHistory: paths("a"), wantForward: paths("d", "c"),
+		},
+		{
+			name: "forward one", steps: 1,
+			history: paths("a"), forward: paths("d", "c"), cwd: dirs["b"],
+			target: dirs["c"], wantHistory: paths("a", "b"), wantForward: paths("d"),
+		},
+		{
+			name: "forward to the end", steps: 2,
+			history: paths("a"), forward: paths("d", "c"), cwd: dirs["b"],
+			target: dirs["d"], wantHistory: paths("a", "b", "c"), wantForward: paths(),
+		},
+		{
+			name: "no history", steps: -1,
+			history: paths(), cwd: dirs["d"],
+			err: "No history available",
+		},
+		{
+			name: "nothing ahead", steps: 1,
+			history: paths("a"), forward: paths(), cwd: dirs["d"],
+			err: "Nothing to go forward to",
+		},
+		{
+			name: "too far back", steps: -3,
+			history: paths("a", "b"), cwd: dirs["d"],
+			err: "Only 2 step(s) back available",
+		},
+		{
+			name: "gone", steps: -1,
+			history: []string{filepath.Join(root, "removed")}, cwd: dirs["d"],
+			err: "Cannot change directory: " + filepath.Join(root, "removed") + " no longer exists",
+		},
+	}
+	for _, tt := range tests {
+		state := &State{Processes: map[string]Process{
+			"api": {History: tt.history, Forward: tt.forward, CurrentDir: tt.cwd},
+		}}
+		target, err := state.Navigate("api", tt.cwd, tt.steps)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: error = %v, want %q", tt.name, err, tt.err)
+			}
+			proc := state.Processes["api"]
+			if !reflect.DeepEqual(proc.History, tt.history) || !reflect.DeepEqual(proc.Forward, tt.forward) {
+				t.Errorf("%s: failed move changed the history", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		proc := state.Processes["api"]
+		forward := proc.Forward
+		if forward == nil {
+			forward = []string{}
+		}
+		if target != tt.target || !reflect.DeepEqual(proc.History, tt.wantHistory) || !reflect.DeepEqual(forward, tt.wantForward) {
+			t.Errorf("%s: went to %s with history %q and forward %q, want %s, %q, %q",
+				tt.name, target, proc.History, forward, tt.target, tt.wantHistory, tt.wantForward)
+		}
+	}
+}
+
+func TestNavigateRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	var visited []string
+	for _, name := range []string{"a", "b", "c"} {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		visited = append(visited, dir)
+	}
+	cwd := filepath.Join(root, "d")
+	if err := os.Mkdir(cwd, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	state := &State{Processes: map[string]Process{"api": {History: append([]string(nil), visited...)}}}
+	back, err := state.Navigate("api", cwd, -3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ahead, err := state.Navigate("api", back, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proc := state.Processes["api"]
+	if ahead != cwd || !reflect.DeepEqual(proc.History, visited) || len(proc.Forward) != 0 {
+		t.Errorf("back 3 then forward 3 ended in %s with history %q and forward %q", ahead, proc.History, proc.Forward)
+	}
+}
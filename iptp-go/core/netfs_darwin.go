@@ -0,0 +1,36 @@
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+// networkFSTypes are macOS names of network and FUSE filesystems
+var networkFSTypes = map[string]bool{
+	"nfs": true, "smbfs": true, "afpfs": true, "webdav": true,
+	"ftp": true, "macfuse": true, "osxfuse": true, "fusefs": true,
+}
+
+// deviceOf returns the device a file lives on
+func deviceOf(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)
+	}
+	return 0
+}
+
+// isNetworkFS reports whether dir is on a network filesystem
+func isNetworkFS(dir string) bool {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dir, &fs); err != nil {
+		return false
+	}
+	name := make([]byte, 0, len(fs.Fstypename))
+	for _, c := range fs.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	return networkFSTypes[string(name)]
+}
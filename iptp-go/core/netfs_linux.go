@@ -0,0 +1,35 @@
+package core
+
+import (
+	"os"
+	"syscall"
+)
+
+// networkFSMagic are statfs f_type values of network and FUSE filesystems
+var networkFSMagic = map[int64]bool{
+	0x6969:     true, // NFS
+	0x517B:     true, // SMB
+	0xFF534D42: true, // CIFS
+	0xFE534D42: true, // SMB2
+	0x65735546: true, // FUSE (sshfs, rclone, ...)
+	0x5346414F: true, // AFS
+	0x01021997: true, // 9P
+	0x00C36400: true, // Ceph
+}
+
+// deviceOf returns the device a file lives on
+func deviceOf(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(st.Dev)
+	}
+	return 0
+}
+
+// isNetworkFS reports whether dir is on a network filesystem
+func isNetworkFS(dir string) bool {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(dir, &fs); err != nil {
+		return false
+	}
+	return networkFSMagic[int64(fs.Type)]
+}
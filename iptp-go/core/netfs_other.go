@@ -0,0 +1,15 @@
+//go:build !linux && !darwin
+
+package core
+
+import "os"
+
+// deviceOf is not tracked here, so no directory looks like a mount point
+func deviceOf(info os.FileInfo) uint64 {
+	return 0
+}
+
+// isNetworkFS cannot tell on this platform
+func isNetworkFS(dir string) bool {
+	return false
+}
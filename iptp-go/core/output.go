@@ -0,0 +1,221 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// OutputFormat selects how a command prints its result
+// The zero value is the usual human-readable text
+type OutputFormat struct {
+	JSON     bool
+	Template *template.Template
+}
+
+// Text reports whether the command should print its normal output
+func (f OutputFormat) Text() bool {
+	return !f.JSON && f.Template == nil
+}
+
+// ProcessInfo is the JSON shape of a process in list, jump and state
+// Fields are always present; pulses and history are [] when empty
+type ProcessInfo struct {
+	Name       string   `json:"name"`
+	Intention  string   `json:"intention"`
+	Intent     Intent   `json:"intent"`
+	CurrentDir string   `json:"current_dir"`
+	PID        int      `json:"pid"`
+	Timestamp  string   `json:"timestamp"`
+	Live       bool     `json:"live"`     // an iptp shell is open on it now
+	Session    bool     `json:"session"`  // its 'spawn' session is running
+	Archived   string   `json:"archived"` // when it was archived, "" if in use
+	Pulses     []Pulse  `json:"pulses"`
+	History    []string `json:"history"`
+
+	// Repository status of CurrentDir, in list only
+	Git *GitStatus `json:"git,omitempty"`
+}
+
+// StateInfo is the JSON shape of 'state': the process plus the calling shell
+type StateInfo struct {
+	ProcessInfo
+	ShellDir   string      `json:"shell_dir"`
+	ShellPID   int         `json:"shell_pid"`
+	Recordings []Recording `json:"recordings"`
+}
+
+// newProcessInfo converts a stored process to its JSON shape
+func newProcessInfo(name string, proc Process, live map[string]bool) ProcessInfo {
+	info := ProcessInfo{
+		Name:       name,
+		Intention:  proc.Intention,
+		Intent:     proc.Intent,
+		CurrentDir: proc.CurrentDir,
+		PID:        proc.PID,
+		Timestamp:  proc.Timestamp,
+		Live:       live[name],
+		Session:    sessionAlive(proc),
+		Archived:   proc.Archived,
+		Pulses:     proc.Pulses,
+		History:    proc.History,
+	}
+	if info.Pulses == nil {
+		info.Pulses = []Pulse{}
+	}
+	if info.History == nil {
+		info.History = []string{}
+	}
+	if info.Intent.Tags == nil {
+		info.Intent.Tags = []string{}
+	}
+	return info
+}
+
+// filterProcessInfos keeps the processes whose intention passes the filter
+func filterProcessInfos(infos []ProcessInfo, filter IntentFilter) []ProcessInfo {
+	if filter.Empty() {
+		return infos
+	}
+	now := time.Now()
+	kept := make([]ProcessInfo, 0, len(infos))
+	for _, info := range infos {
+		if filter.Match(info.Intent, now) {
+			kept = append(kept, info)
+		}
+	}
+	return kept
+}
+
+// sortedProcessInfos returns every process, sorted by name
+func sortedProcessInfos(state *State) []ProcessInfo {
+	names := state.ListProcesses()
+	sort.Strings(names)
+
+	live := liveProcesses()
+	infos := make([]ProcessInfo, 0, len(names))
+	for _, name := range names {
+		proc, _ := state.GetProcess(name)
+		infos = append(infos, newProcessInfo(name, proc, live))
+	}
+	return infos
+}
+
+// ParseOutputFlags removes --json and --format from args
+// --format takes json or a Go template (--format='{{.Name}}')
+func ParseOutputFlags(args []string) (OutputFormat, []string, error) {
+	var format OutputFormat
+	var rest []string
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		var spec string
+		switch {
+		case arg == "--json":
+			format.JSON = true
+			continue
+		case strings.HasPrefix(arg, "--format="):
+			spec = strings.TrimPrefix(arg, "--format=")
+		case arg == "--format":
+			if i+1 >= len(args) {
+				return format, nil, fmt.Errorf("--format needs a value")
+			}
+			i++
+			spec = args[i]
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+
+		if spec == "json" {
+			format.JSON = true
+			continue
+		}
+		tmpl, err := template.New("format").Funcs(outputFuncs).Parse(spec)
+		if err != nil {
+			return format, nil, fmt.Errorf("bad --format template: %v", err)
+		}
+		format.Template = tmpl
+	}
+	return format, rest, nil
+}
+
+// hasOutputFlag reports whether args ask for JSON or template output
+func hasOutputFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "--json" || arg == "--format" || strings.HasPrefix(arg, "--format=") {
+			return true
+		}
+	}
+	return false
+}
+
+// outputFuncs are available to --format templates
+var outputFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+// WriteOutput prints v as indented JSON or through the template
+// A template is applied to each element when v is a slice
+func WriteOutput(format OutputFormat, v interface{}) int {
+	if format.Template == nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(v); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	items := []interface{}{v}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+		items = items[:0]
+		for i := 0; i < rv.Len(); i++ {
+			items = append(items, rv.Index(i).Interface())
+		}
+	}
+	for _, item := range items {
+		if err := format.Template.Execute(os.Stdout, item); err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			return 1
+		}
+		fmt.Println()
+	}
+	return 0
+}
+
+// outputError is the JSON shape of a failed command
+type outputError struct {
+	Error string `json:"error"`
+}
+
+// OutputError reports a failure in the format the caller asked for:
+// the usual message for text, {"error": ...} for JSON and stderr for a
+// template, so a script never parses a human message as a result
+func OutputError(format OutputFormat, status int, msg string) int {
+	switch {
+	case format.Text():
+		fmt.Println(msg)
+	case format.JSON:
+		WriteOutput(format, outputError{Error: strings.TrimPrefix(msg, "✗ ")})
+	default:
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	return status
+}
+
+// OutputUsageError reports a bad --json/--format flag
+func OutputUsageError(err error) int {
+	fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+	return 2
+}
@@ -0,0 +1,690 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// getmethere picker
+//
+// On a terminal, getmethere is a full-screen picker: type to filter, arrows
+// to move, Enter to go, Esc to cancel. Candidates are saved processes,
+// bookmarks, visited directories, the directories below the current one and
+// those under the search roots (from the index, or walked in the background).
+// A preview pane shows the highlighted directory's git status, contents and
+// README. Without a terminal, getmethere falls back to a numbered list.
+
+// pickerReadmes are shown in the preview pane, first found wins
+var pickerReadmes = []string{"README.md", "README", "README.txt", "readme.md"}
+
+// pickerItem is one choice in the picker
+type pickerItem struct {
+	Dir   string
+	Name  string // process or bookmark name
+	Kind  string // "process", "bookmark", "recent" or "dir"
+	Mtime int64
+}
+
+// pickerKindBonus ranks saved places above plain directories
+var pickerKindBonus = map[string]int{"process": 30, "bookmark": 25, "recent": 10}
+
+// label is how an item is listed
+func (item pickerItem) label() string {
+	switch item.Kind {
+	case "process":
+		return fmt.Sprintf("⚙ %s  %s", item.Name, displayDir(item.Dir))
+	case "bookmark":
+		return fmt.Sprintf("@%s  %s", item.Name, displayDir(item.Dir))
+	case "recent":
+		return "★ " + displayDir(item.Dir)
+	}
+	return "  " + displayDir(item.Dir)
+}
+
+// displayDir abbreviates the home directory to ~
+func displayDir(dir string) string {
+	if home, err := os.UserHomeDir(); err == nil && isUnder(dir, home) {
+		return "~" + strings.TrimPrefix(dir, home)
+	}
+	return dir
+}
+
+// savedPickerItems returns processes, bookmarks and visited directories
+func savedPickerItems(state *State) []pickerItem {
+	var items []pickerItem
+	for _, name := range state.ListProcesses() {
+		proc, _ := state.GetProcess(name)
+		if proc.CurrentDir != "" && proc.Archived == "" {
+			items = append(items, pickerItem{Dir: proc.CurrentDir, Name: name, Kind: "process"})
+		}
+	}
+	for name, mark := range state.Bookmarks {
+		items = append(items, pickerItem{Dir: mark.Dir, Name: name, Kind: "bookmark"})
+	}
+	for dir := range state.Directories {
+		items = append(items, pickerItem{Dir: dir, Kind: "recent"})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind > items[j].Kind // process, recent, bookmark
+		}
+		return items[i].Name+items[i].Dir < items[j].Name+items[j].Dir
+	})
+	return items
+}
+
+// rootPickerItems returns directories under the search roots
+// The index answers at once; otherwise each root is walked
+func rootPickerItems() []pickerItem {
+	home, _ := os.UserHomeDir()
+	var items []pickerItem
+	for _, root := range searchRoots([]string{home}) {
+		candidates, ok := indexCandidates(root)
+		if !ok {
+			candidates = walkCandidates(root, 4)
+		}
+		for _, c := range candidates {
+			items = append(items, pickerItem{Dir: c.Dir, Kind: "dir", Mtime: c.Mtime})
+		}
+	}
+	return items
+}
+
+// rankPickerItems filters and orders items for a query (all items if "")
+func rankPickerItems(query string, items []pickerItem, visits map[string]DirVisit) []pickerItem {
+	q := newDirQuery(query)
+	now := time.Now()
+
+	type scored struct {
+		item  pickerItem
+		score int
+	}
+	var matches []scored
+	seen := make(map[string]bool)
+	for _, item := range items {
+		key := item.Kind + "\x00" + item.Name + "\x00" + item.Dir
+		if item.Kind == "dir" || item.Kind == "recent" {
+			key = item.Dir // a plain directory is listed once
+		}
+		if seen[key] {
+			continue
+		}
+
+		display := displayDir(item.Dir)
+		score := 0
+		if query != "" {
+			s, ok := q.score(display)
+			if item.Name != "" {
+				if ns, nok := q.score(item.Name); nok && (!ok || ns+16 > s) {
+					s, ok = ns+16, true
+				}
+			}
+			if !ok {
+				continue
+			}
+			score = s
+		}
+		seen[key] = true
+		score -= penaltyDepth * strings.Count(display, "/")
+		score += pickerKindBonus[item.Kind]
+		score += recencyBonus(DirCandidate{Dir: item.Dir, Mtime: item.Mtime}, visits, now)
+		matches = append(matches, scored{item, score})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].item.Dir) < len(matches[j].item.Dir)
+	})
+	ranked := make([]pickerItem, len(matches))
+	for i, m := range matches {
+		ranked[i] = m.item
+	}
+	return ranked
+}
+
+// picker is the state of a running full-screen picker
+type picker struct {
+	state  *State
+	query  []rune
+	shown  []pickerItem
+	cursor int
+	offset int
+	cols   int
+	rows   int
+
+	mu       sync.Mutex
+	items    []pickerItem
+	loading  bool
+	changed  bool // more items arrived or a preview is ready
+	previews map[string][]string
+	pending  map[string]bool
+}
+
+// runPicker shows the picker on the terminal and returns the chosen directory
+// ok is false when the user cancelled
+func runPicker(state *State, query string) (dir string, ok bool, err error) {
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return "", false, err
+	}
+	defer restore()
+
+	p := &picker{
+		state:    state,
+		query:    []rune(query),
+		previews: make(map[string][]string),
+		pending:  make(map[string]bool),
+		loading:  true,
+	}
+	p.cols, p.rows = terminalSize(os.Stdout)
+
+	// Saved places and the current directory first, the roots as they come
+	cwd, _ := os.Getwd()
+	p.items = savedPickerItems(state)
+	for _, c := range walkCandidates(cwd, 3) {
+		p.items = append(p.items, pickerItem{Dir: c.Dir, Kind: "dir", Mtime: c.Mtime})
+	}
+	go func() {
+		more := rootPickerItems()
+		p.mu.Lock()
+		p.items = append(p.items, more...)
+		p.loading = false
+		p.changed = true
+		p.mu.Unlock()
+	}()
+
+	stopResize := watchResize(func() {
+		p.mu.Lock()
+		p.changed = true
+		p.mu.Unlock()
+	})
+	defer stopResize()
+
+	fmt.Print("\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer fmt.Print("\x1b[?25h\x1b[?1049l")
+
+	p.filter()
+	p.draw()
+
+	buf := make([]byte, 256)
+	var pending []byte
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil && n == 0 && err != io.EOF {
+			return "", false, err
+		}
+		if n == 0 {
+			// Read timed out: pick up background results and resizes
+			p.mu.Lock()
+			changed := p.changed
+			p.changed = false
+			p.mu.Unlock()
+			if changed {
+				p.cols, p.rows = terminalSize(os.Stdout)
+				p.refresh()
+				p.draw()
+			}
+			continue
+		}
+
+		pending = append(pending, buf[:n]...)
+		for len(pending) > 0 {
+			action, used := parsePickerKey(pending)
+			if used == 0 {
+				break // incomplete sequence
+			}
+			pending = pending[used:]
+
+			switch action.kind {
+			case "accept":
+				if len(p.shown) == 0 {
+					continue
+				}
+				return p.shown[p.cursor].Dir, true, nil
+			case "cancel":
+				return "", false, nil
+			case "up":
+				p.move(-1)
+			case "down":
+				p.move(1)
+			case "pageup":
+				p.move(-p.listRows())
+			case "pagedown":
+				p.move(p.listRows())
+			case "backspace":
+				if len(p.query) > 0 {
+					p.query = p.query[:len(p.query)-1]
+					p.filter()
+				}
+			case "clear":
+				p.query = nil
+				p.filter()
+			case "word":
+				trimmed := strings.TrimRight(string(p.query), " ")
+				if i := strings.LastIndexAny(trimmed, " /"); i >= 0 {
+					p.query = []rune(trimmed[:i+1])
+				} else {
+					p.query = nil
+				}
+				p.filter()
+			case "char":
+				p.query = append(p.query, action.r)
+				p.filter()
+			}
+		}
+		p.draw()
+	}
+}
+
+// pickerKey is a decoded keypress
+type pickerKey struct {
+	kind string
+	r    rune
+}
+
+// parsePickerKey decodes one key from the start of buf
+// Returns used == 0 when buf holds only part of a sequence
+func parsePickerKey(buf []byte) (pickerKey, int) {
+	switch b := buf[0]; b {
+	case '\r', '\n':
+		return pickerKey{kind: "accept"}, 1
+	case 0x03, 0x07: // Ctrl-C, Ctrl-G
+		return pickerKey{kind: "cancel"}, 1
+	case 0x7f, 0x08:
+		return pickerKey{kind: "backspace"}, 1
+	case 0x15: // Ctrl-U
+		return pickerKey{kind: "clear"}, 1
+	case 0x17: // Ctrl-W
+		return pickerKey{kind: "word"}, 1
+	case 0x10, 0x0b: // Ctrl-P, Ctrl-K
+		return pickerKey{kind: "up"}, 1
+	case 0x0e, 0x09: // Ctrl-N, Tab
+		return pickerKey{kind: "down"}, 1
+	case 0x1b:
+		if len(buf) == 1 {
+			return pickerKey{kind: "cancel"}, 1 // a lone Esc
+		}
+		if buf[1] != '[' && buf[1] != 'O' {
+			return pickerKey{kind: "cancel"}, 1
+		}
+		// CSI/SS3: parameters, then a final byte in @..~
+		for i := 2; i < len(buf); i++ {
+			if buf[i] >= 0x40 && buf[i] <= 0x7e {
+				seq := string(buf[2 : i+1])
+				switch seq {
+				case "A":
+					return pickerKey{kind: "up"}, i + 1
+				case "B":
+					return pickerKey{kind: "down"}, i + 1
+				case "5~":
+					return pickerKey{kind: "pageup"}, i + 1
+				case "6~":
+					return pickerKey{kind: "pagedown"}, i + 1
+				}
+				return pickerKey{kind: "none"}, i + 1
+			}
+		}
+		return pickerKey{}, 0
+	}
+
+	if buf[0] < 0x20 {
+		return pickerKey{kind: "none"}, 1
+	}
+	if !utf8.FullRune(buf) {
+		return pickerKey{}, 0
+	}
+	r, size := utf8.DecodeRune(buf)
+	return pickerKey{kind: "char", r: r}, size
+}
+
+// filter re-ranks the items for the current query
+func (p *picker) filter() {
+	p.mu.Lock()
+	items := p.items
+	p.mu.Unlock()
+
+	p.shown = rankPickerItems(string(p.query), items, p.state.Directories)
+	p.cursor, p.offset = 0, 0
+}
+
+// refresh re-ranks after new items arrived, keeping the highlighted directory
+func (p *picker) refresh() {
+	selected := ""
+	if len(p.shown) > 0 {
+		selected = p.shown[p.cursor].Dir
+	}
+	p.filter()
+	for i, item := range p.shown {
+		if item.Dir == selected {
+			p.move(i)
+			break
+		}
+	}
+}
+
+// move moves the highlight, scrolling the list as needed
+func (p *picker) move(delta int) {
+	p.cursor += delta
+	if p.cursor >= len(p.shown) {
+		p.cursor = len(p.shown) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if rows := p.listRows(); p.cursor >= p.offset+rows {
+		p.offset = p.cursor - rows + 1
+	}
+}
+
+// listRows is how many items fit below the prompt and status lines
+func (p *picker) listRows() int {
+	if p.rows < 4 {
+		return 1
+	}
+	return p.rows - 2
+}
+
+// draw repaints the whole screen
+func (p *picker) draw() {
+	listWidth := p.cols
+	previewWidth := 0
+	if p.cols >= 80 {
+		listWidth = p.cols / 2
+		previewWidth = p.cols - listWidth - 3
+	}
+
+	var preview []string
+	if previewWidth > 0 && len(p.shown) > 0 {
+		preview = p.preview(p.shown[p.cursor].Dir)
+	}
+
+	var b strings.Builder
+	b.WriteString("\x1b[H")
+	fmt.Fprintf(&b, "\x1b[2K\x1b[1mgetmethere>\x1b[0m %s\x1b[7m \x1b[0m\r\n", string(p.query))
+
+	p.mu.Lock()
+	total, loading := len(p.items), p.loading
+	p.mu.Unlock()
+	status := fmt.Sprintf("  %d/%d", len(p.shown), total)
+	if loading {
+		status += "  searching…"
+	}
+	status += "  ↑↓ move  ⏎ go  esc cancel"
+	fmt.Fprintf(&b, "\x1b[2K\x1b[2m%s\x1b[0m\r\n", fitWidth(status, p.cols))
+
+	for row := 0; row < p.listRows(); row++ {
+		b.WriteString("\x1b[2K")
+		i := p.offset + row
+		line := ""
+		if i < len(p.shown) {
+			line = fitWidth(p.shown[i].label(), listWidth-2)
+			if i == p.cursor {
+				line = "\x1b[7m> " + padWidth(line, listWidth-2) + "\x1b[0m"
+			} else {
+				line = "  " + padWidth(line, listWidth-2)
+			}
+		} else {
+			line = strings.Repeat(" ", listWidth)
+		}
+		b.WriteString(line)
+		if previewWidth > 0 {
+			b.WriteString(" \x1b[2m│\x1b[0m ")
+			if row < len(preview) {
+				b.WriteString(fitWidth(preview[row], previewWidth))
+			}
+		}
+		if row < p.listRows()-1 {
+			b.WriteString("\r\n")
+		}
+	}
+	os.Stdout.WriteString(b.String())
+}
+
+// preview returns the preview lines of dir, loading them in the background
+func (p *picker) preview(dir string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if lines, ok := p.previews[dir]; ok {
+		return lines
+	}
+	if !p.pending[dir] {
+		p.pending[dir] = true
+		rows := p.listRows()
+		go func() {
+			lines := previewDirectory(dir, rows)
+			p.mu.Lock()
+			p.previews[dir] = lines
+			delete(p.pending, dir)
+			p.changed = true
+			p.mu.Unlock()
+		}()
+	}
+	return []string{displayDir(dir), "…"}
+}
+
+// previewDirectory describes a directory: git status, contents and README
+func previewDirectory(dir string, maxLines int) []string {
+	lines := []string{"\x1b[1m" + displayDir(dir) + "\x1b[0m"}
+
+	if branch, ok := gitBranch(dir); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		cmd := exec.CommandContext(ctx, "git", "status", "--porcelain")
+		cmd.Dir = dir
+		output, err := cmd.Output()
+		cancel()
+		switch changes := strings.TrimSpace(string(output)); {
+		case err != nil:
+			lines = append(lines, "⎇ "+branch)
+		case changes == "":
+			lines = append(lines, "⎇ "+branch+", clean")
+		default:
+			count := strings.Count(changes, "\n") + 1
+			lines = append(lines, fmt.Sprintf("⎇ %s, %d changed", branch, count))
+		}
+	}
+	lines = append(lines, "")
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return append(lines, "✗ "+err.Error())
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].IsDir() && !entries[j].IsDir()
+	})
+	budget := maxLines / 2
+	for i, entry := range entries {
+		if i == budget {
+			lines = append(lines, fmt.Sprintf("… %d more", len(entries)-budget))
+			break
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			name += "/"
+		}
+		lines = append(lines, name)
+	}
+
+	for _, name := range pickerReadmes {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		lines = append(lines, "", "\x1b[1m"+name+"\x1b[0m")
+		for _, line := range strings.Split(string(data), "\n") {
+			if len(lines) >= maxLines {
+				break
+			}
+			lines = append(lines, strings.Map(func(r rune) rune {
+				if r == 0x1b {
+					return -1
+				}
+				return r
+			}, strings.ReplaceAll(line, "\t", "    ")))
+		}
+		break
+	}
+	return lines
+}
+
+// fitWidth truncates s to n runes, passing escape sequences through
+func fitWidth(s string, n int) string {
+	var b strings.Builder
+	width, escape := 0, false
+	for _, r := range s {
+		switch {
+		case escape:
+			b.WriteRune(r)
+			escape = r < 0x40 || r > 0x7e || r == '['
+		case r == 0x1b:
+			b.WriteRune(r)
+			escape = true
+		case r < 0x20:
+			// control characters from README files
+		case width < n:
+			b.WriteRune(r)
+			width++
+		}
+	}
+	if strings.ContainsRune(s, 0x1b) {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// padWidth pads s with spaces to n runes
+func padWidth(s string, n int) string {
+	if w := utf8.RuneCountInString(s); w < n {
+		return s + strings.Repeat(" ", n-w)
+	}
+	return s
+}
+
+// getMeThereByNumber is the line-based getmethere for scripts and dumb
+// terminals: ask for a name, list numbered matches, read a number
+func getMeThereByNumber(reader *bufio.Reader, state *State, dirName string) (string, bool) {
+	if dirName == "" {
+		username := os.Getenv("USER")
+		if username == "" {
+			username = os.Getenv("USERNAME") // Windows
+		}
+		fmt.Printf("Hello, %s! Where would you like to work today?\n", username)
+		fmt.Print("Enter the directory name: ")
+
+		dirName, _ = reader.ReadString('\n')
+		dirName = strings.TrimSpace(dirName)
+		if dirName == "" {
+			fmt.Println("✗ No input provided")
+			return "", false
+		}
+	}
+
+	fmt.Println("Searching...")
+	dirs := getMeThereMatches(state, dirName)
+	if len(dirs) == 0 {
+		fmt.Printf("✗ No directories found matching '%s'\n", dirName)
+		return "", false
+	}
+
+	currentDir, _ := os.Getwd()
+	fmt.Printf("\nFound %d matching directories:\n", len(dirs))
+	for i, dir := range dirs {
+		// Show relative path if under current dir
+		relPath, err := filepath.Rel(currentDir, dir)
+		displayPath := dir
+		if err == nil && !strings.HasPrefix(relPath, "..") {
+			displayPath = "./" + relPath
+		}
+		fmt.Printf("  %d) %s\n", i+1, displayPath)
+	}
+
+	fmt.Print("\nEnter number (or 'q' to quit): ")
+	choice, _ := reader.ReadString('\n')
+	choice = strings.TrimSpace(choice)
+	if choice == "q" || choice == "Q" {
+		fmt.Println("Cancelled")
+		return "", false
+	}
+
+	var selectedIdx int
+	if _, err := fmt.Sscanf(choice, "%d", &selectedIdx); err != nil || selectedIdx < 1 || selectedIdx > len(dirs) {
+		fmt.Println("✗ Invalid selection")
+		return "", false
+	}
+	return dirs[selectedIdx-1], true
+}
+
+// getMeThereMatches searches below the current directory, then the roots
+func getMeThereMatches(state *State, dirName string) []string {
+	currentDir, _ := os.Getwd()
+	dirs := matchedDirs(RankDirectories(dirName, currentDir, 3, state.Directories), 10)
+	if len(dirs) > 0 {
+		return dirs
+	}
+
+	// If nothing found locally, search from home or search_roots (slower)
+	homeDir, _ := os.UserHomeDir()
+	for _, root := range searchRoots([]string{homeDir}) {
+		found := matchedDirs(RankDirectories(dirName, root, 4, state.Directories), 20-len(dirs))
+		dirs = append(dirs, found...)
+		if len(dirs) >= 20 {
+			break
+		}
+	}
+	return dirs
+}
+
+// getMeThere picks a directory with the picker on a terminal, otherwise
+// with the numbered list
+func getMeThere(reader *bufio.Reader, state *State, query string) (string, bool) {
+	if isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		dir, ok, err := runPicker(state, query)
+		if err == nil {
+			if !ok {
+				fmt.Println("Cancelled")
+			}
+			return dir, ok
+		}
+	}
+	return getMeThereByNumber(reader, state, query)
+}
+
+// cmdGetMeThereNonInteractive runs getmethere from the command line
+// Without a terminal, 'getmethere QUERY' prints the ranked matches for scripts
+func cmdGetMeThereNonInteractive(state *State, process string, args []string) int {
+	query := strings.Join(args, " ")
+	if query != "" && !isTerminal(os.Stdout) {
+		dirs := getMeThereMatches(state, query)
+		for _, dir := range dirs {
+			fmt.Println(dir)
+		}
+		if len(dirs) == 0 {
+			return 1
+		}
+		return 0
+	}
+
+	dir, ok := getMeThere(bufio.NewReader(os.Stdin), state, query)
+	if !ok {
+		return 1
+	}
+	oldDir, _ := os.Getwd()
+	fmt.Printf("✓ Changed to: %s\n", dir)
+	moveParent(state, process, dir, oldDir)
+	offerProjectNameNonInteractive(state, process, dir)
+	return 0
+}
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pluginPrefix is the executable name prefix for external commands
+// iptp-foo on PATH becomes the command "foo"
+const pluginPrefix = "iptp-"
+
+// pluginQueryTimeout bounds --iptp-describe and --iptp-complete calls
+const pluginQueryTimeout = 500 * time.Millisecond
+
+// Plugin is an iptp-<name> executable discovered on PATH
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// PluginContext is written as JSON to a plugin's stdin
+type PluginContext struct {
+	Process    string   `json:"process"`
+	Intention  string   `json:"intention"`
+	CurrentDir string   `json:"current_dir"`
+	Pulses     []Pulse  `json:"pulses"`
+	Args       []string `json:"args"`
+	StateFile  string   `json:"state_file"`
+	PulsesFile string   `json:"pulses_file"`
+}
+
+// FindPlugin looks up iptp-<name> on PATH
+func FindPlugin(name string) (string, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// ListPlugins scans PATH for iptp-<name> executables
+// The first match on PATH wins, like command lookup
+func ListPlugins() []Plugin {
+	seen := make(map[string]bool)
+	var plugins []Plugin
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			fileName := entry.Name()
+			if !strings.HasPrefix(fileName, pluginPrefix) || entry.IsDir() {
+				continue
+			}
+			name := strings.TrimPrefix(fileName, pluginPrefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if name == "" || seen[name] {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil || (runtime.GOOS != "windows" && info.Mode()&0111 == 0) {
+				continue
+			}
+
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, fileName)})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// RunPlugin executes a plugin for a process and applies the pulses it reports
+// The plugin receives the process context in IPTP_* variables and as JSON on
+// stdin. It may report pulses by writing JSON objects, one per line, to the
+// file named by IPTP_PULSES_FILE.
+func RunPlugin(state *State, process, path string, args []string) int {
+	proc, _ := state.GetProcess(process)
+	cwd, _ := os.Getwd()
+
+	reply, err := os.CreateTemp("", "iptp-pulses-*.jsonl")
+	if err != nil {
+		fmt.Printf("✗ Cannot create plugin reply file: %v\n", err)
+		return 1
+	}
+	reply.Close()
+	defer os.Remove(reply.Name())
+
+	ctx := PluginContext{
+		Process:    process,
+		Intention:  proc.Intention,
+		CurrentDir: cwd,
+		Pulses:     proc.Pulses,
+		Args:       args,
+		StateFile:  state.filepath,
+		PulsesFile: reply.Name(),
+	}
+	if ctx.Pulses == nil {
+		ctx.Pulses = []Pulse{}
+	}
+	if ctx.Args == nil {
+		ctx.Args = []string{}
+	}
+	input, _ := json.Marshal(ctx)
+
+	cmd := exec.Command(path, args...)
+	cmd.Env = append(os.Environ(),
+		"IPTP_PLUGIN=1",
+		"IPTP_PROCESS="+process,
+		"IPTP_INTENTION="+proc.Intention,
+		"IPTP_CWD="+cwd,
+		"IPTP_STATE_FILE="+state.filepath,
+		"IPTP_PULSES_FILE="+reply.Name(),
+	)
+	cmd.Stdin = bytes.NewReader(append(input, '\n'))
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	exitCode := 0
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		} else {
+			fmt.Printf("✗ Error executing plugin: %v\n", err)
+			return 127
+		}
+	}
+
+	pulses, err := readPluginPulses(reply.Name())
+	if err != nil {
+		fmt.Printf("✗ Ignoring plugin pulses: %v\n", err)
+		return exitCode
+	}
+	if len(pulses) > 0 {
+		for _, pulse := range pulses {
+			state.SetPulse(process, pulse)
+		}
+		state.Save()
+	}
+
+	return exitCode
+}
+
+// readPluginPulses parses the plugin reply file
+// Each non-empty line is a pulse object: {"name": ..., "TV": ..., "response": ...}
+func readPluginPulses(path string) ([]Pulse, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var pulses []Pulse
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var pulse Pulse
+		if err := json.Unmarshal([]byte(line), &pulse); err != nil {
+			return nil, fmt.Errorf("bad pulse %q: %v", line, err)
+		}
+		if pulse.Name == "" {
+			return nil, fmt.Errorf("pulse without name: %q", line)
+		}
+		switch pulse.TV {
+		case "Y", "N", "U":
+		default:
+			return nil, fmt.Errorf("pulse %q has TV %q (want Y, N or U)", pulse.Name, pulse.TV)
+		}
+		pulses = append(pulses, pulse)
+	}
+	return pulses, scanner.Err()
+}
+
+// DescribePlugin asks a plugin for its one-line help via --iptp-describe
+// Plugins that don't support it are described by their path
+func DescribePlugin(path string) string {
+	output, err := queryPlugin(path, "--iptp-describe")
+	if err != nil {
+		return ""
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(output), "\n")
+	return line
+}
+
+// CompletePlugin asks a plugin for completions via --iptp-complete ARGS...
+func CompletePlugin(path string, args []string) []string {
+	output, err := queryPlugin(path, append([]string{"--iptp-complete"}, args...)...)
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(output)
+}
+
+// queryPlugin runs a plugin metadata query with a short timeout
+func queryPlugin(path string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginQueryTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), "IPTP_PLUGIN=1")
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// cmdPlugins lists discovered plugins
+func cmdPlugins() int {
+	plugins := ListPlugins()
+	if len(plugins) == 0 {
+		fmt.Println("No plugins found (add iptp-<name> executables to PATH)")
+		return 0
+	}
+
+	fmt.Println("=== Plugins ===")
+	for _, plugin := range plugins {
+		if _, builtin := LookupCommand(plugin.Name); builtin {
+			fmt.Printf("  %-19s - shadowed by builtin (%s)\n", plugin.Name, plugin.Path)
+			continue
+		}
+		description := DescribePlugin(plugin.Path)
+		if description == "" {
+			description = plugin.Path
+		}
+		fmt.Printf("  %-19s - %s\n", plugin.Name, description)
+	}
+	return 0
+}
@@ -0,0 +1,370 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LiveShell is the presence record an interactive shell keeps while open
+// Each shell owns one file, so shells never contend for a lock
+type LiveShell struct {
+	PID        int      `json:"pid"`
+	Process    string   `json:"process"`
+	Dir        string   `json:"dir"`
+	Started    string   `json:"started"`
+	LastActive string   `json:"last_active"`
+	Env        []string `json:"env,omitempty"`
+}
+
+// ShellMessage is a note sent with 'send', shown at the target's next prompt
+type ShellMessage struct {
+	From    string `json:"from"`
+	FromPID int    `json:"from_pid"`
+	Text    string `json:"text"`
+	Sent    string `json:"sent"`
+}
+
+// pullSkipEnv are variables 'pull' never copies from another shell: its own
+// place and process, and those describing its terminal, multiplexer pane or
+// SSH connection, which would be wrong for this one
+var pullSkipEnv = map[string]bool{
+	"PWD": true, "OLDPWD": true, "SHLVL": true, "_": true, "iptp_PROCESS": true, actionsFileEnv: true,
+	"TERM": true, "COLORTERM": true, "TERM_PROGRAM": true, "TERM_PROGRAM_VERSION": true,
+	"COLUMNS": true, "LINES": true, "WINDOWID": true, "GPG_TTY": true, "DISPLAY": true,
+	"TMUX": true, "TMUX_PANE": true, "STY": true,
+	"SSH_TTY": true, "SSH_CONNECTION": true, "SSH_CLIENT": true, "SSH_AUTH_SOCK": true,
+}
+
+// getShellsDir returns the directory holding live shell records and inboxes
+// Records include the environment and 'pull' adopts it, so the directory
+// must be private to the user: $XDG_RUNTIME_DIR when set, otherwise one in
+// the temp directory that is checked before every use
+func getShellsDir() (string, error) {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("iptp_shells_%d", os.Getuid()))
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dir = filepath.Join(runtimeDir, "iptp_shells")
+	}
+	return dir, ensurePrivateDir(dir)
+}
+
+// getRuntimeDir returns the private directory holding iptp's sockets:
+// $XDG_RUNTIME_DIR/iptp when set, otherwise a per-user one in the temp
+// directory, checked the same way as the shells directory
+func getRuntimeDir() (string, error) {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("iptp_%d", os.Getuid()))
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dir = filepath.Join(runtimeDir, "iptp")
+	}
+	return dir, ensurePrivateDir(dir)
+}
+
+// ensurePrivateDir creates dir with mode 0700, or checks that an existing
+// one is a real directory owned by this user that nobody else can enter
+func ensurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("refusing to use %s: not a directory", dir)
+	}
+	if err := checkPrivateDir(info); err != nil {
+		return fmt.Errorf("refusing to use %s: %v", dir, err)
+	}
+	return nil
+}
+
+// presencePath returns a shell's presence file
+func presencePath(dir string, pid int) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.json", pid))
+}
+
+// inboxPath returns a shell's message inbox (JSON lines)
+func inboxPath(dir string, pid int) string {
+	return filepath.Join(dir, fmt.Sprintf("%d.inbox", pid))
+}
+
+// updatePresence writes this shell's record; called before every prompt
+func (sh *Shell) updatePresence() {
+	shellsDir, err := getShellsDir()
+	if err != nil {
+		if !sh.presenceWarned {
+			fmt.Printf("✗ Presence disabled: %v\n", err)
+			sh.presenceWarned = true
+		}
+		return
+	}
+	dir, _ := os.Getwd()
+	record := LiveShell{
+		PID:        os.Getpid(),
+		Process:    sh.currentProcess,
+		Dir:        dir,
+		Started:    sh.started.Format(time.RFC3339),
+		LastActive: sh.lastActive.Format(time.RFC3339),
+		Env:        os.Environ(),
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+
+	// Write then rename so readers never see a partial record
+	path := presencePath(shellsDir, record.PID)
+	if err := os.WriteFile(path+".tmp", data, 0600); err == nil {
+		os.Rename(path+".tmp", path)
+	}
+}
+
+// removePresence forgets this shell when it exits
+func (sh *Shell) removePresence() {
+	if dir, err := getShellsDir(); err == nil {
+		os.Remove(presencePath(dir, os.Getpid()))
+		os.Remove(inboxPath(dir, os.Getpid()))
+	}
+}
+
+// ListLiveShells returns the open shells, oldest first
+// Records of shells that died without cleaning up are removed
+func ListLiveShells() []LiveShell {
+	dir, err := getShellsDir()
+	if err != nil {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var shells []LiveShell
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if !processAlive(pid) {
+			os.Remove(presencePath(dir, pid))
+			os.Remove(inboxPath(dir, pid))
+			continue
+		}
+		data, err := os.ReadFile(presencePath(dir, pid))
+		if err != nil {
+			continue
+		}
+		var shell LiveShell
+		if json.Unmarshal(data, &shell) == nil {
+			shells = append(shells, shell)
+		}
+	}
+
+	sort.Slice(shells, func(i, j int) bool {
+		return shells[i].Started < shells[j].Started
+	})
+	return shells
+}
+
+// findLiveShells returns the open shells matching a process name or PID
+func findLiveShells(target string) []LiveShell {
+	var matches []LiveShell
+	for _, shell := range ListLiveShells() {
+		if shell.Process == target || strconv.Itoa(shell.PID) == target {
+			matches = append(matches, shell)
+		}
+	}
+	return matches
+}
+
+// liveProcesses returns the names of processes with an open shell
+func liveProcesses() map[string]bool {
+	live := make(map[string]bool)
+	for _, shell := range ListLiveShells() {
+		live[shell.Process] = true
+	}
+	return live
+}
+
+// liveMarker annotates 'list' entries that have an open shell or session
+func liveMarker(live map[string]bool, name string, proc Process) string {
+	marker := ""
+	if live[name] {
+		marker += " ● live"
+	}
+	if sessionAlive(proc) {
+		marker += " ⧉ session"
+	}
+	return marker
+}
+
+// SendMessage appends a message to a shell's inbox
+func SendMessage(pid int, msg ShellMessage) error {
+	dir, err := getShellsDir()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(inboxPath(dir, pid), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f, "%s\n", data)
+	return err
+}
+
+// deliverMessages prints and clears messages sent to this shell
+func (sh *Shell) deliverMessages() {
+	dir, err := getShellsDir()
+	if err != nil {
+		return
+	}
+	inbox := inboxPath(dir, os.Getpid())
+
+	// Take the inbox away first; messages sent meanwhile start a new one
+	taken := inbox + ".reading"
+	if err := os.Rename(inbox, taken); err != nil {
+		return
+	}
+	defer os.Remove(taken)
+
+	f, err := os.Open(taken)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg ShellMessage
+		if json.Unmarshal(scanner.Bytes(), &msg) != nil {
+			continue
+		}
+		fmt.Printf("📨 %s (%s): %s\n", msg.From, formatTimestamp(msg.Sent), msg.Text)
+	}
+}
+
+// cmdWhoNonInteractive lists open shells with their process, directory and idle time
+func cmdWhoNonInteractive(state *State, process string, args []string) int {
+	shells := ListLiveShells()
+	if len(shells) == 0 {
+		fmt.Println("No live shells")
+		return 0
+	}
+
+	fmt.Println("=== Live Shells ===")
+	for _, shell := range shells {
+		idle := "?"
+		if t, err := time.Parse(time.RFC3339, shell.LastActive); err == nil {
+			idle = formatIdle(time.Since(t))
+		}
+		marker := " "
+		if shell.PID == os.Getpid() {
+			marker = "*"
+		}
+		fmt.Printf(" %s %-20s %7d  idle %-6s %s\n", marker, shell.Process, shell.PID, idle, shell.Dir)
+	}
+	return 0
+}
+
+// cmdSendNonInteractive delivers a message to every open shell of a process
+// iptp send NAME|PID "message"
+func cmdSendNonInteractive(state *State, process string, args []string) int {
+	if len(args) < 2 {
+		fmt.Println("Usage: send NAME|PID \"message\"")
+		return 1
+	}
+
+	targets := findLiveShells(args[0])
+	if len(targets) == 0 {
+		fmt.Printf("✗ No live shell for '%s' (see 'who')\n", args[0])
+		return 1
+	}
+
+	msg := ShellMessage{
+		From:    process,
+		FromPID: os.Getpid(),
+		Text:    joinArgs(args[1:]),
+		Sent:    time.Now().Format(time.RFC3339),
+	}
+	for _, target := range targets {
+		if err := SendMessage(target.PID, msg); err != nil {
+			fmt.Printf("✗ Cannot send to %d: %v\n", target.PID, err)
+			return 1
+		}
+	}
+
+	fmt.Printf("✓ Sent to %s (%d shell(s))\n", args[0], len(targets))
+	return 0
+}
+
+// cmdPull adopts another shell's current directory and environment
+func (sh *Shell) cmdPull(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: pull NAME|PID")
+		return
+	}
+
+	var target *LiveShell
+	for _, shell := range findLiveShells(args[0]) {
+		if shell.PID != os.Getpid() {
+			target = &shell
+			break
+		}
+	}
+	if target == nil {
+		fmt.Printf("✗ No other live shell for '%s' (see 'who')\n", args[0])
+		return
+	}
+
+	// Like cd, so history, frecency and the directory hooks all see the move
+	if !sh.moveDirectory(target.Dir, true) {
+		return
+	}
+
+	fmt.Printf("✓ Pulled %s: %s\n", target.Process, target.Dir)
+	if len(target.Env) == 0 {
+		return
+	}
+	fmt.Printf("  %d environment variable(s) updated\n", pullEnvironment(target.Env))
+}
+
+// pullEnvironment sets the variables of env that differ here, except for
+// pullSkipEnv; variables the other shell lacks are left alone. Returns how
+// many were set
+func pullEnvironment(env []string) (changed int) {
+	for _, kv := range env {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" || pullSkipEnv[key] {
+			continue
+		}
+		if current, set := os.LookupEnv(key); !set || current != value {
+			os.Setenv(key, value)
+			changed++
+		}
+	}
+	return changed
+}
+
+// formatIdle shows an idle duration as 45s, 12m or 3h
+func formatIdle(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	default:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPullEnvironment(t *testing.T) {
+	t.Setenv("IPTP_TEST_SAME", "1")
+	t.Setenv("IPTP_TEST_CHANGED", "old")
+	t.Setenv("IPTP_TEST_ONLY_HERE", "kept")
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("TMUX_PANE", "%1")
+	os.Unsetenv("IPTP_TEST_NEW")
+	t.Cleanup(func() { os.Unsetenv("IPTP_TEST_NEW") })
+
+	changed := pullEnvironment([]string{
+		"IPTP_TEST_SAME=1",
+		"IPTP_TEST_CHANGED=new",
+		"IPTP_TEST_NEW=added",
+		"TERM=dumb",
+		"TMUX_PANE=%7",
+		"PWD=/elsewhere",
+		"=broken",
+		"NOVALUE",
+	})
+	if changed != 2 {
+		t.Errorf("pullEnvironment changed %d variables, want 2", changed)
+	}
+
+	tests := []struct {
+		key, want string
+	}{
+		{"IPTP_TEST_SAME", "1"},
+		{"IPTP_TEST_CHANGED", "new"},
+		{"IPTP_TEST_NEW", "added"},
+		{"IPTP_TEST_ONLY_HERE", "kept"}, // not unset because the other shell lacks it
+		{"TERM", "xterm-256color"},
+		{"TMUX_PANE", "%1"},
+	}
+	for _, tt := range tests {
+		if got := os.Getenv(tt.key); got != tt.want {
+			t.Errorf("after pull %s = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	if wd := os.Getenv("PWD"); wd == "/elsewhere" {
+		t.Errorf("pull copied PWD")
+	}
+}
@@ -0,0 +1,62 @@
+//go:build !windows
+
+package core
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
+
+// detachedProcAttr starts a child in its own session so it outlives the shell
+func detachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// checkPrivateDir reports why a directory is not safe for private files:
+// it must belong to this user and be closed to everyone else
+func checkPrivateDir(info os.FileInfo) error {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("owned by uid %d", st.Uid)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("mode %04o, want 0700", perm)
+	}
+	return nil
+}
+
+// checkSocketOwner reports why a Unix socket can't be trusted: it must be a
+// socket, and this user's, before anything is sent to it
+func checkSocketOwner(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("refusing to use %s: not a socket", path)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("refusing to use %s: owned by uid %d", path, st.Uid)
+	}
+	return nil
+}
+
+// listenUnixPrivate listens on a Unix socket only this user can connect to
+// The umask makes the socket 0600 as it is created, rather than leaving a
+// window before a chmod
+func listenUnixPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
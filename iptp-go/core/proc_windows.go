@@ -0,0 +1,44 @@
+//go:build windows
+
+package core
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// processAlive reports whether a process with the given PID exists
+func processAlive(pid int) bool {
+	const processQueryLimitedInformation = 0x1000
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	syscall.CloseHandle(h)
+	return true
+}
+
+// detachedProcAttr starts a child without a console so it outlives the shell
+func detachedProcAttr() *syscall.SysProcAttr {
+	const detachedProcess = 0x00000008
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// checkPrivateDir accepts any directory; Windows keeps %TEMP% per user and
+// file modes do not describe its ACLs
+func checkPrivateDir(info os.FileInfo) error {
+	return nil
+}
+
+// checkSocketOwner accepts any socket; like the directory holding it, it is
+// covered by the per-user %TEMP%
+func checkSocketOwner(path string) error {
+	return nil
+}
+
+// listenUnixPrivate listens on a Unix socket; sockets in the per-user %TEMP%
+// are not reachable by other users
+func listenUnixPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,399 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Project detection
+//
+// Whenever a process changes directory, the directories above it are
+// searched for project markers. The nearest language manifest (go.mod,
+// Cargo.toml, package.json, pyproject.toml, setup.py) marks the project
+// root; without one, the nearest Makefile or git repository does. The root,
+// language and name are kept as pulses, an unnamed shell_PID process is
+// offered the project's name, and 'project run|test' use the language's
+// usual commands.
+
+// projectMarker is a file or directory that marks a project root
+type projectMarker struct {
+	file     string
+	language string // "" if the marker says nothing about the language
+}
+
+// projectMarkers in order of precedence
+var projectMarkers = []projectMarker{
+	{"go.mod", "Go"},
+	{"Cargo.toml", "Rust"},
+	{"package.json", "JavaScript"},
+	{"pyproject.toml", "Python"},
+	{"setup.py", "Python"},
+	{"Makefile", "Make"},
+	{".git", ""},
+}
+
+// Project pulse names
+const (
+	pulseProjectRoot     = "project root"
+	pulseProjectLanguage = "project language"
+	pulseProjectName     = "project name"
+	pulseProjectAutoname = "project autoname" // N: the offer was declined for this root
+)
+
+// Project is a detected project
+type Project struct {
+	Root     string
+	Name     string
+	Language string
+	Markers  []string
+}
+
+// DetectProject finds the project containing dir
+// The home directory and those above it are never a project
+func DetectProject(dir string) (Project, bool) {
+	home, _ := os.UserHomeDir()
+
+	var fallback Project
+	found := false
+	for d := filepath.Clean(dir); d != home; {
+		if markers := projectMarkersIn(d); len(markers) > 0 {
+			project := Project{Root: d, Markers: markers, Language: "unknown"}
+			for _, marker := range projectMarkers {
+				if marker.language != "" && containsString(markers, marker.file) {
+					project.Language = marker.language
+					break
+				}
+			}
+			if project.Language != "unknown" && project.Language != "Make" {
+				project.Name = projectName(project)
+				return project, true
+			}
+			if !found {
+				fallback, found = project, true
+			}
+			if containsString(markers, ".git") {
+				break // nothing above a repository belongs to it
+			}
+		}
+
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+
+	if !found {
+		return Project{}, false
+	}
+	fallback.Name = projectName(fallback)
+	return fallback, true
+}
+
+// projectMarkersIn lists the markers present in dir
+func projectMarkersIn(dir string) []string {
+	var markers []string
+	for _, marker := range projectMarkers {
+		if _, err := os.Stat(filepath.Join(dir, marker.file)); err == nil {
+			markers = append(markers, marker.file)
+		}
+	}
+	return markers
+}
+
+// projectName reads the name from the manifest, falling back to the
+// root directory's name
+func projectName(project Project) string {
+	var name string
+	switch project.Language {
+	case "Go":
+		name = goModuleName(filepath.Join(project.Root, "go.mod"))
+	case "Rust":
+		name = tomlName(filepath.Join(project.Root, "Cargo.toml"), "package")
+	case "JavaScript":
+		name = packageJSONName(filepath.Join(project.Root, "package.json"))
+	case "Python":
+		name = tomlName(filepath.Join(project.Root, "pyproject.toml"), "project", "tool.poetry")
+	}
+	if name == "" {
+		name = filepath.Base(project.Root)
+	}
+	return name
+}
+
+// goModuleName returns the last element of the module path
+func goModuleName(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "module" {
+			return path.Base(strings.Trim(fields[1], `"`))
+		}
+	}
+	return ""
+}
+
+// packageJSONName returns the package name without its @scope/
+func packageJSONName(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	return path.Base(pkg.Name)
+}
+
+// tomlName returns name = "..." from the first of the given tables
+// Only as much TOML as manifests need: [table] headers and key = "value"
+func tomlName(file string, tables ...string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	table := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			table = strings.Trim(line, "[] ")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) == "name" && containsString(tables, table) {
+			return strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return ""
+}
+
+// projectPulses refreshes the project pulses for a process now in dir
+// Outside a project they turn N, if the process was in one before
+func projectPulses(pulses []Pulse, dir string) []Pulse {
+	project, ok := DetectProject(dir)
+	if !ok {
+		for _, pulse := range pulses {
+			if pulse.Name == pulseProjectRoot && pulse.TV == "Y" {
+				pulses = mergePulse(pulses, Pulse{Name: pulseProjectRoot, TV: "N"})
+				pulses = mergePulse(pulses, Pulse{Name: pulseProjectLanguage, TV: "N"})
+				return mergePulse(pulses, Pulse{Name: pulseProjectName, TV: "N"})
+			}
+		}
+		return pulses
+	}
+
+	pulses = mergePulse(pulses, Pulse{Name: pulseProjectRoot, TV: "Y", Response: project.Root})
+	pulses = mergePulse(pulses, Pulse{Name: pulseProjectLanguage, TV: "Y", Response: project.Language})
+	return mergePulse(pulses, Pulse{Name: pulseProjectName, TV: "Y", Response: project.Name})
+}
+
+// projectNameOffer returns the project an unnamed process in dir could be
+// named after, unless the offer was declined there before
+func projectNameOffer(state *State, process, dir string) (Project, bool) {
+	if !strings.HasPrefix(process, "shell_") {
+		return Project{}, false
+	}
+	project, ok := DetectProject(dir)
+	if !ok {
+		return Project{}, false
+	}
+	if proc, exists := state.GetProcess(process); exists {
+		for _, pulse := range proc.Pulses {
+			if pulse.Name == pulseProjectAutoname && pulse.TV == "N" && pulse.Response == project.Root {
+				return Project{}, false
+			}
+		}
+	}
+	return project, true
+}
+
+// acceptProjectName asks whether to name the process after the project
+// project_autoname = ask (default), always or never in ~/.iptprc
+// A declined offer is remembered as a pulse and not repeated for that root
+func acceptProjectName(reader *bufio.Reader, config *Config, state *State, process string, project Project) bool {
+	mode := "ask"
+	if config != nil {
+		if value, ok := config.Get("project_autoname"); ok {
+			mode = value
+		}
+	}
+	switch mode {
+	case "never":
+		return false
+	case "always":
+		return true
+	}
+
+	fmt.Printf("%s project '%s' at %s\n", project.Language, project.Name, project.Root)
+	fmt.Printf("Name this shell '%s'? [Y/n] ", sanitizeProcessName(project.Name))
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "" || answer == "y" || answer == "yes" {
+		return true
+	}
+
+	state.SetPulse(process, Pulse{Name: pulseProjectAutoname, TV: "N", Response: project.Root})
+	state.Save()
+	return false
+}
+
+// projectIntention is the intention given to a process named after a project
+func projectIntention(project Project) string {
+	return "working on " + project.Name
+}
+
+// offerProjectNameNonInteractive offers the project name after a move made
+// from the command line; only asks when stdin is a terminal
+func offerProjectNameNonInteractive(state *State, process, dir string) {
+	project, ok := projectNameOffer(state, process, dir)
+	if !ok || !isTerminal(os.Stdin) {
+		return
+	}
+	config, _ := LoadConfig(getConfigFilePath())
+	if acceptProjectName(bufio.NewReader(os.Stdin), config, state, process, project) {
+		cmdNameNonInteractive(state, process, []string{"--here", projectIntention(project)})
+	}
+}
+
+// projectCommand returns the command for 'project run' or 'project test'
+// A Makefile target of the same name wins over the language's default
+func projectCommand(project Project, action string) []string {
+	if makefileHasTarget(filepath.Join(project.Root, "Makefile"), action) {
+		return []string{"make", action}
+	}
+
+	defaults := map[string]map[string][]string{
+		"Go":         {"run": {"go", "run", "."}, "test": {"go", "test", "./..."}},
+		"Rust":       {"run": {"cargo", "run"}, "test": {"cargo", "test"}},
+		"JavaScript": {"run": {"npm", "start"}, "test": {"npm", "test"}},
+		"Python":     {"test": {"python3", "-m", "pytest"}},
+	}
+	return defaults[project.Language][action]
+}
+
+// makefileHasTarget reports whether a Makefile defines target
+func makefileHasTarget(file, target string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		name, _, ok := strings.Cut(line, ":")
+		if ok && !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line[len(name)+1:], "=") {
+			for _, t := range strings.Fields(name) {
+				if t == target {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// printProject shows the detected project
+func printProject(project Project) {
+	fmt.Printf("=== Project: %s ===\n", project.Name)
+	fmt.Printf("  Root:     %s\n", project.Root)
+	fmt.Printf("  Language: %s\n", project.Language)
+	fmt.Printf("  Markers:  %s\n", strings.Join(project.Markers, ", "))
+	for _, action := range []string{"run", "test"} {
+		if parts := projectCommand(project, action); parts != nil {
+			fmt.Printf("  %-9s %s\n", action+":", strings.Join(parts, " "))
+		}
+	}
+}
+
+// runProjectCommand runs 'project run|test' in the project root
+func runProjectCommand(project Project, action string, args []string) int {
+	parts := projectCommand(project, action)
+	if parts == nil {
+		fmt.Printf("✗ No %s command for a %s project (add a '%s' target to a Makefile)\n",
+			action, project.Language, action)
+		return 1
+	}
+	parts = append(parts, args...)
+
+	oldDir, _ := os.Getwd()
+	if err := os.Chdir(project.Root); err != nil {
+		fmt.Printf("✗ Cannot change directory: %v\n", err)
+		return 1
+	}
+	defer os.Chdir(oldDir)
+
+	fmt.Printf("→ %s (in %s)\n", strings.Join(parts, " "), project.Root)
+	return ExecuteScript(parts)
+}
+
+// currentProject detects the project of the working directory
+func currentProject() (Project, bool) {
+	dir, _ := os.Getwd()
+	project, ok := DetectProject(dir)
+	if !ok {
+		fmt.Println("✗ Not in a project (no go.mod, package.json, Cargo.toml, pyproject.toml, Makefile or .git above)")
+	}
+	return project, ok
+}
+
+// cmdProject handles the 'project' command in the shell
+func (sh *Shell) cmdProject(args []string) {
+	project, ok := currentProject()
+	if !ok {
+		return
+	}
+
+	if len(args) > 0 && args[0] == "root" {
+		sh.changeDirectory(project.Root)
+		return
+	}
+	projectAction(project, args)
+}
+
+// cmdProjectNonInteractive handles 'iptp project ...'
+func cmdProjectNonInteractive(state *State, process string, args []string) int {
+	project, ok := currentProject()
+	if !ok {
+		return 1
+	}
+
+	if len(args) > 0 && args[0] == "root" {
+		oldDir, _ := os.Getwd()
+		fmt.Printf("✓ Changed to: %s\n", project.Root)
+		moveParent(state, process, project.Root, oldDir)
+		offerProjectNameNonInteractive(state, process, project.Root)
+		return 0
+	}
+	return projectAction(project, args)
+}
+
+// projectAction runs the project subcommands shared by shell and CLI
+func projectAction(project Project, args []string) int {
+	if len(args) == 0 {
+		printProject(project)
+		return 0
+	}
+
+	switch args[0] {
+	case "run", "test":
+		return runProjectCommand(project, args[0], args[1:])
+	default:
+		fmt.Println("Usage: project [root|run [ARGS...]|test [ARGS...]]")
+		return 1
+	}
+}
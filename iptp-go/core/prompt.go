@@ -0,0 +1,324 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promptTimeout bounds how long the whole prompt may take to render
+// Segments that miss the deadline render as empty
+const promptTimeout = 150 * time.Millisecond
+
+// promptSegment computes one {segment} of the prompt template
+// arg is the optional text after ':' (e.g. {intention:15})
+type promptSegment func(ctx context.Context, snap *promptSnapshot, arg string) string
+
+// promptSnapshot is the shell state the segments may look at
+// It is copied before the segments start, so a segment that outlives the
+// deadline never touches the live shell or state
+type promptSnapshot struct {
+	name      string
+	intention string
+	pulses    []Pulse
+	dir       string
+	lastExit  int
+}
+
+// snapshotPrompt copies what the segments need from the shell
+func (sh *Shell) snapshotPrompt() *promptSnapshot {
+	snap := &promptSnapshot{
+		name:     sh.displayName,
+		dir:      sh.getCurrentDirName(),
+		lastExit: sh.lastExit,
+	}
+	if proc, ok := sh.state.GetProcess(sh.currentProcess); ok {
+		snap.intention = proc.Intention
+		snap.pulses = append([]Pulse(nil), proc.Pulses...)
+	}
+	return snap
+}
+
+// promptSegments maps segment names to their implementations
+var promptSegments = map[string]promptSegment{
+	"name":      segmentName,
+	"intention": segmentIntention,
+	"path":      segmentPath,
+	"dir":       segmentDir,
+	"pulses":    segmentPulses,
+	"exit":      segmentExit,
+	"sessions":  segmentSessions,
+	"dns":       segmentDNS,
+	"git":       segmentGit,
+}
+
+// promptToken is either literal text or a segment reference
+type promptToken struct {
+	literal string
+	segment string
+	arg     string
+}
+
+// parsePromptTemplate splits a template like "[{name}] {dir}$ " into tokens
+// Use {{ and }} for literal braces
+func parsePromptTemplate(template string) []promptToken {
+	var tokens []promptToken
+	var literal strings.Builder
+
+	flush := func() {
+		if literal.Len() > 0 {
+			tokens = append(tokens, promptToken{literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '{' && i+1 < len(template) && template[i+1] == '{':
+			literal.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(template) && template[i+1] == '}':
+			literal.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end == -1 {
+				literal.WriteString(template[i:])
+				i = len(template)
+				continue
+			}
+			flush()
+			name, arg, _ := strings.Cut(template[i+1:i+end], ":")
+			tokens = append(tokens, promptToken{segment: strings.TrimSpace(name), arg: arg})
+			i += end
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// renderPrompt expands the prompt template for the shell
+// Segments run concurrently and share a single deadline
+func (sh *Shell) renderPrompt() string {
+	tokens := parsePromptTemplate(sh.config.Prompt)
+	snap := sh.snapshotPrompt()
+
+	ctx, cancel := context.WithTimeout(context.Background(), promptTimeout)
+	defer cancel()
+
+	results := make([]chan string, len(tokens))
+	for i, tok := range tokens {
+		if tok.segment == "" {
+			continue
+		}
+		segment, ok := promptSegments[tok.segment]
+		if !ok {
+			continue
+		}
+		ch := make(chan string, 1)
+		results[i] = ch
+		go func(arg string) {
+			ch <- segment(ctx, snap, arg)
+		}(tok.arg)
+	}
+
+	var out strings.Builder
+	for i, tok := range tokens {
+		if tok.segment == "" {
+			out.WriteString(tok.literal)
+			continue
+		}
+		if results[i] == nil {
+			// Unknown segments are shown verbatim so typos are visible
+			out.WriteString("{" + tok.segment + "}")
+			continue
+		}
+		select {
+		case s := <-results[i]:
+			out.WriteString(s)
+		case <-ctx.Done():
+		}
+	}
+
+	return out.String()
+}
+
+func segmentName(ctx context.Context, snap *promptSnapshot, arg string) string {
+	return snap.name
+}
+
+// segmentIntention shows the process intention, truncated to arg runes (default 20)
+func segmentIntention(ctx context.Context, snap *promptSnapshot, arg string) string {
+	limit := 20
+	if n, err := strconv.Atoi(arg); err == nil && n > 0 {
+		limit = n
+	}
+
+	runes := []rune(snap.intention)
+	if len(runes) <= limit {
+		return snap.intention
+	}
+	return string(runes[:limit-1]) + "…"
+}
+
+func segmentPath(ctx context.Context, snap *promptSnapshot, arg string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "?"
+	}
+	return FormatPath(dir)
+}
+
+func segmentDir(ctx context.Context, snap *promptSnapshot, arg string) string {
+	return snap.dir
+}
+
+// segmentPulses summarises the current process pulses as ✓Y ✗N ?U
+func segmentPulses(ctx context.Context, snap *promptSnapshot, arg string) string {
+	return PulseSummary(snap.pulses)
+}
+
+// segmentExit shows the last exit code, only when it was non-zero
+func segmentExit(ctx context.Context, snap *promptSnapshot, arg string) string {
+	if snap.lastExit == 0 {
+		return ""
+	}
+	return strconv.Itoa(snap.lastExit)
+}
+
+// segmentSessions shows the number of detached terminal sessions (see
+// 'spawn'), if any
+func segmentSessions(ctx context.Context, snap *promptSnapshot, arg string) string {
+	conn, err := dialSessions(false)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reply, _, err := sessionCall(conn, sessionRequest{Op: "list"})
+	if err != nil {
+		return ""
+	}
+
+	n := 0
+	for _, s := range reply.Sessions {
+		if !s.Attached {
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return "1 session"
+	}
+	return fmt.Sprintf("%d sessions", n)
+}
+
+// segmentDNS reports the DNS router status published by the dnsrouting build
+// It only reads a small status file, so it never blocks the prompt
+func segmentDNS(ctx context.Context, snap *promptSnapshot, arg string) string {
+	data, err := os.ReadFile(DNSStatusFilePath())
+	if err != nil {
+		return ""
+	}
+
+	var status struct {
+		Running bool `json:"running"`
+		PID     int  `json:"pid"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil || !status.Running {
+		return ""
+	}
+
+	// A stale file left by a crashed router does not count
+	if status.PID > 0 && !processAlive(status.PID) {
+		return ""
+	}
+	return "dns✓"
+}
+
+// segmentGit shows the branch and a * when the worktree is dirty
+func segmentGit(ctx context.Context, snap *promptSnapshot, arg string) string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+
+	branch, ok := gitBranch(cwd)
+	if !ok {
+		return ""
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain", "--untracked-files=no")
+	cmd.Dir = cwd
+	output, err := cmd.Output()
+	if err == nil && len(strings.TrimSpace(string(output))) > 0 {
+		branch += "*"
+	}
+	return branch
+}
+
+// gitBranch finds the enclosing repository and reads its HEAD directly
+// Detached heads are shown as a short commit hash
+func gitBranch(dir string) (string, bool) {
+	_, gitDir, ok := findGitDir(dir)
+	if !ok {
+		return "", false
+	}
+
+	head, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return "", false
+	}
+	ref := strings.TrimSpace(string(head))
+	if strings.HasPrefix(ref, "ref: ") {
+		return strings.TrimPrefix(ref, "ref: refs/heads/"), true
+	}
+	return shortCommit(ref), true
+}
+
+// PulseSummary counts pulses by truth value, e.g. "✓3 ✗1 ?2"
+func PulseSummary(pulses []Pulse) string {
+	var yes, no, unknown int
+	for _, p := range pulses {
+		switch p.TV {
+		case "Y":
+			yes++
+		case "N":
+			no++
+		default:
+			unknown++
+		}
+	}
+
+	var parts []string
+	if yes > 0 {
+		parts = append(parts, fmt.Sprintf("✓%d", yes))
+	}
+	if no > 0 {
+		parts = append(parts, fmt.Sprintf("✗%d", no))
+	}
+	if unknown > 0 {
+		parts = append(parts, fmt.Sprintf("?%d", unknown))
+	}
+	return strings.Join(parts, " ")
+}
+
+// DNSStatusFilePath returns the file the DNS router writes on start/stop
+func DNSStatusFilePath() string {
+	return filepath.Join(os.TempDir(), "iptp_dns_status.json")
+}
@@ -0,0 +1,26 @@
+//go:build !linux && !darwin
+
+package core
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// ptySupported reports whether sessions can be spawned on this platform
+const ptySupported = false
+
+var errNoPTY = errors.New("terminal sessions are not supported on this platform")
+
+func openPTY() (master, slave *os.File, err error) { return nil, nil, errNoPTY }
+
+func setWinsize(f *os.File, rows, cols int) error { return errNoPTY }
+
+func makeRaw(f *os.File) (func(), error) { return nil, errNoPTY }
+
+func isTerminal(f *os.File) bool { return false }
+
+func watchResize(fn func()) (stop func()) { return func() {} }
+
+func sessionProcAttr() *syscall.SysProcAttr { return nil }
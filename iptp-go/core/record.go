@@ -0,0 +1,501 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxReplayIdle caps pauses during replay so long idle gaps don't stall it
+const maxReplayIdle = 2 * time.Second
+
+// Recording links a session recording to a process
+type Recording struct {
+	File     string  `json:"file"`
+	Started  string  `json:"started"`
+	Duration float64 `json:"duration"` // seconds
+	Commands int     `json:"commands"`
+}
+
+// asciicastHeader is the first line of an asciicast v2 file
+type asciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Recorder captures shell input and output as an asciicast v2 stream
+// While active, the shell and its commands run on a pseudo-terminal, as
+// with script(1): keystrokes are copied from the real terminal (in raw mode)
+// to the pty and its output back, and both are recorded. Commands keep a
+// terminal, so full-screen programs and prompts work and are captured, and
+// each runs in its own session with the pty as its controlling terminal, so
+// ^C reaches it through the pty and /dev/tty writes are recorded too.
+type Recorder struct {
+	path     string
+	started  time.Time
+	file     *os.File
+	mu       sync.Mutex
+	closed   bool
+	commands int
+
+	master     *os.File
+	slave      *os.File
+	input      *bufio.Reader // the shell reads command lines from the pty
+	realStdin  *os.File
+	realStdout *os.File
+	realStderr *os.File
+	restore    func() // leaves raw mode, nil if stdin is not a terminal
+	stopResize func()
+	stopInput  chan struct{}
+	inputDone  chan struct{}
+	done       chan struct{}
+}
+
+// recordingPTY is the pty commands run on while a recording is active
+var recordingPTY *os.File
+
+// getRecordingsDir returns the directory holding session recordings
+// Recordings hold everything typed, so the directory is private to the user
+func getRecordingsDir() (string, error) {
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("iptp_recordings_%d", os.Getuid()))
+	return dir, ensurePrivateDir(dir)
+}
+
+// StartRecorder begins recording to a new file for a process
+func StartRecorder(process, intention string) (*Recorder, error) {
+	if !ptySupported {
+		return nil, fmt.Errorf("recording needs a pseudo-terminal, which this platform lacks")
+	}
+	dir, err := getRecordingsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	started := time.Now()
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.cast", process, started.Format("20060102-150405")))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := terminalSize(os.Stdout)
+	header := asciicastHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: started.Unix(),
+		Title:     fmt.Sprintf("%s: %s", process, intention),
+		Env:       map[string]string{"SHELL": "iptp", "TERM": os.Getenv("TERM")},
+	}
+	data, _ := json.Marshal(header)
+	if _, err := fmt.Fprintf(file, "%s\n", data); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	master, slave, err := openPTY()
+	if err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	setWinsize(master, height, width)
+
+	rec := &Recorder{
+		path:       path,
+		started:    started,
+		file:       file,
+		master:     master,
+		slave:      slave,
+		input:      bufio.NewReader(slave),
+		realStdin:  os.Stdin,
+		realStdout: os.Stdout,
+		realStderr: os.Stderr,
+		stopInput:  make(chan struct{}),
+		inputDone:  make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if isTerminal(os.Stdin) {
+		if rec.restore, err = makeRaw(os.Stdin); err != nil {
+			master.Close()
+			slave.Close()
+			file.Close()
+			os.Remove(path)
+			return nil, err
+		}
+	}
+	rec.stopResize = watchResize(func() {
+		cols, rows := terminalSize(rec.realStdout)
+		setWinsize(master, rows, cols)
+	})
+
+	os.Stdin = slave
+	os.Stdout = slave
+	os.Stderr = slave
+	recordingPTY = slave
+	go rec.copyInput()
+	go rec.copyOutput()
+
+	return rec, nil
+}
+
+// copyInput forwards keystrokes from the real terminal to the pty
+// Raw mode reads time out, so the loop notices Stop; when stdin is not a
+// terminal its end is passed on as an end-of-file character
+func (r *Recorder) copyInput() {
+	defer close(r.inputDone)
+
+	buf := make([]byte, 1024)
+	for {
+		select {
+		case <-r.stopInput:
+			return
+		default:
+		}
+
+		n, err := r.realStdin.Read(buf)
+		if n > 0 {
+			r.event("i", string(buf[:n]))
+			r.master.Write(buf[:n])
+		}
+		if err != nil && (err != io.EOF || r.restore == nil) {
+			if r.restore == nil {
+				r.master.Write([]byte{4}) // ^D
+			}
+			return
+		}
+	}
+}
+
+// copyOutput forwards pty output to the terminal and the recording
+func (r *Recorder) copyOutput() {
+	defer close(r.done)
+
+	buf := make([]byte, 32*1024)
+	var pending []byte
+	for {
+		n, err := r.master.Read(buf)
+		if n > 0 {
+			r.realStdout.Write(buf[:n])
+
+			// Don't split multi-byte characters across events
+			data := append(pending, buf[:n]...)
+			complete := validUTF8Prefix(data)
+			pending = append([]byte{}, data[complete:]...)
+			r.event("o", terminalNewlines(string(data[:complete])))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// Input counts a command line typed at the prompt; the keystrokes and
+// their echo are already recorded from the pty
+func (r *Recorder) Input(line string) {
+	if line == "" {
+		return
+	}
+	r.mu.Lock()
+	r.commands++
+	r.mu.Unlock()
+}
+
+// Reader returns the reader the shell takes command lines from while
+// recording
+func (r *Recorder) Reader() *bufio.Reader {
+	return r.input
+}
+
+// event appends one [time, type, data] line to the recording
+func (r *Recorder) event(kind, data string) {
+	if data == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+
+	elapsed := time.Since(r.started).Seconds()
+	line, _ := json.Marshal([]interface{}{roundSeconds(elapsed), kind, data})
+	fmt.Fprintf(r.file, "%s\n", line)
+}
+
+// Stop restores the terminal and closes the recording
+func (r *Recorder) Stop() Recording {
+	os.Stdin = r.realStdin
+	os.Stdout = r.realStdout
+	os.Stderr = r.realStderr
+	recordingPTY = nil
+	close(r.stopInput)
+	r.stopResize()
+	if r.restore != nil {
+		// Leave raw mode only once the copy loop is out of its read, so it
+		// can't take the next line typed to the shell
+		<-r.inputDone
+		r.restore()
+	}
+	r.slave.Close()
+
+	// Programs left running in the background may still hold the pty
+	// open; don't wait for them
+	select {
+	case <-r.done:
+	case <-time.After(500 * time.Millisecond):
+	}
+	r.master.Close()
+
+	r.mu.Lock()
+	r.closed = true
+	r.file.Close()
+	commands := r.commands
+	r.mu.Unlock()
+
+	return Recording{
+		File:     r.path,
+		Started:  r.started.Format(time.RFC3339),
+		Duration: roundSeconds(time.Since(r.started).Seconds()),
+		Commands: commands,
+	}
+}
+
+// AddRecording links a finished recording to a process
+func (s *State) AddRecording(processName string, rec Recording) bool {
+	process, ok := s.Processes[processName]
+	if !ok {
+		return false
+	}
+	process.Recordings = append(process.Recordings, rec)
+	s.Processes[processName] = process
+	return true
+}
+
+// printRecordings adds a recordings section to 'state' output
+func printRecordings(proc Process) {
+	if len(proc.Recordings) == 0 {
+		return
+	}
+	fmt.Println()
+	fmt.Println("=== Recordings ===")
+	for i, rec := range proc.Recordings {
+		fmt.Printf("  %d) %s  %.1fs  %s\n", i+1, formatTimestamp(rec.Started), rec.Duration, rec.File)
+	}
+}
+
+// cmdRecord handles 'record start|stop|list'
+func (sh *Shell) cmdRecord(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: record [start|stop|list]")
+		return
+	}
+
+	switch args[0] {
+	case "start":
+		if sh.recorder != nil {
+			fmt.Printf("✗ Already recording to %s\n", sh.recorder.path)
+			return
+		}
+		proc, _ := sh.state.GetProcess(sh.currentProcess)
+		rec, err := StartRecorder(sh.currentProcess, proc.Intention)
+		if err != nil {
+			fmt.Printf("✗ Cannot start recording: %v\n", err)
+			return
+		}
+		sh.recorder = rec
+		sh.recordingProcess = sh.currentProcess
+		sh.terminalReader = sh.reader
+		sh.reader = rec.Reader()
+		fmt.Printf("● Recording %s to %s\n", sh.currentProcess, rec.path)
+		fmt.Println("  Type 'record stop' to finish")
+	case "stop":
+		if sh.recorder == nil {
+			fmt.Println("Not recording")
+			return
+		}
+		rec := sh.stopRecording()
+		fmt.Printf("✓ Saved recording (%.1fs, %d commands): %s\n", rec.Duration, rec.Commands, rec.File)
+	case "list":
+		cmdRecordList(sh.state, sh.currentProcess, args[1:])
+	default:
+		fmt.Printf("Unknown record command: %s\n", args[0])
+		fmt.Println("Available: start, stop, list")
+	}
+}
+
+// stopRecording ends the active recording and links it to the process
+// that was current when recording started
+func (sh *Shell) stopRecording() Recording {
+	rec := sh.recorder.Stop()
+	sh.recorder = nil
+	sh.reader = sh.terminalReader
+	if sh.state.AddRecording(sh.recordingProcess, rec) {
+		sh.state.Save()
+	}
+	return rec
+}
+
+// cmdRecordList lists recordings of a process (--all for every process)
+func cmdRecordList(state *State, process string, args []string) int {
+	names := []string{process}
+	if len(args) > 0 && args[0] == "--all" {
+		names = state.ListProcesses()
+	}
+
+	found := false
+	for _, name := range names {
+		proc, ok := state.GetProcess(name)
+		if !ok || len(proc.Recordings) == 0 {
+			continue
+		}
+		found = true
+		fmt.Printf("=== Recordings: %s ===\n", name)
+		for i, rec := range proc.Recordings {
+			fmt.Printf("  %d) %s  %6.1fs  %3d commands  %s\n",
+				i+1, formatTimestamp(rec.Started), rec.Duration, rec.Commands, rec.File)
+		}
+	}
+
+	if !found {
+		fmt.Println("No recordings (start one with 'record start')")
+	}
+	return 0
+}
+
+// cmdReplayNonInteractive plays back a recording
+// iptp replay FILE|N [--speed 2]
+func cmdReplayNonInteractive(state *State, process string, args []string) int {
+	speed := 1.0
+	var target string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--speed", "-s":
+			if i+1 < len(args) {
+				if v, err := strconv.ParseFloat(args[i+1], 64); err == nil && v > 0 {
+					speed = v
+				}
+				i++
+			}
+		default:
+			target = args[i]
+		}
+	}
+
+	if target == "" {
+		fmt.Println("Usage: replay FILE|N [--speed 2]")
+		return 1
+	}
+
+	// A number refers to the current process's 'record list'
+	if n, err := strconv.Atoi(target); err == nil {
+		proc, _ := state.GetProcess(process)
+		if n < 1 || n > len(proc.Recordings) {
+			fmt.Printf("✗ No recording %d for %s\n", n, process)
+			return 1
+		}
+		target = proc.Recordings[n-1].File
+	}
+
+	if err := replayCast(target, speed, os.Stdout); err != nil {
+		fmt.Printf("\n✗ Cannot replay %s: %v\n", target, err)
+		return 1
+	}
+	return 0
+}
+
+// replayCast writes the output events of an asciicast file with their timing
+func replayCast(path string, speed float64, out io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	if !scanner.Scan() {
+		return fmt.Errorf("empty recording")
+	}
+	var header asciicastHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version != 2 {
+		return fmt.Errorf("not an asciicast v2 file")
+	}
+
+	last := 0.0
+	for scanner.Scan() {
+		var event []interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || len(event) != 3 {
+			continue
+		}
+		at, _ := event[0].(float64)
+		kind, _ := event[1].(string)
+		data, _ := event[2].(string)
+		if kind != "o" {
+			continue
+		}
+
+		delay := time.Duration((at - last) / speed * float64(time.Second))
+		if delay > maxReplayIdle {
+			delay = maxReplayIdle
+		}
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		last = at
+
+		io.WriteString(out, data)
+	}
+	return scanner.Err()
+}
+
+// validUTF8Prefix returns the length of data without a trailing partial character
+func validUTF8Prefix(data []byte) int {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
+
+// terminalNewlines converts bare \n to \r\n as the terminal driver would
+func terminalNewlines(s string) string {
+	if !strings.Contains(s, "\n") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// roundSeconds keeps event times readable (microsecond precision)
+func roundSeconds(s float64) float64 {
+	return float64(int64(s*1e6)) / 1e6
+}
+
+// formatTimestamp shows an RFC3339 time as local "2006-01-02 15:04"
+func formatTimestamp(ts string) string {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ts
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordedCommandInterrupt(t *testing.T) {
+	if !ptySupported {
+		t.Skip("no pseudo-terminals on this platform")
+	}
+	t.Setenv("TMPDIR", t.TempDir())
+
+	// Keystrokes come from a pipe standing in for the real terminal
+	keys, typed, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer typed.Close()
+	stdin, stdout, stderr := os.Stdin, os.Stdout, os.Stderr
+	defer func() { os.Stdin, os.Stdout, os.Stderr = stdin, stdout, stderr }()
+	os.Stdin = keys
+	if os.Stdout, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Stdout.Close()
+
+	rec, err := StartRecorder("api", "working on the api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan int, 1)
+	go func() {
+		exitCode, _ := RunScript([]string{"sleep", "30"})
+		done <- exitCode
+	}()
+
+	// ^C is lost if typed before sleep holds the pty, so keep typing it
+	exitCode := 0
+	deadline := time.After(5 * time.Second)
+	for interrupted := false; !interrupted; {
+		select {
+		case exitCode = <-done:
+			interrupted = true
+		case <-time.After(100 * time.Millisecond):
+			typed.Write([]byte{3})
+		case <-deadline:
+			rec.Stop()
+			t.Fatal("^C typed while recording did not interrupt sleep")
+		}
+	}
+	recording := rec.Stop()
+
+	if exitCode == 0 {
+		t.Errorf("interrupted sleep exited with code 0")
+	}
+	data, err := os.ReadFile(recording.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"i","\u0003"]`) {
+		t.Errorf("recording has no ^C input:\n%s", data)
+	}
+}
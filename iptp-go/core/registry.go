@@ -0,0 +1,870 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Command is a builtin registered with the shell
+// Run is used inside the interactive shell, Exec by "iptp CMD ..."
+// A command may provide either or both
+type Command struct {
+	Name        string
+	Aliases     []string
+	Usage       string // e.g. "goto PATH"
+	Help        string // one-line description
+	Group       string // help section
+	Subcommands []Subcommand
+	Run         func(sh *Shell, args []string)
+	Exec        func(state *State, process string, args []string) int
+	Complete    func(state *State, args []string) []string
+
+	// CLIOnly commands only run as "iptp CMD", because inside the iptp
+	// shell the name belongs to a system command (rm)
+	CLIOnly bool
+}
+
+// Subcommand documents one form of a command with subcommands (dns start, dns logs [N])
+// When present they replace the command's own help line
+type Subcommand struct {
+	Usage string
+	Help  string
+}
+
+// commandRegistry holds builtins by name and alias
+var commandRegistry = map[string]*Command{}
+
+// commandOrder keeps registration order for help output
+var commandOrder []*Command
+
+// helpGroups lists the core help sections in display order
+// Groups registered by extensions follow in alphabetical order
+var helpGroups = []string{"Navigation", "Process Management", "System"}
+
+// RegisterCommand adds a builtin to the registry
+// Registering the same name twice replaces the earlier command
+func RegisterCommand(cmd *Command) {
+	if existing, ok := commandRegistry[cmd.Name]; ok {
+		for i, c := range commandOrder {
+			if c == existing {
+				commandOrder = append(commandOrder[:i], commandOrder[i+1:]...)
+				break
+			}
+		}
+	}
+
+	commandRegistry[cmd.Name] = cmd
+	for _, alias := range cmd.Aliases {
+		commandRegistry[alias] = cmd
+	}
+	commandOrder = append(commandOrder, cmd)
+}
+
+// LookupCommand finds a builtin by name or alias
+func LookupCommand(name string) (*Command, bool) {
+	cmd, ok := commandRegistry[name]
+	return cmd, ok
+}
+
+// LookupBuiltin finds a command the interactive shell runs itself
+func LookupBuiltin(name string) (*Command, bool) {
+	cmd, ok := commandRegistry[name]
+	if !ok || cmd.CLIOnly {
+		return nil, false
+	}
+	return cmd, true
+}
+
+// commandGroups returns registered commands grouped for help output
+func commandGroups(filter func(*Command) bool) ([]string, map[string][]*Command) {
+	groups := make(map[string][]*Command)
+	for _, cmd := range commandOrder {
+		if cmd.Help == "" || !filter(cmd) {
+			continue
+		}
+		groups[cmd.Group] = append(groups[cmd.Group], cmd)
+	}
+
+	var order []string
+	for _, g := range helpGroups {
+		if _, ok := groups[g]; ok {
+			order = append(order, g)
+		}
+	}
+	var extra []string
+	for g := range groups {
+		if !containsString(helpGroups, g) {
+			extra = append(extra, g)
+		}
+	}
+	sort.Strings(extra)
+
+	return append(order, extra...), groups
+}
+
+// printCommandHelp prints registered commands section by section
+func printCommandHelp(filter func(*Command) bool) {
+	order, groups := commandGroups(filter)
+	for _, group := range order {
+		fmt.Printf("%s:\n", group)
+		for _, cmd := range groups[group] {
+			if len(cmd.Subcommands) == 0 {
+				fmt.Printf("  %-19s - %s\n", cmd.Usage, cmd.Help)
+				continue
+			}
+			for _, sub := range cmd.Subcommands {
+				fmt.Printf("  %-19s - %s\n", sub.Usage, sub.Help)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+// CompleteCommand returns completion candidates for a partial command line
+// args[0] is the command being completed; the last arg is the partial word
+func CompleteCommand(state *State, args []string) []string {
+	if len(args) <= 1 {
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		var names []string
+		for name := range commandRegistry {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+		for _, plugin := range ListPlugins() {
+			if strings.HasPrefix(plugin.Name, prefix) {
+				names = append(names, plugin.Name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if cmd, ok := LookupCommand(args[0]); ok {
+		if cmd.Complete != nil {
+			return cmd.Complete(state, args[1:])
+		}
+		if len(cmd.Subcommands) > 0 {
+			return completeWords(cmd.subcommandNames()...)(state, args[1:])
+		}
+		return nil
+	}
+
+	if path, ok := FindPlugin(args[0]); ok {
+		return CompletePlugin(path, args[1:])
+	}
+
+	return nil
+}
+
+// subcommandNames returns the first word after the command name in each subcommand
+func (c *Command) subcommandNames() []string {
+	var names []string
+	for _, sub := range c.Subcommands {
+		fields := strings.Fields(sub.Usage)
+		if len(fields) > 1 && !containsString(names, fields[1]) {
+			names = append(names, fields[1])
+		}
+	}
+	return names
+}
+
+// completeDirectories completes the last argument as a directory path
+func completeDirectories(state *State, args []string) []string {
+	partial := ""
+	if len(args) > 0 {
+		partial = args[len(args)-1]
+	}
+
+	if strings.HasPrefix(partial, bookmarkPrefix) && !strings.Contains(partial, "/") {
+		return completeBookmarks(state, partial)
+	}
+
+	dir, prefix := filepath.Split(partial)
+	searchDir := dir
+	if searchDir == "" {
+		searchDir = "."
+	} else if strings.HasPrefix(searchDir, bookmarkPrefix) {
+		if resolved, err := state.ResolveBookmark(searchDir); err == nil {
+			searchDir = resolved
+		}
+	} else if strings.HasPrefix(searchDir, "~") {
+		if expanded, err := ExpandPath(searchDir); err == nil {
+			searchDir = expanded
+		}
+	}
+
+	entries, err := os.ReadDir(searchDir)
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		matches = append(matches, dir+entry.Name()+string(os.PathSeparator))
+	}
+	return matches
+}
+
+// completeFiles completes the last argument as a file or directory path
+func completeFiles(state *State, args []string) []string {
+	partial := ""
+	if len(args) > 0 {
+		partial = args[len(args)-1]
+	}
+
+	dir, prefix := filepath.Split(partial)
+	searchDir := dir
+	if searchDir == "" {
+		searchDir = "."
+	}
+
+	entries, err := os.ReadDir(searchDir)
+	if err != nil {
+		return nil
+	}
+
+	var matches []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		name := dir + entry.Name()
+		if entry.IsDir() {
+			name += string(os.PathSeparator)
+		}
+		matches = append(matches, name)
+	}
+	return matches
+}
+
+// completeProcesses completes the last argument as a saved process name
+func completeProcesses(state *State, args []string) []string {
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[len(args)-1]
+	}
+
+	var matches []string
+	for _, name := range state.ListProcesses() {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// completeLiveShells completes the first argument as a process with an open shell
+func completeLiveShells(state *State, args []string) []string {
+	if len(args) > 1 {
+		return nil
+	}
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	var matches []string
+	for name := range liveProcesses() {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// completeWords returns a completer for a fixed set of subcommands
+func completeWords(words ...string) func(*State, []string) []string {
+	return func(state *State, args []string) []string {
+		if len(args) > 1 {
+			return nil
+		}
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		var matches []string
+		for _, w := range words {
+			if strings.HasPrefix(w, prefix) {
+				matches = append(matches, w)
+			}
+		}
+		return matches
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	// Navigation
+	RegisterCommand(&Command{
+		Name:     "cd",
+		Usage:    "cd [PATH|-]",
+		Help:     "Change directory (standard command)",
+		Group:    "Navigation",
+		Run:      (*Shell).cmdCd,
+		Complete: completeDirectories,
+	})
+	RegisterCommand(&Command{
+		Name:  "goto",
+		Usage: "goto PATH|@MARK|TERM...",
+		Help:  "Change directory with auto-save ('*pattern*' to fuzzy find)",
+		Group: "Navigation",
+		Subcommands: []Subcommand{
+			{"goto PATH|@MARK", "Change directory ('*pattern*' to fuzzy find)"},
+			{"goto TERM...", "Best-ranked visited directory matching every term"},
+			{"goto -i TERM...", "Choose among the best matches"},
+			{"goto --forget [PATH...]", "Drop directories from the ranking"},
+		},
+		Run:      (*Shell).cmdGoto,
+		Exec:     cmdGotoNonInteractive,
+		Complete: completeDirectories,
+	})
+	RegisterCommand(&Command{
+		Name:     "mark",
+		Usage:    "mark NAME [--tag TAG]",
+		Help:     "Bookmark the current directory (goto @NAME to return)",
+		Group:    "Navigation",
+		Exec:     cmdMarkNonInteractive,
+		Complete: func(state *State, args []string) []string { return completeBookmarks(state, args[len(args)-1]) },
+	})
+	RegisterCommand(&Command{
+		Name:  "marks",
+		Usage: "marks [--tag TAG]... [--json]",
+		Help:  "List bookmarks ('marks export [FILE]', 'marks import FILE')",
+		Group: "Navigation",
+		Subcommands: []Subcommand{
+			{"marks [--tag TAG]... [--json]", "List bookmarks"},
+			{"marks export [FILE]", "Write bookmarks as JSON (stdout by default)"},
+			{"marks import FILE", "Merge bookmarks from an export"},
+		},
+		Exec: cmdMarksNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:     "unmark",
+		Usage:    "unmark NAME",
+		Help:     "Remove a bookmark",
+		Group:    "Navigation",
+		Exec:     cmdUnmarkNonInteractive,
+		Complete: func(state *State, args []string) []string { return completeBookmarks(state, args[len(args)-1]) },
+	})
+	RegisterCommand(&Command{
+		Name:  "index",
+		Usage: "index [status|rebuild|roots]",
+		Help:  "Directory index used by goto and getmethere",
+		Group: "Navigation",
+		Subcommands: []Subcommand{
+			{"index status", "Show indexed directories, roots and freshness"},
+			{"index rebuild", "Index the roots from scratch"},
+			{"index roots [add|remove DIR]", "List or change the indexed roots (default: home)"},
+		},
+		Exec: cmdIndexNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:  "hook",
+		Usage: "hook allow|deny [DIR|FILE] | hook list",
+		Help:  "Trust or block a directory's .iptp/enter and .iptp/leave hooks",
+		Group: "Navigation",
+		Subcommands: []Subcommand{
+			{"hook allow [DIR|FILE]", "Let the hooks run as they are now (default: nearest hooks above)"},
+			{"hook deny [DIR|FILE]", "Never run the hooks, and stop asking"},
+			{"hook list", "Show decisions and hooks waiting for one"},
+		},
+		Exec: cmdHookNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:  "getmethere",
+		Usage: "getmethere [QUERY]",
+		Help:  "Pick a directory, process or bookmark with live filtering",
+		Group: "Navigation",
+		Run:   (*Shell).cmdGetMeThere,
+		Exec:  cmdGetMeThereNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:  "project",
+		Usage: "project [root|run|test]",
+		Help:  "Show the project around the current directory",
+		Group: "Navigation",
+		Subcommands: []Subcommand{
+			{"project", "Show the project's root, language, name and commands"},
+			{"project root", "Change to the project root"},
+			{"project run [ARGS...]", "Run the project (make run, go run ., cargo run, npm start)"},
+			{"project test [ARGS...]", "Test the project (make test, go test ./..., cargo test, ...)"},
+		},
+		Run:  (*Shell).cmdProject,
+		Exec: cmdProjectNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:  "back",
+		Usage: "back [N]",
+		Help:  "Go back N directories in navigation history",
+		Group: "Navigation",
+		Run: func(sh *Shell, args []string) {
+			cmdNavigate(sh.state, sh.currentProcess, sh.moveDirectory, args, -1)
+		},
+		Exec: func(state *State, process string, args []string) int {
+			return cmdNavigate(state, process, parentMover(state, process), args, -1)
+		},
+	})
+	RegisterCommand(&Command{
+		Name:  "forward",
+		Usage: "forward [N]",
+		Help:  "Go forward N directories again after back",
+		Group: "Navigation",
+		Run: func(sh *Shell, args []string) {
+			cmdNavigate(sh.state, sh.currentProcess, sh.moveDirectory, args, 1)
+		},
+		Exec: func(state *State, process string, args []string) int {
+			return cmdNavigate(state, process, parentMover(state, process), args, 1)
+		},
+	})
+	RegisterCommand(&Command{
+		Name:  "pushd",
+		Usage: "pushd [DIR]",
+		Help:  "Push the current directory and change to DIR (no DIR: swap with the top)",
+		Group: "Navigation",
+		Run: func(sh *Shell, args []string) {
+			cmdPushd(sh.state, sh.currentProcess, sh.moveDirectory, args)
+		},
+		Exec: func(state *State, process string, args []string) int {
+			return cmdPushd(state, process, parentMover(state, process), args)
+		},
+		Complete: completeDirectories,
+	})
+	RegisterCommand(&Command{
+		Name:  "popd",
+		Usage: "popd",
+		Help:  "Change to the directory on top of the stack and remove it",
+		Group: "Navigation",
+		Run: func(sh *Shell, args []string) {
+			cmdPopd(sh.state, sh.currentProcess, sh.moveDirectory)
+		},
+		Exec: func(state *State, process string, args []string) int {
+			return cmdPopd(state, process, parentMover(state, process))
+		},
+	})
+	RegisterCommand(&Command{
+		Name:  "dirs",
+		Usage: "dirs [-v|-c]",
+		Help:  "Show the directory stack",
+		Group: "Navigation",
+		Subcommands: []Subcommand{
+			{"dirs", "Current directory, then the stack from the top"},
+			{"dirs -v", "Numbered stack, and the back/forward history around you"},
+			{"dirs -c", "Clear the stack"},
+		},
+		Run: func(sh *Shell, args []string) {
+			cmdDirs(sh.state, sh.currentProcess, args)
+		},
+		Exec: cmdDirs,
+	})
+	RegisterCommand(&Command{
+		Name:  "pwd",
+		Usage: "pwd",
+		Help:  "Show current directory",
+		Group: "Navigation",
+		Run: func(sh *Shell, args []string) {
+			dir, _ := os.Getwd()
+			fmt.Println(dir)
+		},
+	})
+
+	// Process Management
+	RegisterCommand(&Command{
+		Name:  "name",
+		Usage: "name [--explain] [--here] [INTENTION]",
+		Help:  "Name current process with intention",
+		Group: "Process Management",
+		Subcommands: []Subcommand{
+			{"name INTENTION", "Name the process and offer to go where the intention is worked on"},
+			{"name --explain INTENTION", "Also show which grammar rule chose the name"},
+			{"name --here INTENTION", "Stay, and remember this directory for the intention"},
+		},
+		Run:  (*Shell).cmdName,
+		Exec: cmdNameNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:  "grammar",
+		Usage: "grammar [init|test [PHRASE...]]",
+		Help:  "Show the rules that turn intentions into process names",
+		Group: "Process Management",
+		Subcommands: []Subcommand{
+			{"grammar", "Show the grammar file, packs in use, stop words and rules"},
+			{"grammar init", "Write the built-in grammar out for editing"},
+			{"grammar test", "Check the grammar's test phrases"},
+			{"grammar test PHRASE...", "Show how a phrase would be named"},
+		},
+		Run:  func(sh *Shell, args []string) { cmdGrammar(args) },
+		Exec: func(state *State, process string, args []string) int { return cmdGrammar(args) },
+	})
+	RegisterCommand(&Command{
+		Name:  "save",
+		Usage: "save",
+		Help:  "Save current state",
+		Group: "Process Management",
+		Run:   func(sh *Shell, args []string) { sh.cmdSave() },
+		Exec: func(state *State, process string, args []string) int {
+			return cmdSaveNonInteractive(state, process)
+		},
+	})
+	RegisterCommand(&Command{
+		Name:  "list",
+		Usage: "list [--tag T] [--ticket T] [--verb V] [--due WHEN] [--archived|--all] [--json|--format=TMPL]",
+		Help:  "List all saved processes",
+		Group: "Process Management",
+		Subcommands: []Subcommand{
+			{"list --tag T[,T]", "Only processes whose intention has these #tags"},
+			{"list --ticket KEY / --verb V / --subject TEXT", "Filter by ticket, action verb or subject"},
+			{"list --due overdue|today|week|DATE", "Only processes due by then"},
+			{"list --archived / --all", "Only archived processes, or every process"},
+		},
+		Run: func(sh *Shell, args []string) {
+			if len(args) > 0 {
+				cmdListNonInteractive(sh.state, args)
+				return
+			}
+			sh.cmdList()
+		},
+		Exec: func(state *State, process string, args []string) int {
+			return cmdListNonInteractive(state, args)
+		},
+		Complete: completeWords("--json", "--format=", "--tag", "--ticket", "--verb", "--subject", "--due", "--archived", "--all"),
+	})
+	RegisterCommand(&Command{
+		Name:  "git-status",
+		Usage: "git-status [--all]",
+		Help:  "Refresh the git pulses and show the repository status",
+		Group: "Process Management",
+		Subcommands: []Subcommand{
+			{"git-status", "Refresh this process's git pulses and show branch, upstream, changes and stashes"},
+			{"git-status --all", "One-line git summary for every process in a repository"},
+		},
+		Exec: cmdGitStatusNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:  "jump",
+		Usage: "jump PROCESS [--json|--format=TMPL]",
+		Help:  "Jump to saved process location",
+		Group: "Process Management",
+		Run: func(sh *Shell, args []string) {
+			// With an output flag, describe the target instead of jumping
+			if hasOutputFlag(args) {
+				cmdJumpNonInteractive(sh.state, sh.currentProcess, args)
+				return
+			}
+			sh.cmdJump(args)
+		},
+		Exec: func(state *State, process string, args []string) int {
+			return cmdJumpNonInteractive(state, process, args)
+		},
+		Complete: completeProcesses,
+	})
+	RegisterCommand(&Command{
+		Name:  "rename",
+		Usage: "rename [OLD] NEW",
+		Help:  "Rename a process (this one if OLD is left out)",
+		Group: "Process Management",
+		Run: func(sh *Shell, args []string) {
+			old := sh.currentProcess
+			if name := cmdRename(sh.state, sh.currentProcess, args); name != "" && (len(args) == 1 || args[0] == old) {
+				sh.currentProcess = name
+				sh.displayName = name
+			}
+		},
+		Exec: func(state *State, process string, args []string) int {
+			name := cmdRename(state, process, args)
+			if name == "" {
+				return 1
+			}
+			if len(args) == 1 || args[0] == process {
+				parentAction("process", name)
+			}
+			return 0
+		},
+		Complete: completeProcesses,
+	})
+	RegisterCommand(&Command{
+		Name:     "rm",
+		Usage:    "rm NAME...",
+		Help:     "Delete saved processes (iptp rm; inside the iptp shell rm is the system rm)",
+		Group:    "Process Management",
+		Exec:     cmdRmNonInteractive,
+		Complete: completeProcesses,
+		CLIOnly:  true,
+	})
+	RegisterCommand(&Command{
+		Name:  "archive",
+		Usage: "archive NAME...",
+		Help:  "Put processes away: kept, but hidden from list and getmethere",
+		Group: "Process Management",
+		Exec: func(state *State, process string, args []string) int {
+			return cmdArchive(state, process, args, true)
+		},
+		Complete: completeProcesses,
+	})
+	RegisterCommand(&Command{
+		Name:  "unarchive",
+		Usage: "unarchive NAME...",
+		Help:  "Bring archived processes back",
+		Group: "Process Management",
+		Exec: func(state *State, process string, args []string) int {
+			return cmdArchive(state, process, args, false)
+		},
+		Complete: completeProcesses,
+	})
+	RegisterCommand(&Command{
+		Name:  "report",
+		Usage: "report [--since WHEN] [--until WHEN] [--format md|csv|json]",
+		Help:  "Hours per intention, day, directory and command, from the time tracked in iptp shells",
+		Group: "Process Management",
+		Subcommands: []Subcommand{
+			{"report", "This week so far, as Markdown"},
+			{"report --since monday|yesterday|7d|DATE", "From the start of that day (--until WHEN: to its end)"},
+			{"report --format csv", "One row per day and process, for timesheets"},
+			{"report --tag T / --ticket K", "Only intentions with these #tags or ticket"},
+		},
+		Exec:     cmdReportNonInteractive,
+		Complete: completeWords("--since", "--until", "--format", "--tag", "--ticket", "--json"),
+	})
+	RegisterCommand(&Command{
+		Name:  "who",
+		Usage: "who",
+		Help:  "List open iptp shells with their process, directory and idle time",
+		Group: "Process Management",
+		Exec:  cmdWhoNonInteractive,
+	})
+	RegisterCommand(&Command{
+		Name:     "send",
+		Usage:    "send NAME \"message\"",
+		Help:     "Show a message at the next prompt of NAME's shells",
+		Group:    "Process Management",
+		Exec:     cmdSendNonInteractive,
+		Complete: completeLiveShells,
+	})
+	RegisterCommand(&Command{
+		Name:     "pull",
+		Usage:    "pull NAME",
+		Help:     "Adopt another shell's directory and environment",
+		Group:    "Process Management",
+		Run:      (*Shell).cmdPull,
+		Complete: completeLiveShells,
+	})
+	RegisterCommand(&Command{
+		Name:     "spawn",
+		Usage:    "spawn NAME [--shell PATH]",
+		Help:     "Start a terminal session in NAME's directory (Ctrl-] d detaches)",
+		Group:    "Process Management",
+		Exec:     cmdSpawnNonInteractive,
+		Complete: completeProcesses,
+	})
+	RegisterCommand(&Command{
+		Name:     "switch",
+		Aliases:  []string{"attach"},
+		Usage:    "switch NAME",
+		Help:     "Attach to a running session (Ctrl-] n/p cycles sessions)",
+		Group:    "Process Management",
+		Exec:     cmdSwitchNonInteractive,
+		Complete: completeSessions,
+	})
+	RegisterCommand(&Command{
+		Name:  "sessions",
+		Usage: "sessions [kill NAME]",
+		Help:  "List terminal sessions, or end one",
+		Group: "Process Management",
+		Exec:  cmdSessionsNonInteractive,
+		Complete: func(state *State, args []string) []string {
+			if len(args) == 2 && args[0] == "kill" {
+				return completeSessions(state, args[1:])
+			}
+			return completeWords("kill")(state, args)
+		},
+	})
+	RegisterCommand(&Command{
+		Name:  "state",
+		Usage: "state [--json|--format=TMPL]",
+		Help:  "Show current state (IPTP format)",
+		Group: "Process Management",
+		Run: func(sh *Shell, args []string) {
+			if hasOutputFlag(args) {
+				cmdStateNonInteractive(sh.state, sh.currentProcess, args)
+				return
+			}
+			sh.cmdState()
+		},
+		Exec: func(state *State, process string, args []string) int {
+			return cmdStateNonInteractive(state, process, args)
+		},
+		Complete: completeWords("--json", "--format="),
+	})
+	RegisterCommand(&Command{
+		Name:  "record",
+		Usage: "record start|stop|list",
+		Help:  "Record this session (asciicast) into the current process",
+		Group: "Process Management",
+		Subcommands: []Subcommand{
+			{"record start", "Record input and output of this session"},
+			{"record stop", "Finish the recording and link it to the process"},
+			{"record list", "List recordings of the process (--all for every process)"},
+		},
+		Run: (*Shell).cmdRecord,
+		Exec: func(state *State, process string, args []string) int {
+			if len(args) == 0 || args[0] != "list" {
+				fmt.Println("Only 'record list' works outside the iptp shell")
+				return 1
+			}
+			return cmdRecordList(state, process, args[1:])
+		},
+	})
+	RegisterCommand(&Command{
+		Name:     "replay",
+		Usage:    "replay FILE|N [--speed 2]",
+		Help:     "Play back a recording (N from 'record list')",
+		Group:    "Process Management",
+		Exec:     cmdReplayNonInteractive,
+		Complete: completeFiles,
+	})
+
+	// System
+	RegisterCommand(&Command{
+		Name:  "time",
+		Usage: "time CMD [ARGS]",
+		Help:  "Run CMD and report real/user/sys time and max RSS",
+		Group: "System",
+		Run:   func(sh *Shell, args []string) { sh.cmdTime(literalWords(args)) },
+	})
+	RegisterCommand(&Command{
+		Name:  "stats",
+		Usage: "stats [--process NAME]",
+		Help:  "Slowest and most frequent commands (--since 7d, --top N)",
+		Group: "System",
+		Exec:  cmdStatsNonInteractive,
+		Complete: func(state *State, args []string) []string {
+			if len(args) >= 2 && (args[len(args)-2] == "--process" || args[len(args)-2] == "-p") {
+				return completeProcesses(state, args)
+			}
+			return completeWords("--process", "--since", "--top")(state, args[len(args)-1:])
+		},
+	})
+	RegisterCommand(&Command{
+		Name:  "plugins",
+		Usage: "plugins",
+		Help:  "List iptp-<name> plugins found on PATH",
+		Group: "System",
+		Run:   func(sh *Shell, args []string) { cmdPlugins() },
+		Exec: func(state *State, process string, args []string) int {
+			return cmdPlugins()
+		},
+	})
+	RegisterCommand(&Command{
+		Name:  "daemon",
+		Usage: "daemon start|stop|status|run|events",
+		Help:  "Control iptpd, the local state daemon",
+		Group: "System",
+		Subcommands: []Subcommand{
+			{"daemon start", "Start iptpd in the background"},
+			{"daemon stop", "Stop iptpd"},
+			{"daemon status", "Show whether iptpd is running"},
+			{"daemon run", "Run iptpd in the foreground"},
+			{"daemon events", "Print state events as JSON lines"},
+		},
+		Exec:     cmdDaemonNonInteractive,
+		Complete: completeWords("start", "stop", "status", "run", "events"),
+	})
+	RegisterCommand(&Command{
+		Name:    "help",
+		Aliases: []string{"--help", "-h"},
+		Usage:   "help",
+		Help:    "Show this help",
+		Group:   "System",
+		Run:     func(sh *Shell, args []string) { sh.cmdHelp() },
+		Exec: func(state *State, process string, args []string) int {
+			cmdHelpNonInteractive()
+			return 0
+		},
+	})
+	RegisterCommand(&Command{
+		Name:    "version",
+		Aliases: []string{"--version", "-v"},
+		Usage:   "version",
+		Help:    "Show version",
+		Group:   "System",
+		Exec: func(state *State, process string, args []string) int {
+			fmt.Println("iptp version 1.0.0")
+			fmt.Println("IPTP Shell Process Manager")
+			return 0
+		},
+	})
+	RegisterCommand(&Command{
+		Name:    "exit",
+		Aliases: []string{"quit"},
+		Usage:   "exit",
+		Help:    "Exit iptp",
+		Group:   "System",
+		Run:     func(sh *Shell, args []string) { sh.running = false },
+	})
+
+	RegisterCommand(&Command{
+		Name:     "init",
+		Usage:    "init bash|zsh|fish",
+		Help:     "Print shell integration so goto/jump/back move your shell",
+		Group:    "System",
+		Exec:     cmdInitNonInteractive,
+		Complete: completeWords("bash", "zsh", "fish"),
+	})
+
+	// Background server holding the terminal sessions (started by spawn)
+	RegisterCommand(&Command{
+		Name: "__sessiond",
+		Exec: func(state *State, process string, args []string) int {
+			if err := RunSessionServer(); err != nil {
+				fmt.Printf("✗ %v\n", err)
+				return 1
+			}
+			return 0
+		},
+	})
+
+	// Directory change hook for shell integration: iptp __chpwd NEW [OLD]
+	RegisterCommand(&Command{
+		Name: "__chpwd",
+		Exec: cmdChpwdNonInteractive,
+	})
+
+	// Environment report for directory hooks: iptp __hookenv
+	RegisterCommand(&Command{
+		Name: "__hookenv",
+		Exec: cmdHookEnvNonInteractive,
+	})
+
+	// Completion backend for shell integration: iptp __complete CMD PARTIAL
+	RegisterCommand(&Command{
+		Name: "__complete",
+		Exec: func(state *State, process string, args []string) int {
+			for _, candidate := range CompleteCommand(state, args) {
+				fmt.Println(candidate)
+			}
+			return 0
+		},
+	})
+}
@@ -0,0 +1,430 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Time reports
+//
+// 'report' sums the time tracked in iptp shells (see tracking.go) per
+// intention, per day, per directory, and counts the commands run, for
+// timesheets and standups. Markdown by default; csv gives one row per day and
+// process; json the whole report.
+
+// reportTop is how many directories and commands a report lists
+const reportTop = 10
+
+// Report is the JSON shape of 'report'
+type Report struct {
+	Since       string          `json:"since"` // RFC 3339
+	Until       string          `json:"until"` // RFC 3339, exclusive
+	Hours       float64         `json:"hours"`
+	Intentions  []ReportEntry   `json:"intentions"`
+	Days        []ReportDay     `json:"days"`
+	Directories []ReportPlace   `json:"directories"`
+	Commands    []ReportCommand `json:"commands"`
+}
+
+// ReportEntry is the time spent on one process
+type ReportEntry struct {
+	Process   string   `json:"process"`
+	Intention string   `json:"intention"`
+	Ticket    string   `json:"ticket"`
+	Tags      []string `json:"tags"`
+	Hours     float64  `json:"hours"`
+	seconds   int64
+}
+
+// ReportDay is the time spent on each process in one day
+type ReportDay struct {
+	Date      string        `json:"date"` // YYYY-MM-DD
+	Hours     float64       `json:"hours"`
+	Processes []ReportEntry `json:"processes"`
+}
+
+// ReportPlace is the time spent in one directory
+type ReportPlace struct {
+	Dir   string  `json:"dir"`
+	Hours float64 `json:"hours"`
+}
+
+// ReportCommand is how often a command was run
+type ReportCommand struct {
+	Command string `json:"command"`
+	Runs    int    `json:"runs"`
+}
+
+// hours turns seconds into hours, to the hundredth
+func hours(seconds int64) float64 {
+	return math.Round(float64(seconds)/36) / 100
+}
+
+// daysAgoPattern matches "7d": seven days ago
+var daysAgoPattern = regexp.MustCompile(`^(\d+)d$`)
+
+// parseWhen turns today, yesterday, a weekday (the last one, today
+// included), "last monday", week, "last week", month, 7d or YYYY-MM-DD into
+// the start of that day
+func parseWhen(value string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	word := strings.ToLower(strings.Join(strings.Fields(value), " "))
+	last := strings.HasPrefix(word, "last ")
+	word = strings.TrimPrefix(word, "last ")
+
+	var when time.Time
+	switch {
+	case word == "today":
+		when = today
+	case word == "yesterday":
+		when = today.AddDate(0, 0, -1)
+	case word == "week":
+		when = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	case word == "month":
+		when = time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		if last {
+			return when.AddDate(0, -1, 0), nil
+		}
+		return when, nil
+	default:
+		if day, ok := weekdays[word]; ok {
+			when = today.AddDate(0, 0, -(int(today.Weekday())-int(day)+7)%7)
+			break
+		}
+		if m := daysAgoPattern.FindStringSubmatch(word); m != nil && !last {
+			n, _ := strconv.Atoi(m[1])
+			return today.AddDate(0, 0, -n), nil
+		}
+		date, err := time.ParseInLocation("2006-01-02", word, now.Location())
+		if err != nil || last {
+			return time.Time{}, fmt.Errorf("can't read '%s' (try monday, yesterday, week, 7d or YYYY-MM-DD)", value)
+		}
+		return date, nil
+	}
+
+	if last {
+		when = when.AddDate(0, 0, -7)
+	}
+	return when, nil
+}
+
+// BuildReport sums the tracked time of the processes passing the filter
+// between since and until
+func BuildReport(state *State, since, until time.Time, filter IntentFilter) Report {
+	report := Report{
+		Since:       since.Format(time.RFC3339),
+		Until:       until.Format(time.RFC3339),
+		Intentions:  []ReportEntry{},
+		Days:        []ReportDay{},
+		Directories: []ReportPlace{},
+		Commands:    []ReportCommand{},
+	}
+
+	var total int64
+	byProcess := make(map[string]int64)
+	byDay := make(map[string]map[string]int64)
+	byDir := make(map[string]int64)
+	byCommand := make(map[string]int)
+
+	now := time.Now()
+	for _, name := range state.ListProcesses() {
+		proc, _ := state.GetProcess(name)
+		if !filter.Match(proc.Intent, now) {
+			continue
+		}
+		for _, span := range proc.Activity {
+			start, end, ok := span.Bounds()
+			if !ok || end.Before(since) || !start.Before(until) {
+				continue
+			}
+			if !start.Before(since) {
+				for command, runs := range span.Commands {
+					byCommand[command] += runs
+				}
+			}
+
+			// Clip to the period, then split at local midnight
+			start, end = start.In(since.Location()), end.In(since.Location())
+			if start.Before(since) {
+				start = since
+			}
+			if end.After(until) {
+				end = until
+			}
+			for start.Before(end) {
+				midnight := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+				stop := end
+				if midnight.Before(stop) {
+					stop = midnight
+				}
+				seconds := int64(stop.Sub(start).Seconds())
+				day := start.Format("2006-01-02")
+				if byDay[day] == nil {
+					byDay[day] = make(map[string]int64)
+				}
+				byDay[day][name] += seconds
+				byProcess[name] += seconds
+				byDir[span.Dir] += seconds
+				total += seconds
+				start = stop
+			}
+		}
+	}
+	report.Hours = hours(total)
+
+	entry := func(name string, seconds int64) ReportEntry {
+		proc, _ := state.GetProcess(name)
+		tags := proc.Intent.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		return ReportEntry{
+			Process:   name,
+			Intention: proc.Intention,
+			Ticket:    proc.Intent.Ticket,
+			Tags:      tags,
+			Hours:     hours(seconds),
+			seconds:   seconds,
+		}
+	}
+	for name, seconds := range byProcess {
+		if seconds > 0 {
+			report.Intentions = append(report.Intentions, entry(name, seconds))
+		}
+	}
+	sortReportEntries(report.Intentions)
+
+	for day, processes := range byDay {
+		reportDay := ReportDay{Date: day, Processes: []ReportEntry{}}
+		var daySeconds int64
+		for name, seconds := range processes {
+			if seconds > 0 {
+				reportDay.Processes = append(reportDay.Processes, entry(name, seconds))
+				daySeconds += seconds
+			}
+		}
+		if daySeconds == 0 {
+			continue
+		}
+		reportDay.Hours = hours(daySeconds)
+		sortReportEntries(reportDay.Processes)
+		report.Days = append(report.Days, reportDay)
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+
+	for dir, seconds := range byDir {
+		if seconds > 0 {
+			report.Directories = append(report.Directories, ReportPlace{Dir: dir, Hours: hours(seconds)})
+		}
+	}
+	sort.Slice(report.Directories, func(i, j int) bool {
+		a, b := report.Directories[i], report.Directories[j]
+		if a.Hours != b.Hours {
+			return a.Hours > b.Hours
+		}
+		return a.Dir < b.Dir
+	})
+	if len(report.Directories) > reportTop {
+		report.Directories = report.Directories[:reportTop]
+	}
+
+	for command, runs := range byCommand {
+		report.Commands = append(report.Commands, ReportCommand{Command: command, Runs: runs})
+	}
+	sort.Slice(report.Commands, func(i, j int) bool {
+		a, b := report.Commands[i], report.Commands[j]
+		if a.Runs != b.Runs {
+			return a.Runs > b.Runs
+		}
+		return a.Command < b.Command
+	})
+	if len(report.Commands) > reportTop {
+		report.Commands = report.Commands[:reportTop]
+	}
+	return report
+}
+
+// sortReportEntries puts the most time first
+func sortReportEntries(entries []ReportEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].seconds != entries[j].seconds {
+			return entries[i].seconds > entries[j].seconds
+		}
+		return entries[i].Process < entries[j].Process
+	})
+}
+
+// period is the report's dates for headings: "2026-10-19 – 2026-10-25"
+func (r Report) period() string {
+	since, err1 := time.Parse(time.RFC3339, r.Since)
+	until, err2 := time.Parse(time.RFC3339, r.Until)
+	if err1 != nil || err2 != nil {
+		return r.Since + " – " + r.Until
+	}
+	last := until.Add(-time.Second)
+	if last.Format("2006-01-02") == since.Format("2006-01-02") {
+		return since.Format("2006-01-02 (Mon)")
+	}
+	return since.Format("2006-01-02 (Mon)") + " – " + last.Format("2006-01-02 (Mon)")
+}
+
+// mdCell escapes a value for a Markdown table
+func mdCell(value string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(value, "|", `\|`), "\n", " ")
+}
+
+// writeReportMarkdown prints the report as Markdown
+func writeReportMarkdown(r Report) {
+	fmt.Printf("# Time report: %s\n\n", r.period())
+	if len(r.Intentions) == 0 {
+		fmt.Println("No time tracked. Time is tracked while you work in the iptp shell.")
+		return
+	}
+	fmt.Printf("Total: **%.2f h**\n\n", r.Hours)
+
+	fmt.Println("## Intentions")
+	fmt.Println()
+	fmt.Println("| Process | Intention | Ticket | Tags | Hours |")
+	fmt.Println("|---|---|---|---|---:|")
+	for _, e := range r.Intentions {
+		tags := ""
+		if len(e.Tags) > 0 {
+			tags = "#" + strings.Join(e.Tags, " #")
+		}
+		fmt.Printf("| %s | %s | %s | %s | %.2f |\n", mdCell(e.Process), mdCell(e.Intention), mdCell(e.Ticket), mdCell(tags), e.Hours)
+	}
+
+	fmt.Println()
+	fmt.Println("## By day")
+	fmt.Println()
+	fmt.Println("| Date | Hours | Worked on |")
+	fmt.Println("|---|---:|---|")
+	for _, day := range r.Days {
+		var worked []string
+		for _, e := range day.Processes {
+			worked = append(worked, fmt.Sprintf("%s %.2f", e.Process, e.Hours))
+		}
+		date := day.Date
+		if t, err := time.Parse("2006-01-02", day.Date); err == nil {
+			date += " (" + t.Format("Mon") + ")"
+		}
+		fmt.Printf("| %s | %.2f | %s |\n", date, day.Hours, mdCell(strings.Join(worked, ", ")))
+	}
+
+	fmt.Println()
+	fmt.Println("## Top directories")
+	fmt.Println()
+	fmt.Println("| Directory | Hours |")
+	fmt.Println("|---|---:|")
+	for _, place := range r.Directories {
+		fmt.Printf("| %s | %.2f |\n", mdCell(displayDir(place.Dir)), place.Hours)
+	}
+
+	if len(r.Commands) > 0 {
+		fmt.Println()
+		fmt.Println("## Top commands")
+		fmt.Println()
+		fmt.Println("| Command | Runs |")
+		fmt.Println("|---|---:|")
+		for _, c := range r.Commands {
+			fmt.Printf("| `%s` | %d |\n", mdCell(c.Command), c.Runs)
+		}
+	}
+}
+
+// writeReportCSV prints one row per day and process, for timesheets
+func writeReportCSV(r Report) int {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"date", "process", "intention", "ticket", "tags", "hours"})
+	for _, day := range r.Days {
+		for _, e := range day.Processes {
+			w.Write([]string{day.Date, e.Process, e.Intention, e.Ticket, strings.Join(e.Tags, " "), strconv.FormatFloat(e.Hours, 'f', 2, 64)})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// cmdReportNonInteractive handles 'report [--since WHEN] [--until WHEN]
+// [--format md|csv|json] [intention filters]'
+func cmdReportNonInteractive(state *State, process string, args []string) int {
+	now := time.Now()
+	since, _ := parseWhen("week", now)
+	until := now
+	style := "md"
+
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		flag, value, hasValue := strings.Cut(args[i], "=")
+		switch flag {
+		case "--since", "--until", "--format":
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return OutputUsageError(fmt.Errorf("%s needs a value", flag))
+			}
+			i++
+			value = args[i]
+		}
+
+		switch flag {
+		case "--since", "--until":
+			when, err := parseWhen(value, now)
+			if err != nil {
+				return OutputUsageError(fmt.Errorf("%s: %v", flag, err))
+			}
+			if flag == "--since" {
+				since = when
+			} else {
+				until = when.AddDate(0, 0, 1) // the whole day
+			}
+		case "--format":
+			if value == "md" || value == "csv" {
+				style = value
+			} else {
+				rest = append(rest, "--format="+value) // json or a template
+			}
+		}
+	}
+
+	format, rest, err := ParseOutputFlags(rest)
+	if err != nil {
+		return OutputUsageError(err)
+	}
+	filter, rest, err := parseIntentFilter(rest)
+	if err != nil {
+		return OutputUsageError(err)
+	}
+	if len(rest) > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: report [--since WHEN] [--until WHEN] [--format md|csv|json] [--tag T] [--ticket K]")
+		return 2
+	}
+	if !until.After(since) {
+		return OutputUsageError(fmt.Errorf("--until is before --since"))
+	}
+
+	report := BuildReport(state, since, until, filter)
+	switch {
+	case !format.Text():
+		return WriteOutput(format, report)
+	case style == "csv":
+		return writeReportCSV(report)
+	default:
+		writeReportMarkdown(report)
+		return 0
+	}
+}
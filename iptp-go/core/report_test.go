@@ -0,0 +1,67 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWhen(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC) // a Wednesday
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"today", "2026-10-14"},
+		{"Today", "2026-10-14"},
+		{"yesterday", "2026-10-13"},
+		{"wednesday", "2026-10-14"}, // today included
+		{"monday", "2026-10-12"},
+		{"thu", "2026-10-08"},
+		{"last monday", "2026-10-05"},
+		{"last  wednesday", "2026-10-07"},
+		{"week", "2026-10-12"},
+		{"last week", "2026-10-05"},
+		{"month", "2026-10-01"},
+		{"last month", "2026-09-01"},
+		{"7d", "2026-10-07"},
+		{"0d", "2026-10-14"},
+		{"2026-09-30", "2026-09-30"},
+	}
+	for _, tt := range tests {
+		got, err := parseWhen(tt.value, now)
+		if err != nil {
+			t.Errorf("parseWhen(%q): %v", tt.value, err)
+			continue
+		}
+		if got.Format("2006-01-02 15:04") != tt.want+" 00:00" {
+			t.Errorf("parseWhen(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "soon", "last 7d", "last 2026-09-30", "2026-13-01", "-3d"} {
+		if got, err := parseWhen(value, now); err == nil {
+			t.Errorf("parseWhen(%q) = %s, want an error", value, got)
+		}
+	}
+}
+
+func TestParseWhenOnSunday(t *testing.T) {
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC) // a Sunday
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"week", "2026-10-12"}, // weeks start on Monday
+		{"sunday", "2026-10-18"},
+		{"saturday", "2026-10-17"},
+		{"last sunday", "2026-10-11"},
+	}
+	for _, tt := range tests {
+		got, err := parseWhen(tt.value, now)
+		if err != nil || got.Format("2006-01-02") != tt.want {
+			t.Errorf("parseWhen(%q) = %s, %v, want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package core
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Resolving intentions to directories
+//
+// After 'name INTENTION', the intention's subject is looked for among the
+// saved processes, bookmarks, visited directories and the directory index:
+// "authentication" finds ~/src/auth and ~/src/authentication-service. The
+// likely places are offered, and the one chosen is remembered for that
+// subject, so naming the same intention again goes straight there.
+// intent_resolve = ask (default), always or never in ~/.iptprc.
+
+// resolveLimit is how many places are offered
+const resolveLimit = 3
+
+// Resolution is the directory learned for an intention's subject
+type Resolution struct {
+	Dir  string `json:"dir"`
+	Uses int    `json:"uses"`
+	Last string `json:"last"` // RFC 3339
+}
+
+// placeSourceBonus favours places the user saved or visited
+var placeSourceBonus = map[string]int{"process": 6, "bookmark": 5, "recent": 3}
+
+// intentPlace is a directory that may be where an intention is worked on
+type intentPlace struct {
+	Dir    string
+	Source string // "learned", "process", "bookmark", "recent" or "dir"
+	Name   string // process or bookmark name
+	Score  int
+}
+
+// resolutionKey is what learned places are remembered by
+func resolutionKey(intent Intent) string {
+	key := intent.Subject
+	if key == "" {
+		key = intent.Ticket
+	}
+	return strings.ToLower(strings.Join(strings.Fields(key), " "))
+}
+
+// Learn remembers dir as the place for an intention
+func (s *State) Learn(intent Intent, dir string) {
+	key := resolutionKey(intent)
+	if key == "" {
+		return
+	}
+	if s.Resolutions == nil {
+		s.Resolutions = make(map[string]Resolution)
+	}
+	resolution := s.Resolutions[key]
+	if resolution.Dir != dir {
+		resolution = Resolution{Dir: dir}
+	}
+	resolution.Uses++
+	resolution.Last = time.Now().Format(time.RFC3339)
+	s.Resolutions[key] = resolution
+}
+
+// nameTokens splits a directory or process name into lower-case words:
+// "authService_v2" → auth, service, v2
+func nameTokens(name string) []string {
+	var tokens []string
+	var current []rune
+	prev := rune(0)
+	for _, r := range name {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			if len(current) > 0 {
+				tokens = append(tokens, strings.ToLower(string(current)))
+			}
+			current = nil
+		case unicode.IsUpper(r) && unicode.IsLower(prev) && len(current) > 0:
+			tokens = append(tokens, strings.ToLower(string(current)))
+			current = []rune{r}
+		default:
+			current = append(current, r)
+		}
+		prev = r
+	}
+	if len(current) > 0 {
+		tokens = append(tokens, strings.ToLower(string(current)))
+	}
+	return tokens
+}
+
+// placeScore scores a name against the subject's words; 0 if no word
+// matches. Words and name tokens match exactly, or when one is a prefix of
+// the other ("auth" and "authentication").
+func placeScore(words []string, name string) int {
+	tokens := nameTokens(name)
+	score, matched := 0, 0
+	for _, word := range words {
+		best := 0
+		for _, token := range tokens {
+			switch {
+			case token == word:
+				best = max(best, 12)
+			case len(token) >= 3 && len(word) >= 3 &&
+				(strings.HasPrefix(word, token) || strings.HasPrefix(token, word)):
+				best = max(best, 6)
+			case len(word) >= 4 && strings.Contains(token, word):
+				best = max(best, 4)
+			}
+		}
+		if best > 0 {
+			matched++
+		}
+		score += best
+	}
+	if matched == 0 {
+		return 0
+	}
+	// Names that are nothing but the subject beat those with more in them
+	if matched == len(words) && len(tokens) == len(words) {
+		score += 4
+	}
+	return score
+}
+
+// subjectWords are the words of the subject worth matching
+func subjectWords(intent Intent) []string {
+	var words []string
+	for _, word := range nameTokens(intent.Subject) {
+		if len(word) >= 2 && !containsString(words, word) {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 && intent.Ticket != "" {
+		words = nameTokens(intent.Ticket)[:1] // the project key: ABC of ABC-123
+	}
+	return words
+}
+
+// ResolveIntent finds the likely directories for an intention, best first
+// A learned place comes back alone
+func ResolveIntent(state *State, process string, intent Intent) []intentPlace {
+	if resolution, ok := state.Resolutions[resolutionKey(intent)]; ok && isDirectory(resolution.Dir) {
+		return []intentPlace{{Dir: resolution.Dir, Source: "learned"}}
+	}
+
+	words := subjectWords(intent)
+	if len(words) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	best := make(map[string]intentPlace)
+	consider := func(item pickerItem) {
+		if item.Kind == "process" && item.Name == process {
+			return // the process being named is here already
+		}
+		score := placeScore(words, filepath.Base(item.Dir))
+		if item.Name != "" {
+			score = max(score, placeScore(words, item.Name))
+		}
+		if score == 0 {
+			return
+		}
+		score += placeSourceBonus[item.Kind]
+		if visit, ok := state.Directories[item.Dir]; ok {
+			score += int(math.Min(frecencyScore(visit, now), 40)) / 4
+		}
+		if current, ok := best[item.Dir]; !ok || score > current.Score {
+			best[item.Dir] = intentPlace{Dir: item.Dir, Source: item.Kind, Name: item.Name, Score: score}
+		}
+	}
+	for _, item := range savedPickerItems(state) {
+		consider(item)
+	}
+	for _, item := range rootPickerItems() {
+		consider(item)
+	}
+
+	places := make([]intentPlace, 0, len(best))
+	for _, place := range best {
+		if isDirectory(place.Dir) {
+			places = append(places, place)
+		}
+	}
+	sort.Slice(places, func(i, j int) bool {
+		if places[i].Score != places[j].Score {
+			return places[i].Score > places[j].Score
+		}
+		if len(places[i].Dir) != len(places[j].Dir) {
+			return len(places[i].Dir) < len(places[j].Dir)
+		}
+		return places[i].Dir < places[j].Dir
+	})
+	if len(places) > resolveLimit {
+		places = places[:resolveLimit]
+	}
+	return places
+}
+
+// label is how a place is offered
+func (place intentPlace) label() string {
+	switch place.Source {
+	case "process":
+		return fmt.Sprintf("%s  (process %s)", displayDir(place.Dir), place.Name)
+	case "bookmark":
+		return fmt.Sprintf("%s  (@%s)", displayDir(place.Dir), place.Name)
+	case "recent":
+		return displayDir(place.Dir) + "  (visited)"
+	}
+	return displayDir(place.Dir)
+}
+
+// chooseIntentPlace picks where to go after naming; ok is false to stay
+// intent_resolve = ask (default), always or never in ~/.iptprc
+func chooseIntentPlace(reader *bufio.Reader, config *Config, state *State, process string, intent Intent, interactive bool) (string, bool) {
+	mode := "ask"
+	if config != nil {
+		if value, ok := config.Get("intent_resolve"); ok {
+			mode = value
+		}
+	}
+	if mode == "never" {
+		return "", false
+	}
+
+	cwd, _ := os.Getwd()
+	places := ResolveIntent(state, process, intent)
+	if len(places) == 0 || places[0].Dir == cwd {
+		return "", false
+	}
+	if places[0].Source == "learned" || mode == "always" {
+		return places[0].Dir, true
+	}
+	if !interactive {
+		return "", false
+	}
+
+	if len(places) == 1 {
+		fmt.Printf("Go to %s? [Y/n] ", places[0].label())
+	} else {
+		fmt.Printf("'%s' may be in:\n", intent.Subject)
+		for i, place := range places {
+			fmt.Printf("  %d) %s\n", i+1, place.label())
+		}
+		fmt.Printf("Go there? [1-%d/n] ", len(places))
+	}
+
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	switch answer {
+	case "", "y", "yes":
+		return places[0].Dir, true
+	case "n", "no", "q":
+		return "", false
+	}
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(places) {
+		return places[n-1].Dir, true
+	}
+	fmt.Println("✗ Invalid selection")
+	return "", false
+}
+
+// resolveIntentNonInteractive moves the calling shell after 'iptp name'
+// Asks only when stdin is a terminal
+func resolveIntentNonInteractive(state *State, process string, intent Intent) {
+	config, _ := LoadConfig(getConfigFilePath())
+	dir, ok := chooseIntentPlace(bufio.NewReader(os.Stdin), config, state, process, intent, isTerminal(os.Stdin))
+	if !ok {
+		return
+	}
+	state.Learn(intent, dir)
+	oldDir, _ := os.Getwd()
+	fmt.Printf("✓ Changed to: %s\n", dir)
+	moveParent(state, process, dir, oldDir)
+}
+
+// parseHereFlag strips --here from the arguments of 'name'
+func parseHereFlag(args []string) (bool, []string) {
+	here := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--here" {
+			here = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	return here, rest
+}
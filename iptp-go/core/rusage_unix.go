@@ -0,0 +1,22 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// maxRSSKB returns the peak resident set size of a finished child in KB
+func maxRSSKB(ps *os.ProcessState) int64 {
+	usage, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok || usage == nil {
+		return 0
+	}
+	// Linux reports kilobytes, macOS and the BSDs report bytes
+	if runtime.GOOS == "darwin" {
+		return int64(usage.Maxrss) / 1024
+	}
+	return int64(usage.Maxrss)
+}
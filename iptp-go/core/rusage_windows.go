@@ -0,0 +1,10 @@
+//go:build windows
+
+package core
+
+import "os"
+
+// maxRSSKB is not available from the Windows process state
+func maxRSSKB(ps *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,750 @@
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Terminal sessions
+//
+// 'spawn NAME' starts a shell on a pseudo-terminal owned by the session
+// server (iptp __sessiond), a background process that outlives iptp. The
+// terminal attaches to one session at a time; Ctrl-] then d detaches and
+// Ctrl-] then n/p switches to the next/previous session.
+
+// sessionPrefixKey starts a session hotkey (Ctrl-])
+const sessionPrefixKey = 0x1d
+
+// sessionScrollback is how much output is replayed when attaching
+const sessionScrollback = 64 * 1024
+
+// sessionWriteTimeout drops an attached terminal that stops reading
+const sessionWriteTimeout = 5 * time.Second
+
+// SessionInfo links a process to its terminal session
+type SessionInfo struct {
+	PID     int    `json:"pid"` // the session's shell
+	Shell   string `json:"shell"`
+	Started string `json:"started"`
+}
+
+// sessionAlive reports whether a process has a running terminal session
+func sessionAlive(proc Process) bool {
+	return proc.Session != nil && processAlive(proc.Session.PID)
+}
+
+// SetSession records (or with nil, clears) a process's terminal session
+func (s *State) SetSession(name string, session *SessionInfo) bool {
+	proc, ok := s.Processes[name]
+	if !ok {
+		return false
+	}
+	proc.Session = session
+	s.Processes[name] = proc
+	return true
+}
+
+// sessionRequest is the first line a client sends to the session server
+type sessionRequest struct {
+	Op    string   `json:"op"` // spawn, attach, list, kill
+	Name  string   `json:"name,omitempty"`
+	Dir   string   `json:"dir,omitempty"`
+	Shell string   `json:"shell,omitempty"`
+	Env   []string `json:"env,omitempty"`
+	Rows  int      `json:"rows,omitempty"`
+	Cols  int      `json:"cols,omitempty"`
+}
+
+// sessionReply answers a request; attach and spawn then stream output
+type sessionReply struct {
+	OK       bool            `json:"ok"`
+	Error    string          `json:"error,omitempty"`
+	PID      int             `json:"pid,omitempty"`
+	Created  bool            `json:"created,omitempty"`
+	Sessions []sessionStatus `json:"sessions,omitempty"`
+}
+
+// sessionStatus describes one session for 'sessions'
+type sessionStatus struct {
+	Name     string `json:"name"`
+	PID      int    `json:"pid"`
+	Started  string `json:"started"`
+	Attached bool   `json:"attached"`
+}
+
+// Client-to-server frames after attaching: type byte, uint32 length, payload
+const (
+	frameData   = 'd' // keyboard input
+	frameResize = 'w' // rows, cols as two uint16
+)
+
+// getSessionSocketPath returns the socket of the session server, inside the
+// private runtime directory so no other user can put one in its place
+func getSessionSocketPath() (string, error) {
+	dir, err := getRuntimeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sessions.sock"), nil
+}
+
+// ===== Server =====
+
+// ptySession is a shell running on a pty inside the session server
+type ptySession struct {
+	name       string
+	master     *os.File
+	cmd        *exec.Cmd
+	started    time.Time
+	mu         sync.Mutex // guards scrollback and client
+	scrollback []byte
+	client     *sessionClient
+}
+
+// sessionClient is the terminal attached to a session
+// Writes happen outside ptySession.mu, so a slow terminal never blocks
+// 'list' or another attach; mu keeps the replay ahead of live output
+type sessionClient struct {
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+// write sends output, closing the connection if the terminal stalls
+func (c *sessionClient) write(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(sessionWriteTimeout))
+	_, err := c.conn.Write(data)
+	if err != nil {
+		c.conn.Close()
+	}
+	return err
+}
+
+// sessionServer owns every session
+type sessionServer struct {
+	mu       sync.Mutex
+	sessions map[string]*ptySession
+	listener net.Listener
+}
+
+// RunSessionServer serves sessions until the last one exits
+func RunSessionServer() error {
+	socketPath, err := getSessionSocketPath()
+	if err != nil {
+		return err
+	}
+	if conn, err := net.DialTimeout("unix", socketPath, daemonDialTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("session server already running on %s", socketPath)
+	}
+	os.Remove(socketPath)
+
+	listener, err := listenUnixPrivate(socketPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socketPath)
+
+	srv := &sessionServer{sessions: make(map[string]*ptySession), listener: listener}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return nil
+		}
+		go srv.serve(conn)
+	}
+}
+
+// serve handles one client connection
+func (srv *sessionServer) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return
+	}
+	var req sessionRequest
+	if err := json.Unmarshal(line, &req); err != nil {
+		writeSessionReply(conn, sessionReply{Error: "bad request"})
+		conn.Close()
+		return
+	}
+
+	switch req.Op {
+	case "list":
+		writeSessionReply(conn, sessionReply{OK: true, Sessions: srv.list()})
+		conn.Close()
+	case "kill":
+		srv.mu.Lock()
+		sess, ok := srv.sessions[req.Name]
+		srv.mu.Unlock()
+		if !ok {
+			writeSessionReply(conn, sessionReply{Error: fmt.Sprintf("no session '%s'", req.Name)})
+		} else {
+			sess.cmd.Process.Kill()
+			writeSessionReply(conn, sessionReply{OK: true})
+		}
+		conn.Close()
+	case "spawn", "attach":
+		srv.mu.Lock()
+		sess, ok := srv.sessions[req.Name]
+		srv.mu.Unlock()
+
+		created := false
+		if !ok {
+			if req.Op == "attach" {
+				writeSessionReply(conn, sessionReply{Error: fmt.Sprintf("no session '%s'", req.Name)})
+				conn.Close()
+				return
+			}
+			if sess, err = srv.spawn(req); err != nil {
+				writeSessionReply(conn, sessionReply{Error: err.Error()})
+				conn.Close()
+				srv.closeIfIdle()
+				return
+			}
+			created = true
+		}
+		srv.attach(sess, conn, reader, req, created)
+	default:
+		writeSessionReply(conn, sessionReply{Error: "unknown op: " + req.Op})
+		conn.Close()
+	}
+}
+
+// spawn starts a shell on a new pty
+func (srv *sessionServer) spawn(req sessionRequest) (*ptySession, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	defer slave.Close()
+	if req.Rows > 0 && req.Cols > 0 {
+		setWinsize(master, req.Rows, req.Cols)
+	}
+
+	cmd := exec.Command(req.Shell)
+	cmd.Dir = req.Dir
+	cmd.Env = req.Env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+	cmd.SysProcAttr = sessionProcAttr()
+	if err := cmd.Start(); err != nil {
+		master.Close()
+		return nil, err
+	}
+
+	sess := &ptySession{name: req.Name, master: master, cmd: cmd, started: time.Now()}
+	srv.mu.Lock()
+	srv.sessions[req.Name] = sess
+	srv.mu.Unlock()
+
+	go srv.pump(sess)
+	return sess, nil
+}
+
+// pump copies pty output to the scrollback and the attached client
+func (srv *sessionServer) pump(sess *ptySession) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := sess.master.Read(buf)
+		if n > 0 {
+			sess.mu.Lock()
+			sess.scrollback = append(sess.scrollback, buf[:n]...)
+			if len(sess.scrollback) > sessionScrollback {
+				sess.scrollback = append([]byte{}, sess.scrollback[len(sess.scrollback)-sessionScrollback:]...)
+			}
+			client := sess.client
+			sess.mu.Unlock()
+
+			if client != nil && client.write(buf[:n]) != nil {
+				sess.mu.Lock()
+				if sess.client == client {
+					sess.client = nil
+				}
+				sess.mu.Unlock()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	// The shell exited
+	sess.cmd.Wait()
+	sess.master.Close()
+	sess.mu.Lock()
+	if sess.client != nil {
+		sess.client.conn.Close()
+		sess.client = nil
+	}
+	sess.mu.Unlock()
+
+	srv.mu.Lock()
+	delete(srv.sessions, sess.name)
+	srv.mu.Unlock()
+	srv.closeIfIdle()
+}
+
+// closeIfIdle stops the server once it has no sessions, including when the
+// first spawn fails
+func (srv *sessionServer) closeIfIdle() {
+	srv.mu.Lock()
+	empty := len(srv.sessions) == 0
+	srv.mu.Unlock()
+	if empty {
+		srv.listener.Close()
+	}
+}
+
+// attach makes conn the session's terminal, replacing any previous one
+func (srv *sessionServer) attach(sess *ptySession, conn net.Conn, reader *bufio.Reader, req sessionRequest, created bool) {
+	client := &sessionClient{conn: conn}
+	client.mu.Lock()
+	sess.mu.Lock()
+	if sess.client != nil {
+		sess.client.conn.Close()
+	}
+	sess.client = client
+	replay := append([]byte{}, sess.scrollback...)
+	sess.mu.Unlock()
+
+	// pump waits on client.mu, so the replay goes out before live output
+	conn.SetWriteDeadline(time.Now().Add(sessionWriteTimeout))
+	writeSessionReply(conn, sessionReply{OK: true, PID: sess.cmd.Process.Pid, Created: created})
+	conn.Write(replay)
+	client.mu.Unlock()
+
+	if req.Rows > 0 && req.Cols > 0 {
+		setWinsize(sess.master, req.Rows, req.Cols)
+	}
+
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			break
+		}
+		payload := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		if _, err := io.ReadFull(reader, payload); err != nil {
+			break
+		}
+		switch header[0] {
+		case frameData:
+			sess.master.Write(payload)
+		case frameResize:
+			if len(payload) == 4 {
+				setWinsize(sess.master, int(binary.BigEndian.Uint16(payload)), int(binary.BigEndian.Uint16(payload[2:])))
+			}
+		}
+	}
+
+	sess.mu.Lock()
+	if sess.client == client {
+		sess.client = nil
+	}
+	sess.mu.Unlock()
+	conn.Close()
+}
+
+// list describes the sessions, sorted by name
+func (srv *sessionServer) list() []sessionStatus {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	var statuses []sessionStatus
+	for name, sess := range srv.sessions {
+		sess.mu.Lock()
+		statuses = append(statuses, sessionStatus{
+			Name:     name,
+			PID:      sess.cmd.Process.Pid,
+			Started:  sess.started.Format(time.RFC3339),
+			Attached: sess.client != nil,
+		})
+		sess.mu.Unlock()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// writeSessionReply sends a reply line
+func writeSessionReply(w io.Writer, reply sessionReply) {
+	data, _ := json.Marshal(reply)
+	w.Write(append(data, '\n'))
+}
+
+// ===== Client =====
+
+// dialSessions connects to the session server, starting it if needed
+func dialSessions(start bool) (net.Conn, error) {
+	socketPath, err := getSessionSocketPath()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialOwnSocket(socketPath)
+	if err == nil || !start {
+		return conn, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(exe, "__sessiond")
+	cmd.SysProcAttr = detachedProcAttr()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	cmd.Process.Release()
+
+	for i := 0; i < 20; i++ {
+		time.Sleep(50 * time.Millisecond)
+		if conn, err = dialOwnSocket(socketPath); err == nil {
+			return conn, nil
+		}
+	}
+	return nil, fmt.Errorf("session server did not start")
+}
+
+// dialOwnSocket connects to a Unix socket after checking this user created it
+func dialOwnSocket(path string) (net.Conn, error) {
+	if err := checkSocketOwner(path); err != nil {
+		return nil, err
+	}
+	return net.DialTimeout("unix", path, daemonDialTimeout)
+}
+
+// sessionCall sends a request and reads the reply line
+// The returned reader holds any output that followed the reply
+func sessionCall(conn net.Conn, req sessionRequest) (sessionReply, *bufio.Reader, error) {
+	var reply sessionReply
+	data, _ := json.Marshal(req)
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return reply, nil, err
+	}
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil {
+		return reply, nil, err
+	}
+	if err := json.Unmarshal(line, &reply); err != nil {
+		return reply, nil, err
+	}
+	if !reply.OK {
+		return reply, nil, fmt.Errorf("%s", reply.Error)
+	}
+	return reply, reader, nil
+}
+
+// listSessions asks the server for its sessions (none if it isn't running)
+func listSessions() []sessionStatus {
+	conn, err := dialSessions(false)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	reply, _, err := sessionCall(conn, sessionRequest{Op: "list"})
+	if err != nil {
+		return nil
+	}
+	return reply.Sessions
+}
+
+// Results of an attached terminal session
+const (
+	sessionDetached = iota
+	sessionEnded
+	sessionNext
+	sessionPrev
+)
+
+// runSession attaches the terminal to a session until detach, switch or exit
+func runSession(state *State, req sessionRequest) (int, error) {
+	conn, err := dialSessions(req.Op == "spawn")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	cols, rows := terminalSize(os.Stdin)
+	req.Rows, req.Cols = rows, cols
+	reply, reader, err := sessionCall(conn, req)
+	if err != nil {
+		return 0, err
+	}
+
+	if reply.Created {
+		// Record the session on the process so 'list' and 'state' show it
+		if state.SetSession(req.Name, &SessionInfo{PID: reply.PID, Shell: req.Shell, Started: time.Now().Format(time.RFC3339)}) {
+			state.Save()
+		}
+	}
+
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		return 0, err
+	}
+	defer restore()
+
+	fmt.Printf("[%s — Ctrl-] d detach, Ctrl-] n/p switch]\r\n", req.Name)
+
+	var writeMu sync.Mutex
+	send := func(kind byte, payload []byte) error {
+		frame := make([]byte, 5+len(payload))
+		frame[0] = kind
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+		copy(frame[5:], payload)
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_, err := conn.Write(frame)
+		return err
+	}
+
+	stopResize := watchResize(func() {
+		cols, rows := terminalSize(os.Stdin)
+		size := make([]byte, 4)
+		binary.BigEndian.PutUint16(size, uint16(rows))
+		binary.BigEndian.PutUint16(size[2:], uint16(cols))
+		send(frameResize, size)
+	})
+	defer stopResize()
+
+	ended := make(chan struct{})
+	go func() {
+		io.Copy(os.Stdout, reader)
+		close(ended)
+	}()
+
+	// Raw mode reads time out, so the loop also notices the session ending
+	buf := make([]byte, 1024)
+	prefix := false
+	for {
+		select {
+		case <-ended:
+			return sessionEnded, nil
+		default:
+		}
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil && err != io.EOF {
+			return sessionDetached, nil
+		}
+
+		var input []byte
+		for _, b := range buf[:n] {
+			if prefix {
+				prefix = false
+				switch b {
+				case 'd', 'D':
+					conn.Close()
+					<-ended
+					return sessionDetached, nil
+				case 'n', 'N':
+					conn.Close()
+					<-ended
+					return sessionNext, nil
+				case 'p', 'P':
+					conn.Close()
+					<-ended
+					return sessionPrev, nil
+				case sessionPrefixKey:
+					input = append(input, b)
+				}
+				continue
+			}
+			if b == sessionPrefixKey {
+				prefix = true
+				continue
+			}
+			input = append(input, b)
+		}
+		if len(input) > 0 {
+			if err := send(frameData, input); err != nil {
+				<-ended
+				return sessionEnded, nil
+			}
+		}
+	}
+}
+
+// attachLoop attaches to name and follows switches until the user detaches
+func attachLoop(state *State, req sessionRequest) int {
+	if !ptySupported {
+		fmt.Println("✗ Terminal sessions are not supported on this platform")
+		return 1
+	}
+	if !isTerminal(os.Stdin) {
+		fmt.Println("✗ Sessions need a terminal")
+		return 1
+	}
+
+	for {
+		result, err := runSession(state, req)
+		if err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+
+		switch result {
+		case sessionDetached:
+			fmt.Printf("\n[detached from %s]\n", req.Name)
+			return 0
+		case sessionEnded:
+			for _, s := range listSessions() {
+				if s.Name == req.Name {
+					fmt.Printf("\n[%s was attached from another terminal]\n", req.Name)
+					return 0
+				}
+			}
+			fmt.Printf("\n[session %s ended]\n", req.Name)
+			if proc, ok := state.GetProcess(req.Name); ok && proc.Session != nil {
+				state.SetSession(req.Name, nil)
+				state.Save()
+			}
+			return 0
+		}
+
+		// Switch to the neighbouring session
+		sessions := listSessions()
+		if len(sessions) == 0 {
+			fmt.Println("\n[no sessions]")
+			return 0
+		}
+		i := 0
+		for j, s := range sessions {
+			if s.Name == req.Name {
+				i = j
+			}
+		}
+		if result == sessionNext {
+			i = (i + 1) % len(sessions)
+		} else {
+			i = (i - 1 + len(sessions)) % len(sessions)
+		}
+		req = sessionRequest{Op: "attach", Name: sessions[i].Name}
+		fmt.Print("\r\n")
+	}
+}
+
+// ===== Commands =====
+
+// cmdSpawnNonInteractive starts (or reattaches to) a session for a process
+// spawn NAME [--shell PATH]
+func cmdSpawnNonInteractive(state *State, process string, args []string) int {
+	var name string
+	shell := os.Getenv("SHELL")
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--shell":
+			if i+1 < len(args) {
+				shell = args[i+1]
+				i++
+			}
+		default:
+			name = args[i]
+		}
+	}
+	if name == "" {
+		fmt.Println("Usage: spawn NAME [--shell PATH]")
+		return 1
+	}
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	// The session starts in the process's directory, creating the process if needed
+	proc, ok := state.GetProcess(name)
+	if !ok {
+		dir, _ := os.Getwd()
+		state.SetProcess(name, "Working in "+name, dir)
+		state.Save()
+		proc, _ = state.GetProcess(name)
+	}
+
+	env := append(os.Environ(), "iptp_PROCESS="+name)
+	return attachLoop(state, sessionRequest{
+		Op:    "spawn",
+		Name:  name,
+		Dir:   proc.CurrentDir,
+		Shell: shell,
+		Env:   env,
+	})
+}
+
+// cmdSwitchNonInteractive attaches the terminal to an existing session
+func cmdSwitchNonInteractive(state *State, process string, args []string) int {
+	if len(args) == 0 {
+		fmt.Println("Usage: switch NAME")
+		return 1
+	}
+	return attachLoop(state, sessionRequest{Op: "attach", Name: args[0]})
+}
+
+// cmdSessionsNonInteractive lists sessions, or kills one with 'sessions kill NAME'
+func cmdSessionsNonInteractive(state *State, process string, args []string) int {
+	if len(args) >= 2 && args[0] == "kill" {
+		conn, err := dialSessions(false)
+		if err != nil {
+			fmt.Println("No sessions")
+			return 1
+		}
+		defer conn.Close()
+		if _, _, err := sessionCall(conn, sessionRequest{Op: "kill", Name: args[1]}); err != nil {
+			fmt.Printf("✗ %v\n", err)
+			return 1
+		}
+		fmt.Printf("✓ Killed session %s\n", args[1])
+		return 0
+	}
+
+	sessions := listSessions()
+	if len(sessions) == 0 {
+		fmt.Println("No sessions (start one with 'spawn NAME')")
+		return 0
+	}
+
+	fmt.Println("=== Sessions ===")
+	for _, s := range sessions {
+		attached := ""
+		if s.Attached {
+			attached = "  (attached)"
+		}
+		fmt.Printf("  ⧉ %-20s PID %-7d since %s%s\n", s.Name, s.PID, formatTimestamp(s.Started), attached)
+	}
+	return 0
+}
+
+// printSession adds the process's terminal session to 'state' output
+func printSession(proc Process) {
+	if proc.Session == nil {
+		return
+	}
+	status := "✗ ended"
+	if sessionAlive(proc) {
+		status = "⧉ alive ('switch' to attach)"
+	}
+	fmt.Println()
+	fmt.Println("=== Session ===")
+	fmt.Printf("  %s  PID %d  %s  since %s\n", status, proc.Session.PID, proc.Session.Shell, formatTimestamp(proc.Session.Started))
+}
+
+// completeSessions completes the first argument as a session name
+func completeSessions(state *State, args []string) []string {
+	if len(args) > 1 {
+		return nil
+	}
+	var names []string
+	for _, s := range listSessions() {
+		names = append(names, s.Name)
+	}
+	return completeWords(names...)(state, args)
+}
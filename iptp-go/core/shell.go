@@ -0,0 +1,539 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Global counter for unnamed shells
+var iptpCounter int32 = 0
+
+// Shell represents the interactive shell
+type Shell struct {
+	state          *State
+	currentProcess string
+	displayName    string // For prompt display
+	config         *Config
+	reader         *bufio.Reader
+	running        bool
+	lastExit       int // Exit code of the last external command
+
+	recorder         *Recorder     // Active session recording, if any
+	recordingProcess string        // Process the recording belongs to
+	terminalReader   *bufio.Reader // sh.reader while a recording replaces it
+
+	started        time.Time       // For 'who'
+	lastActive     time.Time       // Last command entered, for idle time in 'who'
+	presenceWarned bool            // The shells directory was refused once already
+	tracker        activityTracker // Time not yet credited to the process
+
+	gitRefresh chan gitRefresh // Background 'git status', nil if none is running
+}
+
+// NewShell creates a new interactive shell
+func NewShell(state *State, config *Config) *Shell {
+	processName := fmt.Sprintf("shell_%d", os.Getpid())
+	
+	// Generate IPTP-n name for display
+	n := atomic.AddInt32(&iptpCounter, 1)
+	displayName := fmt.Sprintf("IPTP-%d", n)
+	
+	return &Shell{
+		state:          state,
+		currentProcess: processName,
+		displayName:    displayName,
+		config:         config,
+		reader:         bufio.NewReader(os.Stdin),
+		running:        true,
+		started:        time.Now(),
+		lastActive:     time.Now(),
+	}
+}
+
+// Input is where the shell reads lines from; commands read answers to their
+// questions from it too
+func (sh *Shell) Input() *bufio.Reader {
+	return sh.reader
+}
+
+// Run starts the REPL loop
+func (sh *Shell) Run() {
+	// Initialize current process
+	currentDir, _ := os.Getwd()
+	sh.state.SetProcess(sh.currentProcess, "Working in "+sh.currentProcess, currentDir)
+	sh.state.Save()
+	if sh.state.daemon == nil {
+		refreshIndexInBackground()
+	}
+	sh.tracker = newActivityTracker(sh.config, sh.currentProcess, currentDir)
+
+	for sh.running {
+		sh.trackCommand()
+		sh.flushActivity(false)
+		sh.applyGitRefresh()
+		sh.state.Sync()
+		sh.refreshGitInBackground()
+		sh.deliverMessages()
+		sh.updatePresence()
+
+		// Show prompt from the rc file template (default: [name] dir$ )
+		fmt.Print(sh.renderPrompt())
+
+		// Read input
+		line, err := sh.reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+
+		// Parse and execute command
+		line = strings.TrimSpace(line)
+		sh.lastActive = time.Now()
+		sh.trackTyped(line)
+		if sh.recorder != nil {
+			sh.recorder.Input(line)
+		}
+		if line == "" {
+			continue
+		}
+
+		sh.executeCommand(line)
+	}
+
+	if sh.recorder != nil {
+		rec := sh.stopRecording()
+		fmt.Printf("\n✓ Saved recording: %s", rec.File)
+	}
+
+	sh.trackCommand()
+	sh.flushActivity(true)
+	sh.removePresence()
+	fmt.Println("\nGoodbye!")
+}
+
+// getCurrentDirName returns just the current directory name (not full path)
+func (sh *Shell) getCurrentDirName() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "?"
+	}
+
+	// Check if we're at home directory
+	home, _ := os.UserHomeDir()
+	if dir == home {
+		return "~"
+	}
+
+	// Return just the directory name
+	return filepath.Base(dir)
+}
+
+// executeCommand parses and executes a command
+func (sh *Shell) executeCommand(line string) {
+	words, err := SplitCommandLine(line)
+	if err != nil {
+		fmt.Printf("✗ Syntax error: %v\n", err)
+		sh.lastExit = 2
+		return
+	}
+	if len(words) == 0 {
+		return
+	}
+
+	// time is a keyword so the timed command keeps its unexpanded words
+	if words[0].Text == "time" && !words[0].Quoted {
+		sh.cmdTime(words[1:])
+		return
+	}
+
+	// Builtins see quote-removed words; only external commands get globbing
+	parts := wordTexts(words)
+	cmd := parts[0]
+	args := parts[1:]
+
+	if command, ok := LookupBuiltin(cmd); ok {
+		sh.runBuiltin(command, args)
+		return
+	}
+
+	// iptp-<name> plugins fill in for names that aren't commands on PATH,
+	// so a plugin never shadows a system command
+	if path, ok := sh.pluginFor(cmd); ok {
+		expanded, err := sh.expandWords(words[1:])
+		if err != nil {
+			return
+		}
+		sh.lastExit = RunPlugin(sh.state, sh.currentProcess, path, expanded)
+		return
+	}
+
+	// Try to execute as external command/script
+	sh.cmdExec(words)
+}
+
+// pluginFor finds the plugin to run for cmd, if cmd is not a path or a
+// command on PATH
+func (sh *Shell) pluginFor(cmd string) (string, bool) {
+	if strings.ContainsAny(cmd, `/\`) {
+		return "", false
+	}
+	if _, err := exec.LookPath(cmd); err == nil {
+		return "", false
+	}
+	return FindPlugin(cmd)
+}
+
+// runBuiltin runs a registered command and sets the last exit code
+func (sh *Shell) runBuiltin(command *Command, args []string) {
+	switch {
+	case command.Run != nil:
+		command.Run(sh, args)
+		// Builtins report errors themselves; only external commands set $?
+		sh.lastExit = 0
+	case command.Exec != nil:
+		sh.lastExit = command.Exec(sh.state, sh.currentProcess, args)
+	}
+}
+
+// expandWords applies glob expansion using the glob_nomatch rc setting
+func (sh *Shell) expandWords(words []Word) ([]string, error) {
+	noMatch, _ := sh.config.Get("glob_nomatch")
+	args, err := ExpandWords(words, noMatch)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		sh.lastExit = 1
+	}
+	return args, err
+}
+
+// cmdName handles the 'name' command
+func (sh *Shell) cmdName(args []string) {
+	explain, args := parseExplainFlag(args)
+	here, args := parseHereFlag(args)
+	if len(args) == 0 {
+		fmt.Printf("Current process: %s\n", sh.displayName)
+		if proc, ok := sh.state.GetProcess(sh.currentProcess); ok {
+			fmt.Printf("Intention: %s\n", proc.Intention)
+		}
+		return
+	}
+
+	intention := strings.Join(args, " ")
+	_, match := ParseIntent(intention, time.Now())
+	processName := match.Name
+
+	// The name may belong to another process
+	switch checkNameCollision(sh.reader, sh.config, sh.state, sh.currentProcess, processName, intention, true) {
+	case collisionAttach:
+		sh.state.SetArchived(processName, false)
+		sh.cmdJump([]string{processName})
+		return
+	case collisionSuffix:
+		processName = suffixedName(sh.state, processName)
+	case collisionCancel:
+		fmt.Println("✗ Cancelled")
+		return
+	}
+
+	// Update both internal and display names
+	sh.currentProcess = processName
+	sh.displayName = processName
+
+	currentDir, _ := os.Getwd()
+	sh.state.SetProcess(processName, intention, currentDir)
+	sh.state.Save()
+
+	fmt.Printf("✓ Shell named: %s\n", processName)
+	fmt.Printf("  Intention: %s\n", intention)
+	if explain {
+		printIntentionMatch(match)
+		if proc, ok := sh.state.GetProcess(processName); ok {
+			printIntent(proc.Intent)
+		}
+	} else if intention != processName {
+		fmt.Printf("  (parsed process name: %s)\n", processName)
+	}
+
+	// Go where the intention is worked on, or remember this is the place
+	proc, _ := sh.state.GetProcess(processName)
+	if here {
+		sh.state.Learn(proc.Intent, currentDir)
+		sh.state.Save()
+	} else if dir, ok := chooseIntentPlace(sh.reader, sh.config, sh.state, processName, proc.Intent, true); ok {
+		sh.state.Learn(proc.Intent, dir)
+		sh.changeDirectory(dir)
+		fmt.Printf("✓ Changed to: %s\n", dir)
+	}
+}
+
+// resolveGotoPath expands @bookmarks and ~ and fuzzy-matches paths containing *
+func resolveGotoPath(state *State, path string) (string, error) {
+	if strings.HasPrefix(path, bookmarkPrefix) {
+		return state.ResolveBookmark(path)
+	}
+
+	// Expand home directory
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			if path == "~" {
+				path = home
+			} else {
+				path = filepath.Join(home, path[2:]) // Skip "~/"
+			}
+		}
+	}
+
+	// Handle glob matching (best-scored match)
+	if hasGlobMeta(path) && !isDirectory(path) {
+		currentDir, _ := os.Getwd()
+		matches := RankDirectories(path, currentDir, 3, state.Directories)
+		if len(matches) == 0 {
+			return "", fmt.Errorf("No match found for: %s", path)
+		}
+		fmt.Printf("✓ Matched: %s\n", matches[0].Dir)
+		return matches[0].Dir, nil
+	}
+
+	return path, nil
+}
+
+// cmdGoto handles the 'goto' command: a path, or frecency terms
+// goto -i TERMS... picks among matches; goto --forget [PATH...] drops entries
+func (sh *Shell) cmdGoto(args []string) {
+	interactive, forget, args := parseGotoFlags(args)
+	if forget {
+		forgetDirectories(sh.state, args)
+		return
+	}
+	if (len(args) == 0 || len(args) == 1 && args[0] == "-") && !interactive {
+		sh.cmdCd(args)
+		return
+	}
+
+	path, err := resolveGotoTarget(sh.state, args, interactive, sh.reader)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return
+	}
+	sh.changeDirectory(path)
+}
+
+// cmdCd handles the 'cd' command
+func (sh *Shell) cmdCd(args []string) {
+	if len(args) == 0 {
+		// No arguments - cd to home directory (standard bash behavior)
+		home, err := os.UserHomeDir()
+		if err != nil {
+			fmt.Printf("✗ Cannot get home directory: %v\n", err)
+			return
+		}
+		args = []string{home}
+	}
+	if args[0] == "-" {
+		cmdPreviousDir(sh.state, sh.currentProcess, sh.moveDirectory)
+		return
+	}
+
+	path, err := resolveGotoPath(sh.state, args[0])
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return
+	}
+	sh.changeDirectory(path)
+}
+
+// changeDirectory moves the shell and records the move in the process
+func (sh *Shell) changeDirectory(path string) {
+	sh.moveDirectory(path, true)
+}
+
+// moveDirectory moves the shell, recording the directory left in the history
+// unless record is false (back/forward), and runs the directory hooks
+func (sh *Shell) moveDirectory(path string, record bool) bool {
+	oldDir, _ := os.Getwd()
+
+	if err := os.Chdir(path); err != nil {
+		fmt.Printf("✗ Cannot change directory: %v\n", err)
+		return false
+	}
+
+	newDir, _ := os.Getwd()
+	if record {
+		sh.state.UpdateDirectory(sh.currentProcess, newDir, oldDir)
+	} else {
+		sh.state.UpdateDirectory(sh.currentProcess, newDir, "")
+	}
+	sh.state.Save()
+	runDirectoryHooks(sh.state, sh.currentProcess, oldDir, newDir)
+
+	// Silent like bash cd (no output on success), unless an unnamed shell
+	// entered a project it can be named after
+	if project, ok := projectNameOffer(sh.state, sh.currentProcess, newDir); ok {
+		if acceptProjectName(sh.reader, sh.config, sh.state, sh.currentProcess, project) {
+			sh.cmdName([]string{"--here", projectIntention(project)})
+		}
+	}
+	return true
+}
+
+// cmdGetMeThere handles interactive directory finding
+func (sh *Shell) cmdGetMeThere(args []string) {
+	dir, ok := getMeThere(sh.reader, sh.state, strings.Join(args, " "))
+	if !ok {
+		return
+	}
+
+	oldDir, _ := os.Getwd()
+	sh.changeDirectory(dir)
+	if newDir, _ := os.Getwd(); newDir != oldDir {
+		fmt.Printf("✓ Changed to: %s\n", newDir)
+	}
+}
+
+// cmdSave saves the current state
+func (sh *Shell) cmdSave() {
+	currentDir, _ := os.Getwd()
+	sh.state.UpdateDirectory(sh.currentProcess, currentDir, "")
+	sh.state.Save()
+
+	fmt.Printf("✓ Saved state for: %s @ %s\n", sh.displayName, currentDir)
+}
+
+// cmdList lists all saved processes
+func (sh *Shell) cmdList() {
+	processes := sh.state.ListProcesses()
+	if len(processes) == 0 {
+		fmt.Println("No saved processes")
+		return
+	}
+
+	live := liveProcesses()
+	repos := processGitStatuses(sh.state)
+	fmt.Println("=== Available Processes ===")
+	for _, name := range processes {
+		if proc, ok := sh.state.GetProcess(name); ok && proc.Archived == "" {
+			fmt.Printf("  → %s: %s (PID: %d)%s%s%s\n", name, proc.CurrentDir, proc.PID, intentMarker(proc.Intent), gitMarker(repos, proc.CurrentDir), liveMarker(live, name, proc))
+		}
+	}
+	printArchivedHint(sh.state)
+}
+
+// cmdJump jumps to a saved process location
+func (sh *Shell) cmdJump(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: jump PROCESS")
+		return
+	}
+
+	targetProcess := args[0]
+	proc, ok := sh.state.GetProcess(targetProcess)
+	if !ok {
+		fmt.Printf("✗ Process '%s' not found\n", targetProcess)
+		return
+	}
+	if proc.Archived != "" {
+		fmt.Println(archivedError(targetProcess))
+		return
+	}
+
+	oldDir, _ := os.Getwd()
+
+	if err := os.Chdir(proc.CurrentDir); err != nil {
+		fmt.Printf("✗ Cannot change directory: %v\n", err)
+		return
+	}
+
+	// Update both internal and display names
+	sh.currentProcess = targetProcess
+	sh.displayName = targetProcess
+	
+	newDir, _ := os.Getwd()
+	sh.state.UpdateDirectory(sh.currentProcess, newDir, oldDir)
+	sh.state.Save()
+	runDirectoryHooks(sh.state, sh.currentProcess, oldDir, newDir)
+
+	fmt.Printf("✓ Jumped to %s @ %s\n", targetProcess, newDir)
+}
+
+// cmdState shows current process state
+func (sh *Shell) cmdState() {
+	proc, ok := sh.state.GetProcess(sh.currentProcess)
+	if !ok {
+		fmt.Println("No state for current process")
+		return
+	}
+
+	currentDir, _ := os.Getwd()
+
+	fmt.Println("=== Current Process State (IPTP Format) ===")
+	fmt.Printf("Process: %s\n", sh.displayName)
+	fmt.Printf("Directory: %s\n", currentDir)
+	fmt.Printf("PID: %d\n", os.Getpid())
+	fmt.Println()
+	fmt.Println("=== Intentions ===")
+	fmt.Printf("  \"%s\"\n", proc.Intention)
+	printIntent(proc.Intent)
+	fmt.Println()
+	fmt.Println("=== Pulses (Trivalent) ===")
+	for _, pulse := range proc.Pulses {
+		data, _ := json.Marshal(pulse)
+		fmt.Printf("  %s\n", data)
+	}
+	printSession(proc)
+	printRecordings(proc)
+}
+
+// cmdHelp shows help information
+func (sh *Shell) cmdHelp() {
+	fmt.Println("iptp - IPTP Shell Process Manager")
+	fmt.Println()
+	printCommandHelp(func(cmd *Command) bool { return (cmd.Run != nil || cmd.Exec != nil) && !cmd.CLIOnly })
+	fmt.Println("External Commands:")
+	fmt.Println("  ls, mkdir, etc      - Any standard Unix command")
+	fmt.Println("  ./script.sh         - Run any script in iptp context")
+	fmt.Println("  NAME                - Runs iptp-NAME from PATH as a plugin (see 'plugins')")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  name \"working on authentication\"  # Set process name")
+	fmt.Println("  cd wrk1                          # Standard cd works!")
+	fmt.Println("  goto wrk2                        # Navigate with save")
+	fmt.Println("  getmethere                       # Interactive search")
+	fmt.Println("  jump test2                       # Jump to saved location")
+	fmt.Println()
+	fmt.Println("Tips:")
+	fmt.Println("  • New shells start with IPTP-1, IPTP-2, etc.")
+	fmt.Println("  • Use 'name' to give your shell a meaningful name")
+	fmt.Println("  • getmethere searches current dir first (fast!)")
+	fmt.Println("  • cd works like normal bash/zsh")
+	fmt.Println("  • goto auto-saves your location")
+	fmt.Println("  • Customise the prompt with prompt = \"...\" in ~/.iptprc")
+	fmt.Println()
+}
+
+// cmdExec executes external commands/scripts
+// Unquoted arguments are glob-expanded
+func (sh *Shell) cmdExec(words []Word) {
+	parts, err := sh.expandWords(words)
+	if err != nil || len(parts) == 0 {
+		return
+	}
+
+	// record_commands = true in ~/.iptprc times every foreground command
+	if value, _ := sh.config.Get("record_commands"); value != "true" {
+		sh.lastExit = ExecuteScript(parts)
+		return
+	}
+
+	exitCode, run := RunScript(parts)
+	sh.lastExit = exitCode
+	if run != nil && sh.state.RecordCommand(sh.currentProcess, *run) {
+		sh.state.Save()
+	}
+}
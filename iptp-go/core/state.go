@@ -0,0 +1,371 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Pulse represents a trivalent truth condition (IPTP)
+type Pulse struct {
+	Name     string `json:"name"`
+	TV       string `json:"TV"` // "Y", "N", or "U"
+	Response string `json:"response"`
+}
+
+// Process represents a named shell process with state
+type Process struct {
+	Intention  string   `json:"intention"`
+	Intent     Intent   `json:"intent"` // the intention's structured fields
+	CurrentDir string   `json:"current_dir"`
+	History    []string `json:"history"`
+	Forward    []string `json:"forward,omitempty"` // ahead of the cursor after 'back'
+	Stack      []string `json:"stack,omitempty"`   // pushd/popd
+	PID        int      `json:"pid"`
+	Timestamp  string   `json:"timestamp"`
+	Pulses     []Pulse  `json:"pulses"`
+
+	// Command timing, recorded by 'time' and record_commands
+	CommandStats map[string]CommandStat `json:"command_stats,omitempty"`
+	Runs         []CommandRun           `json:"runs,omitempty"`
+
+	// Session recordings (asciicast v2 files)
+	Recordings []Recording `json:"recordings,omitempty"`
+
+	// Terminal session started with 'spawn', if any
+	Session *SessionInfo `json:"session,omitempty"`
+
+	// Names of bookmarks made from this process (see State.Bookmarks)
+	Bookmarks []string `json:"bookmarks,omitempty"`
+
+	// Active time in the iptp shell (see tracking.go)
+	Activity []ActivitySpan `json:"activity,omitempty"`
+
+	// When 'archive' put the process away (RFC 3339), "" if in use
+	Archived string `json:"archived,omitempty"`
+}
+
+// State represents the global iptp state
+type State struct {
+	Processes map[string]Process  `json:"processes"`
+	Bookmarks map[string]Bookmark `json:"bookmarks,omitempty"`
+
+	// Frecency database of visited directories (see frecency.go)
+	Directories map[string]DirVisit `json:"directories,omitempty"`
+
+	// Directories learned for intentions, by subject (see resolve.go)
+	Resolutions map[string]Resolution `json:"resolutions,omitempty"`
+
+	filepath string
+
+	// Set when iptpd is running; Save then sends changed processes to it
+	daemon             *DaemonClient
+	snapshot           map[string]string
+	bookmarkSnapshot   map[string]string
+	resolutionSnapshot map[string]string
+
+	// Directory visits and forgets not yet sent to iptpd
+	dirVisits  []string
+	dirForgets []string
+}
+
+// NewState creates a new empty state
+func NewState(filepath string) *State {
+	return &State{
+		Processes: make(map[string]Process),
+		filepath:  filepath,
+	}
+}
+
+// LoadState loads state from JSON file
+func LoadState(filepath string) (*State, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	state.filepath = filepath
+	state.markSaved()
+	return &state, nil
+}
+
+// OpenState loads state from iptpd if it is running, otherwise from the file
+// IPTP_NO_DAEMON=1 forces direct file access
+func OpenState(filepath string) (*State, error) {
+	if daemonAllowed() {
+		if client, err := DialDaemon(); err == nil {
+			state := NewState(filepath)
+			state.daemon = client
+			if err := state.Refresh(); err == nil {
+				return state, nil
+			}
+			client.Close()
+			state.daemon = nil
+		}
+	}
+	return LoadState(filepath)
+}
+
+// Save writes state to iptpd or, without a daemon, to the JSON file
+func (s *State) Save() error {
+	if s.daemon == nil {
+		s.joinDaemon()
+	}
+	if s.daemon != nil {
+		if err := s.saveDaemon(); err == nil {
+			s.dirVisits, s.dirForgets = nil, nil
+			return nil
+		}
+		// Daemon went away; fall back to the file
+		s.daemon.Close()
+		s.daemon = nil
+	}
+	if err := s.saveMerged(); err != nil {
+		return err
+	}
+	s.dirVisits, s.dirForgets = nil, nil
+	s.markSaved()
+	return nil
+}
+
+// saveMerged applies the changes made since the last load or save to the
+// file as it is now and adopts the result, so shells sharing the file
+// without iptpd don't undo each other's moves, renames, removals or
+// bookmarks. Nothing is written when nothing changed.
+func (s *State) saveMerged() error {
+	disk, err := LoadState(s.filepath)
+	if err != nil {
+		// No state file yet (or an unreadable one): write ours
+		return s.saveFile()
+	}
+	if disk.Processes == nil {
+		disk.Processes = make(map[string]Process)
+	}
+
+	changed := false
+	for name, data := range snapshotProcesses(s.Processes) {
+		base, seen := s.snapshot[name]
+		if seen && base == data {
+			continue
+		}
+		current, ok := disk.Processes[name]
+		if !ok || !seen {
+			base = "" // new here, or removed from the file since
+		}
+		proc, err := mergeProcess(current, json.RawMessage(base), json.RawMessage(data))
+		if err != nil {
+			return err
+		}
+		disk.Processes[name] = proc
+		changed = true
+	}
+	for name := range s.snapshot {
+		if _, ok := s.Processes[name]; !ok {
+			delete(disk.Processes, name)
+			changed = true
+		}
+	}
+
+	// Bookmarks and resolutions merge by name: another shell's additions
+	// stay, and only the ones removed here are removed
+	set, removed := entryChanges(snapshotEntries(s.Bookmarks), s.bookmarkSnapshot)
+	if len(set) > 0 && disk.Bookmarks == nil {
+		disk.Bookmarks = make(map[string]Bookmark)
+	}
+	for _, name := range set {
+		disk.Bookmarks[name] = s.Bookmarks[name]
+	}
+	for _, name := range removed {
+		delete(disk.Bookmarks, name)
+	}
+	changed = changed || len(set) > 0 || len(removed) > 0
+
+	set, removed = entryChanges(snapshotEntries(s.Resolutions), s.resolutionSnapshot)
+	if len(set) > 0 && disk.Resolutions == nil {
+		disk.Resolutions = make(map[string]Resolution)
+	}
+	for _, subject := range set {
+		disk.Resolutions[subject] = s.Resolutions[subject]
+	}
+	for _, subject := range removed {
+		delete(disk.Resolutions, subject)
+	}
+	changed = changed || len(set) > 0 || len(removed) > 0
+
+	now := time.Now()
+	for _, dir := range s.dirVisits {
+		disk.visitDirectory(dir, now)
+		changed = true
+	}
+	for _, dir := range s.dirForgets {
+		delete(disk.Directories, dir)
+		changed = true
+	}
+
+	if changed {
+		if err := disk.saveFile(); err != nil {
+			return err
+		}
+	}
+	s.Processes = disk.Processes
+	s.Bookmarks = disk.Bookmarks
+	s.Resolutions = disk.Resolutions
+	s.Directories = disk.Directories
+	return nil
+}
+
+// saveFile writes state to the JSON file
+// Written to a temporary file and renamed, so readers never see half of it
+func (s *State) saveFile() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Private to the user: runs keep the command lines typed (see timing.go)
+	tmp := fmt.Sprintf("%s.%d.tmp", s.filepath, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.filepath)
+}
+
+// SetProcess creates or updates a process
+func (s *State) SetProcess(name, intention, currentDir string) {
+	process := Process{
+		Intention:  intention,
+		CurrentDir: currentDir,
+		History:    []string{},
+		PID:        os.Getpid(),
+		Timestamp:  time.Now().Format(time.RFC3339),
+		Pulses: []Pulse{
+			{Name: "process named", TV: "Y", Response: name},
+			{Name: "directory saved", TV: "Y", Response: currentDir},
+		},
+	}
+
+	// Preserve history and command stats if process already exists
+	if existing, ok := s.Processes[name]; ok {
+		process.History = existing.History
+		process.Forward = existing.Forward
+		process.Stack = existing.Stack
+		process.CommandStats = existing.CommandStats
+		process.Runs = existing.Runs
+		process.Recordings = existing.Recordings
+		process.Session = existing.Session
+		process.Bookmarks = existing.Bookmarks
+		process.Activity = existing.Activity
+		if existing.Intention == intention {
+			process.Intent = existing.Intent // keep due dates resolved when named
+		}
+	}
+	if process.Intent.Empty() {
+		process.Intent, _ = ParseIntent(intention, time.Now())
+	}
+	process.Pulses = projectPulses(process.Pulses, currentDir)
+	process.Pulses = gitPulses(process.Pulses, currentDir)
+
+	s.Processes[name] = process
+}
+
+// UpdateDirectory updates the current directory for a process
+func (s *State) UpdateDirectory(processName, newDir, oldDir string) {
+	process, ok := s.Processes[processName]
+
+	// Only an actual move counts as a visit; without an oldDir (save,
+	// back/forward) compare against where the process last was
+	from := oldDir
+	if from == "" && ok {
+		from = process.CurrentDir
+	}
+	if from != newDir {
+		s.recordVisit(newDir)
+	}
+
+	if !ok {
+		// Create new process if doesn't exist
+		s.SetProcess(processName, "Working in "+processName, newDir)
+		return
+	}
+
+	// Add old directory to history; a new move drops the forward list
+	if oldDir != "" && oldDir != newDir {
+		process.History = append(process.History, oldDir)
+		process.Forward = nil
+	}
+
+	process.CurrentDir = newDir
+	process.Timestamp = time.Now().Format(time.RFC3339)
+	// Refresh the core pulses, keeping any published by plugins
+	process.Pulses = mergePulse(process.Pulses, Pulse{Name: "process named", TV: "Y", Response: processName})
+	process.Pulses = mergePulse(process.Pulses, Pulse{Name: "directory saved", TV: "Y", Response: newDir})
+	process.Pulses = projectPulses(process.Pulses, newDir)
+	process.Pulses = gitPulses(process.Pulses, newDir)
+
+	s.Processes[processName] = process
+}
+
+// SetPulse adds or replaces a pulse (matched by name) on a process
+func (s *State) SetPulse(processName string, pulse Pulse) bool {
+	process, ok := s.Processes[processName]
+	if !ok {
+		return false
+	}
+
+	process.Pulses = mergePulse(process.Pulses, pulse)
+	process.Timestamp = time.Now().Format(time.RFC3339)
+	s.Processes[processName] = process
+	return true
+}
+
+// EvaluatePulses re-checks the pulses the state can verify on its own
+// "directory saved" turns N when the directory no longer exists
+func (s *State) EvaluatePulses(processName string) ([]Pulse, bool) {
+	process, ok := s.Processes[processName]
+	if !ok {
+		return nil, false
+	}
+
+	dirPulse := Pulse{Name: "directory saved", TV: "Y", Response: process.CurrentDir}
+	if info, err := os.Stat(process.CurrentDir); err != nil || !info.IsDir() {
+		dirPulse.TV = "N"
+	}
+	process.Pulses = mergePulse(process.Pulses, dirPulse)
+
+	s.Processes[processName] = process
+	return process.Pulses, true
+}
+
+// mergePulse replaces the pulse with the same name or appends it
+func mergePulse(pulses []Pulse, pulse Pulse) []Pulse {
+	for i, p := range pulses {
+		if p.Name == pulse.Name {
+			updated := make([]Pulse, len(pulses))
+			copy(updated, pulses)
+			updated[i] = pulse
+			return updated
+		}
+	}
+	return append(append([]Pulse{}, pulses...), pulse)
+}
+
+// GetProcess retrieves a process by name
+func (s *State) GetProcess(name string) (Process, bool) {
+	process, ok := s.Processes[name]
+	return process, ok
+}
+
+// ListProcesses returns all process names
+func (s *State) ListProcesses() []string {
+	names := make([]string, 0, len(s.Processes))
+	for name := range s.Processes {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSaveMergedEntries(t *testing.T) {
+	t.Setenv("IPTP_NO_DAEMON", "1")
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	initial := NewState(path)
+	initial.Bookmarks = map[string]Bookmark{"api": {Dir: "/src/api"}, "old": {Dir: "/src/old"}}
+	initial.Resolutions = map[string]Resolution{"api": {Dir: "/src/api", Uses: 1}}
+	if err := initial.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two shells read the file, then each changes its own bookmarks
+	first, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Bookmarks["web"] = Bookmark{Dir: "/src/web"}
+	first.Resolutions["web"] = Resolution{Dir: "/src/web", Uses: 1}
+	if err := first.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	second.Bookmarks["docs"] = Bookmark{Dir: "/src/docs"}
+	delete(second.Bookmarks, "old")
+	second.Resolutions["api"] = Resolution{Dir: "/src/api", Uses: 2}
+	if err := second.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	disk, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bookmarks []string
+	for name := range disk.Bookmarks {
+		bookmarks = append(bookmarks, name)
+	}
+	sort.Strings(bookmarks)
+	if want := []string{"api", "docs", "web"}; !reflect.DeepEqual(bookmarks, want) {
+		t.Errorf("bookmarks after both saves = %q, want %q", bookmarks, want)
+	}
+	if len(disk.Resolutions) != 2 || disk.Resolutions["api"].Uses != 2 || disk.Resolutions["web"].Dir != "/src/web" {
+		t.Errorf("resolutions after both saves = %+v", disk.Resolutions)
+	}
+	if len(second.Bookmarks) != 3 {
+		t.Errorf("the saving shell has bookmarks %+v, want the merged ones", second.Bookmarks)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package core
+
+import "os"
+
+// terminalSize returns the default console size on Windows
+func terminalSize(f *os.File) (int, int) {
+	return 80, 24
+}
@@ -0,0 +1,304 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecordedRuns caps the per-process run log kept in the state file
+const maxRecordedRuns = 500
+
+// CommandRun records the resources used by one external command
+type CommandRun struct {
+	Command   string `json:"command"`
+	Key       string `json:"key"`
+	Dir       string `json:"dir"`
+	Timestamp string `json:"timestamp"`
+	WallMs    int64  `json:"wall_ms"`
+	UserMs    int64  `json:"user_ms"`
+	SysMs     int64  `json:"sys_ms"`
+	MaxRSSKB  int64  `json:"max_rss_kb"`
+	ExitCode  int    `json:"exit_code"`
+}
+
+// CommandStat aggregates every recorded run of a command for a process
+type CommandStat struct {
+	Count    int    `json:"count"`
+	Failures int    `json:"failures"`
+	TotalMs  int64  `json:"total_ms"`
+	MaxMs    int64  `json:"max_ms"`
+	MaxRSSKB int64  `json:"max_rss_kb"`
+	LastRun  string `json:"last_run"`
+}
+
+// newCommandRun builds a CommandRun from a finished process
+func newCommandRun(parts []string, wall time.Duration, ps *os.ProcessState) *CommandRun {
+	dir, _ := os.Getwd()
+	return &CommandRun{
+		Command:   strings.Join(parts, " "),
+		Key:       commandKey(parts),
+		Dir:       dir,
+		Timestamp: time.Now().Format(time.RFC3339),
+		WallMs:    wall.Milliseconds(),
+		UserMs:    ps.UserTime().Milliseconds(),
+		SysMs:     ps.SystemTime().Milliseconds(),
+		MaxRSSKB:  maxRSSKB(ps),
+		ExitCode:  ps.ExitCode(),
+	}
+}
+
+// commandKey groups runs for stats: the program name plus its subcommand
+// e.g. "go build ./..." -> "go build", "ls -la" -> "ls"
+func commandKey(parts []string) string {
+	key := filepath.Base(parts[0])
+	if len(parts) > 1 {
+		sub := parts[1]
+		if sub != "" && !strings.HasPrefix(sub, "-") && !strings.ContainsAny(sub, "/.=*") {
+			key += " " + sub
+		}
+	}
+	return key
+}
+
+// RecordCommand adds a finished command to a process's stats
+func (s *State) RecordCommand(processName string, run CommandRun) bool {
+	process, ok := s.Processes[processName]
+	if !ok {
+		return false
+	}
+
+	if process.CommandStats == nil {
+		process.CommandStats = make(map[string]CommandStat)
+	}
+	process.CommandStats[run.Key] = process.CommandStats[run.Key].add(run)
+
+	process.Runs = append(process.Runs, run)
+	if len(process.Runs) > maxRecordedRuns {
+		process.Runs = process.Runs[len(process.Runs)-maxRecordedRuns:]
+	}
+
+	s.Processes[processName] = process
+	return true
+}
+
+// add folds one run into the aggregate
+func (stat CommandStat) add(run CommandRun) CommandStat {
+	stat.Count++
+	if run.ExitCode != 0 {
+		stat.Failures++
+	}
+	stat.TotalMs += run.WallMs
+	if run.WallMs > stat.MaxMs {
+		stat.MaxMs = run.WallMs
+	}
+	if run.MaxRSSKB > stat.MaxRSSKB {
+		stat.MaxRSSKB = run.MaxRSSKB
+	}
+	stat.LastRun = run.Timestamp
+	return stat
+}
+
+// printTiming prints a time report in the style of the time builtin
+func printTiming(run *CommandRun) {
+	fmt.Printf("\nreal    %s\n", formatMs(run.WallMs))
+	fmt.Printf("user    %s\n", formatMs(run.UserMs))
+	fmt.Printf("sys     %s\n", formatMs(run.SysMs))
+	if run.MaxRSSKB > 0 {
+		fmt.Printf("maxrss  %s\n", formatKB(run.MaxRSSKB))
+	}
+}
+
+// cmdTime runs a command and reports wall, user and sys time and peak memory
+// Like bash, time is a keyword: the shell passes it the unexpanded words so
+// the timed command still gets glob expansion. Timed runs are always
+// recorded in the current process.
+func (sh *Shell) cmdTime(words []Word) {
+	if len(words) == 0 {
+		fmt.Println("Usage: time COMMAND [ARGS...]")
+		return
+	}
+
+	// Builtins run in-process, so only wall time is meaningful
+	if command, ok := LookupBuiltin(words[0].Text); ok {
+		start := time.Now()
+		sh.runBuiltin(command, wordTexts(words[1:]))
+		fmt.Printf("\nreal    %s\n", formatMs(time.Since(start).Milliseconds()))
+		return
+	}
+
+	parts, err := sh.expandWords(words)
+	if err != nil || len(parts) == 0 {
+		return
+	}
+
+	exitCode, run := RunScript(parts)
+	sh.lastExit = exitCode
+	if run == nil {
+		return
+	}
+
+	printTiming(run)
+	sh.state.RecordCommand(sh.currentProcess, *run)
+	sh.state.Save()
+}
+
+// literalWords wraps plain arguments as words that expand to themselves
+func literalWords(args []string) []Word {
+	words := make([]Word, len(args))
+	for i, arg := range args {
+		words[i] = Word{Text: arg, Pattern: escapeGlob(arg)}
+	}
+	return words
+}
+
+// cmdStatsNonInteractive shows the slowest and most frequent commands of a process
+// iptp stats [--process NAME] [--since 7d] [--top N]
+func cmdStatsNonInteractive(state *State, process string, args []string) int {
+	top := 10
+	var since time.Time
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--process", "-p":
+			if i+1 < len(args) {
+				process = args[i+1]
+				i++
+			}
+		case "--since", "-s":
+			if i+1 < len(args) {
+				d, err := parseAge(args[i+1])
+				if err != nil {
+					fmt.Printf("✗ Invalid --since value: %s\n", args[i+1])
+					return 1
+				}
+				since = time.Now().Add(-d)
+				i++
+			}
+		case "--top", "-n":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil && n > 0 {
+					top = n
+				}
+				i++
+			}
+		default:
+			fmt.Println("Usage: iptp stats [--process NAME] [--since 7d] [--top N]")
+			return 1
+		}
+	}
+
+	proc, ok := state.GetProcess(process)
+	if !ok {
+		fmt.Printf("✗ Process '%s' not found\n", process)
+		return 1
+	}
+
+	stats := proc.CommandStats
+	if !since.IsZero() {
+		stats = aggregateRuns(proc.Runs, since)
+	}
+	if len(stats) == 0 {
+		fmt.Printf("No recorded commands for %s\n", process)
+		fmt.Println("Use 'time CMD', or set record_commands = true in ~/.iptprc")
+		return 0
+	}
+
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	title := "all time"
+	if !since.IsZero() {
+		title = "since " + since.Format("2006-01-02 15:04")
+	}
+	fmt.Printf("=== Command Stats: %s (%s) ===\n", process, title)
+
+	// Slowest by average wall time
+	sort.SliceStable(keys, func(i, j int) bool {
+		a, b := stats[keys[i]], stats[keys[j]]
+		return a.TotalMs/int64(a.Count) > b.TotalMs/int64(b.Count)
+	})
+	fmt.Println("\nSlowest (average wall time):")
+	printStatRows(keys, stats, top)
+
+	// Most frequent by run count
+	sort.SliceStable(keys, func(i, j int) bool {
+		return stats[keys[i]].Count > stats[keys[j]].Count
+	})
+	fmt.Println("\nMost frequent:")
+	printStatRows(keys, stats, top)
+
+	return 0
+}
+
+// printStatRows prints up to top rows of command stats
+func printStatRows(keys []string, stats map[string]CommandStat, top int) {
+	for i, key := range keys {
+		if i >= top {
+			break
+		}
+		stat := stats[key]
+		line := fmt.Sprintf("  %-20s %4d runs  avg %-8s max %-8s", key, stat.Count,
+			formatMs(stat.TotalMs/int64(stat.Count)), formatMs(stat.MaxMs))
+		if stat.MaxRSSKB > 0 {
+			line += "  rss " + formatKB(stat.MaxRSSKB)
+		}
+		if stat.Failures > 0 {
+			line += fmt.Sprintf("  ✗%d", stat.Failures)
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
+
+// aggregateRuns builds stats from the run log, limited to runs after since
+func aggregateRuns(runs []CommandRun, since time.Time) map[string]CommandStat {
+	stats := make(map[string]CommandStat)
+	for _, run := range runs {
+		t, err := time.Parse(time.RFC3339, run.Timestamp)
+		if err != nil || t.Before(since) {
+			continue
+		}
+		stats[run.Key] = stats[run.Key].add(run)
+	}
+	return stats
+}
+
+// parseAge parses a duration like "90m", "12h" or "7d"
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// formatMs formats milliseconds as 0.123s or 2m03.4s
+func formatMs(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d < time.Minute {
+		return fmt.Sprintf("%.3fs", d.Seconds())
+	}
+	return fmt.Sprintf("%dm%04.1fs", int(d.Minutes()), d.Seconds()-float64(int(d.Minutes())*60))
+}
+
+// formatKB formats a size in kilobytes as KB, MB or GB
+func formatKB(kb int64) string {
+	switch {
+	case kb >= 1024*1024:
+		return fmt.Sprintf("%.1f GB", float64(kb)/(1024*1024))
+	case kb >= 1024:
+		return fmt.Sprintf("%.1f MB", float64(kb)/1024)
+	default:
+		return fmt.Sprintf("%d KB", kb)
+	}
+}
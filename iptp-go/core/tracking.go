@@ -0,0 +1,190 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// Time tracking
+//
+// The iptp shell credits the time it is used to its process, directory by
+// directory. Time between lines typed at the prompt counts when the gap is
+// shorter than idle_timeout in ~/.iptprc (default 5m); a longer gap means the
+// user was away and counts for nothing. Time spent running a command counts in
+// full. 'report' sums the spans up per intention (see report.go).
+//
+// The shell collects tracked time itself and adds it to the state at most
+// once per activitySaveInterval (and on exit), so it doesn't rewrite the
+// state, or send the span log to iptpd, after every line.
+
+const (
+	// defaultIdleTimeout is the longest gap between lines that counts as work
+	defaultIdleTimeout = 5 * time.Minute
+
+	// activityKeep is how long spans are kept in the state file
+	activityKeep = 90 * 24 * time.Hour
+
+	// maxActivitySpans caps the per-process span log
+	maxActivitySpans = 5000
+
+	// activitySaveInterval is how often the shell saves tracked time
+	activitySaveInterval = time.Minute
+)
+
+// ActivitySpan is a stretch of active time in one directory
+type ActivitySpan struct {
+	Start    string         `json:"start"` // RFC 3339
+	Seconds  int64          `json:"seconds"`
+	Dir      string         `json:"dir"`
+	Commands map[string]int `json:"commands,omitempty"` // by commandKey
+}
+
+// Bounds returns the span's start and end; ok is false if Start is bad
+func (span ActivitySpan) Bounds() (time.Time, time.Time, bool) {
+	start, err := time.Parse(time.RFC3339, span.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, start.Add(time.Duration(span.Seconds) * time.Second), true
+}
+
+// Track credits start..end in dir to a process, counting command if not ""
+// A stretch that carries on from the last span in the same directory
+// extends it; spans are kept to the second, so gaps under 2s don't count.
+// Returns false if nothing was recorded.
+func (s *State) Track(processName, dir string, start, end time.Time, command string) bool {
+	process, ok := s.Processes[processName]
+	if !ok {
+		return false
+	}
+	if end.Sub(start) < time.Second && command == "" {
+		return false
+	}
+
+	spans := process.Activity
+	var last *ActivitySpan
+	if n := len(spans); n > 0 && spans[n-1].Dir == dir {
+		if _, lastEnd, ok := spans[n-1].Bounds(); ok && start.Sub(lastEnd) < 2*time.Second {
+			last = &spans[n-1]
+		}
+	}
+	if last == nil {
+		spans = pruneActivity(spans, start)
+		spans = append(spans, ActivitySpan{Start: start.Format(time.RFC3339), Dir: dir})
+		last = &spans[len(spans)-1]
+	}
+	if lastStart, _, ok := last.Bounds(); ok {
+		last.Seconds = max(last.Seconds, int64(end.Sub(lastStart).Seconds()))
+	}
+	if command != "" {
+		if last.Commands == nil {
+			last.Commands = make(map[string]int)
+		}
+		last.Commands[command]++
+	}
+
+	process.Activity = spans
+	s.Processes[processName] = process
+	return true
+}
+
+// pruneActivity drops spans older than activityKeep and over the cap
+func pruneActivity(spans []ActivitySpan, now time.Time) []ActivitySpan {
+	cutoff := now.Add(-activityKeep)
+	keep := 0
+	for keep < len(spans) {
+		if start, _, ok := spans[keep].Bounds(); ok && start.After(cutoff) {
+			break
+		}
+		keep++
+	}
+	spans = spans[keep:]
+	if len(spans) >= maxActivitySpans {
+		spans = spans[len(spans)-maxActivitySpans+1:]
+	}
+	return spans
+}
+
+// activityTracker is the stretch of shell time not yet credited
+type activityTracker struct {
+	since   time.Time
+	process string
+	dir     string
+	idle    time.Duration
+
+	pending []trackedStretch // credited but not yet in the state
+	flushed time.Time
+}
+
+// trackedStretch is one State.Track call waiting for flushActivity
+type trackedStretch struct {
+	process, dir string
+	start, end   time.Time
+	command      string
+}
+
+// add queues a stretch for the next flush
+func (t *activityTracker) add(start, end time.Time, command string) {
+	t.pending = append(t.pending, trackedStretch{process: t.process, dir: t.dir, start: start, end: end, command: command})
+}
+
+// newActivityTracker starts tracking a shell's time
+func newActivityTracker(config *Config, process, dir string) activityTracker {
+	idle := defaultIdleTimeout
+	if config != nil {
+		if value, ok := config.Get("idle_timeout"); ok {
+			if d, err := time.ParseDuration(value); err == nil && d > 0 {
+				idle = d
+			}
+		}
+	}
+	now := time.Now()
+	return activityTracker{since: now, process: process, dir: dir, idle: idle, flushed: now}
+}
+
+// trackTyped credits the wait for a line typed at the prompt, unless the
+// shell sat idle, and counts the command it runs
+func (sh *Shell) trackTyped(line string) {
+	now := time.Now()
+	t := &sh.tracker
+	if now.Sub(t.since) <= t.idle {
+		t.add(t.since, now, "")
+	}
+	t.since = now
+	if words := strings.Fields(line); len(words) > 0 {
+		t.add(now, now, commandKey(words))
+	}
+}
+
+// trackCommand credits the time a command ran, then follows the shell to
+// its new process and directory
+func (sh *Shell) trackCommand() {
+	now := time.Now()
+	t := &sh.tracker
+	t.add(t.since, now, "")
+	t.since = now
+	t.process = sh.currentProcess
+	t.dir, _ = os.Getwd()
+}
+
+// flushActivity adds the queued time to the state and saves it, once per
+// activitySaveInterval unless force is set; nothing is saved when no span
+// changed
+func (sh *Shell) flushActivity(force bool) {
+	t := &sh.tracker
+	if len(t.pending) == 0 || (!force && time.Since(t.flushed) < activitySaveInterval) {
+		return
+	}
+
+	changed := false
+	for _, stretch := range t.pending {
+		if sh.state.Track(stretch.process, stretch.dir, stretch.start, stretch.end, stretch.command) {
+			changed = true
+		}
+	}
+	t.pending, t.flushed = nil, time.Now()
+	if changed {
+		sh.state.Save()
+	}
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrack(t *testing.T) {
+	t0 := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return t0.Add(time.Duration(seconds) * time.Second) }
+
+	type stretch struct {
+		dir        string
+		start, end int
+		command    string
+	}
+	tests := []struct {
+		name     string
+		stretch  []stretch
+		recorded []bool
+		want     []ActivitySpan
+	}{
+		{
+			name:     "one stretch",
+			stretch:  []stretch{{"/src", 0, 90, ""}},
+			recorded: []bool{true},
+			want:     []ActivitySpan{{Start: "2026-10-14T09:00:00Z", Seconds: 90, Dir: "/src"}},
+		},
+		{
+			name:     "carried on in the same directory",
+			stretch:  []stretch{{"/src", 0, 60, ""}, {"/src", 61, 120, "go test"}, {"/src", 120, 150, "go test"}},
+			recorded: []bool{true, true, true},
+			want: []ActivitySpan{
+				{Start: "2026-10-14T09:00:00Z", Seconds: 150, Dir: "/src", Commands: map[string]int{"go test": 2}},
+			},
+		},
+		{
+			name:     "a break starts a new span",
+			stretch:  []stretch{{"/src", 0, 60, ""}, {"/src", 600, 660, ""}},
+			recorded: []bool{true, true},
+			want: []ActivitySpan{
+				{Start: "2026-10-14T09:00:00Z", Seconds: 60, Dir: "/src"},
+				{Start: "2026-10-14T09:10:00Z", Seconds: 60, Dir: "/src"},
+			},
+		},
+		{
+			name:     "another directory starts a new span",
+			stretch:  []stretch{{"/src", 0, 60, ""}, {"/docs", 60, 90, ""}},
+			recorded: []bool{true, true},
+			want: []ActivitySpan{
+				{Start: "2026-10-14T09:00:00Z", Seconds: 60, Dir: "/src"},
+				{Start: "2026-10-14T09:01:00Z", Seconds: 30, Dir: "/docs"},
+			},
+		},
+		{
+			name:     "under a second without a command",
+			stretch:  []stretch{{"/src", 0, 0, ""}},
+			recorded: []bool{false},
+			want:     nil,
+		},
+		{
+			name:     "a quick command still counts",
+			stretch:  []stretch{{"/src", 0, 0, "ls"}},
+			recorded: []bool{true},
+			want:     []ActivitySpan{{Start: "2026-10-14T09:00:00Z", Dir: "/src", Commands: map[string]int{"ls": 1}}},
+		},
+	}
+	for _, tt := range tests {
+		state := &State{Processes: map[string]Process{"api": {}}}
+		for i, s := range tt.stretch {
+			if got := state.Track("api", s.dir, at(s.start), at(s.end), s.command); got != tt.recorded[i] {
+				t.Errorf("%s: Track #%d = %v, want %v", tt.name, i+1, got, tt.recorded[i])
+			}
+		}
+		if got := state.Processes["api"].Activity; !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: activity %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	state := &State{Processes: map[string]Process{}}
+	if state.Track("missing", "/src", t0, at(60), "") {
+		t.Errorf("Track recorded time for a missing process")
+	}
+}
+
+func TestPruneActivity(t *testing.T) {
+	now := time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC)
+	span := func(age time.Duration) ActivitySpan {
+		return ActivitySpan{Start: now.Add(-age).Format(time.RFC3339), Seconds: 60, Dir: "/src"}
+	}
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		spans []ActivitySpan
+		want  []ActivitySpan
+	}{
+		{"nothing old", []ActivitySpan{span(2 * day), span(day)}, []ActivitySpan{span(2 * day), span(day)}},
+		{"old spans dropped", []ActivitySpan{span(100 * day), span(91 * day), span(89 * day)}, []ActivitySpan{span(89 * day)}},
+		{"bad start dropped", []ActivitySpan{{Start: "yesterday"}, span(day)}, []ActivitySpan{span(day)}},
+		{"all old", []ActivitySpan{span(200 * day)}, []ActivitySpan{}},
+	}
+	for _, tt := range tests {
+		if got := pruneActivity(tt.spans, now); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: pruneActivity = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// Over the cap, the oldest go, leaving room for the span about to be added
+	spans := make([]ActivitySpan, maxActivitySpans+10)
+	for i := range spans {
+		spans[i] = span(time.Duration(len(spans)-i) * time.Minute)
+	}
+	pruned := pruneActivity(spans, now)
+	if len(pruned) != maxActivitySpans-1 || pruned[len(pruned)-1].Start != spans[len(spans)-1].Start {
+		t.Errorf("pruneActivity kept %d spans ending in %+v, want the newest %d", len(pruned), pruned[len(pruned)-1], maxActivitySpans-1)
+	}
+}
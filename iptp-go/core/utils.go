@@ -0,0 +1,104 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sanitizeProcessName converts a string to a valid process name
+func sanitizeProcessName(name string) string {
+	// Replace non-alphanumeric characters (except - and _) with _
+	re := regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+	name = re.ReplaceAllString(name, "_")
+
+	// Remove leading/trailing underscores
+	name = strings.Trim(name, "_")
+
+	if name == "" {
+		return "unnamed"
+	}
+
+	return name
+}
+
+// FindDirectoryFuzzy returns the best directory matching a glob or fuzzy
+// pattern below the current directory (see RankDirectories)
+func FindDirectoryFuzzy(pattern string) (string, error) {
+	// Get current directory
+	currentDir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	matches := RankDirectories(pattern, currentDir, 3, nil)
+	if len(matches) == 0 {
+		return "", nil
+	}
+	return matches[0].Dir, nil
+}
+
+// FindDirectoriesInteractive searches for directories matching a pattern
+func FindDirectoriesInteractive(pattern string) ([]string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return FindDirectoriesFrom(pattern, homeDir, 5, 20)
+}
+
+// FindDirectoriesFrom searches for directories from a starting point,
+// best match first
+// Much faster than searching from home when you're already in a subdirectory
+func FindDirectoriesFrom(pattern, startDir string, maxDepth, maxResults int) ([]string, error) {
+	if _, err := os.Stat(startDir); err != nil {
+		return nil, err
+	}
+	return matchedDirs(RankDirectories(pattern, startDir, maxDepth, nil), maxResults), nil
+}
+
+// ExpandPath expands ~ to home directory and handles relative paths
+func ExpandPath(path string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	// Make absolute
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	return absPath, nil
+}
+
+// FormatPath formats a path for display (shortens home directory)
+func FormatPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+
+	if strings.HasPrefix(path, home) {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+
+	return path
+}
+
+// PrintError prints an error message in a consistent format
+func PrintError(format string, args ...interface{}) {
+	fmt.Printf("✗ "+format+"\n", args...)
+}
+
+// PrintSuccess prints a success message in a consistent format
+func PrintSuccess(format string, args ...interface{}) {
+	fmt.Printf("✓ "+format+"\n", args...)
+}
\ No newline at end of file
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// inotifyMask reports directories appearing and disappearing
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_ONLYDIR
+
+// dirWatcher keeps a DirIndex current with inotify
+// When the kernel's watch limit is reached, the rest is left to rescans
+type dirWatcher struct {
+	ix      *DirIndex
+	file    *os.File
+	fd      int
+	mu      sync.Mutex
+	watches map[int32]string // watch descriptor -> directory
+	byDir   map[string]int32
+	full    bool // hit fs.inotify.max_user_watches
+}
+
+// startDirWatcher watches every indexed directory; nil if inotify is unavailable
+func startDirWatcher(ix *DirIndex) *dirWatcher {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil
+	}
+	w := &dirWatcher{
+		ix:      ix,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		fd:      fd,
+		watches: make(map[int32]string),
+		byDir:   make(map[string]int32),
+	}
+	w.Sync()
+	go w.readEvents()
+	return w
+}
+
+// Sync adds watches for indexed directories that have none and drops
+// watches of directories no longer indexed
+func (w *dirWatcher) Sync() {
+	w.ix.mu.RLock()
+	dirs := make([]string, 0, len(w.ix.dirs))
+	for dir := range w.ix.dirs {
+		dirs = append(dirs, dir)
+	}
+	w.ix.mu.RUnlock()
+
+	indexed := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		indexed[dir] = true
+	}
+
+	w.mu.Lock()
+	for dir, wd := range w.byDir {
+		if !indexed[dir] {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.byDir, dir)
+			delete(w.watches, wd)
+		}
+	}
+	w.full = false
+	w.mu.Unlock()
+
+	w.add(dirs)
+}
+
+// add watches directories until the watch limit is reached
+func (w *dirWatcher) add(dirs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, dir := range dirs {
+		if w.full {
+			break
+		}
+		if _, ok := w.byDir[dir]; ok {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+		if err == syscall.ENOSPC {
+			w.full = true
+			break
+		}
+		if err != nil {
+			continue
+		}
+		w.watches[int32(wd)] = dir
+		w.byDir[dir] = int32(wd)
+	}
+
+	w.ix.mu.Lock()
+	w.ix.watching = len(w.byDir)
+	w.ix.mu.Unlock()
+}
+
+// forget drops the watches of a removed directory tree
+func (w *dirWatcher) forget(dirs []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, dir := range dirs {
+		if wd, ok := w.byDir[dir]; ok {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.byDir, dir)
+			delete(w.watches, wd)
+		}
+	}
+
+	w.ix.mu.Lock()
+	w.ix.watching = len(w.byDir)
+	w.ix.mu.Unlock()
+}
+
+// readEvents applies directory creations and removals to the index
+func (w *dirWatcher) readEvents() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return // closed
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				w.ix.requestRescan(false)
+				continue
+			}
+
+			w.mu.Lock()
+			parent, ok := w.watches[event.Wd]
+			if event.Mask&syscall.IN_IGNORED != 0 && ok {
+				delete(w.watches, event.Wd)
+				delete(w.byDir, parent)
+			}
+			w.mu.Unlock()
+			if !ok || name == "" || event.Mask&syscall.IN_ISDIR == 0 {
+				continue
+			}
+
+			dir := filepath.Join(parent, name)
+			switch {
+			case event.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+				w.ix.mu.RLock()
+				rules := w.ix.rules
+				w.ix.mu.RUnlock()
+				if info, err := os.Lstat(dir); err == nil && !rules.Skip(dir, info) {
+					w.add(w.ix.addTrees([]string{dir}, rules))
+				}
+			case event.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+				w.forget(w.ix.removeTree(dir))
+			}
+		}
+	}
+}
+
+// Close stops watching
+func (w *dirWatcher) Close() error {
+	return w.file.Close()
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package core
+
+// dirWatcher is Linux-only; elsewhere the index relies on periodic rescans
+type dirWatcher struct{}
+
+// startDirWatcher returns nil: no filesystem notifications on this platform
+func startDirWatcher(ix *DirIndex) *dirWatcher {
+	return nil
+}
+
+// Sync does nothing without notifications
+func (w *dirWatcher) Sync() {}
+
+// Close does nothing without notifications
+func (w *dirWatcher) Close() error {
+	return nil
+}